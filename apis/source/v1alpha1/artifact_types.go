@@ -20,6 +20,7 @@ import (
 	"path"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -43,12 +44,65 @@ type Artifact struct {
 	// +optional
 	Checksum string `json:"checksum"`
 
+	// Digest is the digest of the artifact in the form of
+	// '<algorithm>:<checksum>', for example 'sha256:<checksum>'. Unlike
+	// Checksum, the algorithm is SHA-256 or SHA-512.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
 	// LastUpdateTime is the timestamp corresponding to the last update of this
 	// artifact.
 	// +required
 	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
 }
 
+// ArtifactFormat is the archive format a source is packaged as
+// +kubebuilder:validation:Enum=tar.gz;tar.zst;zip
+type ArtifactFormat string
+
+const (
+	// ArtifactFormatTarGzip packages the source as a gzip compressed tarball
+	ArtifactFormatTarGzip ArtifactFormat = "tar.gz"
+	// ArtifactFormatTarZstd packages the source as a zstd compressed tarball
+	ArtifactFormatTarZstd ArtifactFormat = "tar.zst"
+	// ArtifactFormatZip packages the source as a zip archive
+	ArtifactFormatZip ArtifactFormat = "zip"
+)
+
+// ArtifactFormats are the supported artifact formats
+var ArtifactFormats = []ArtifactFormat{ArtifactFormatTarGzip, ArtifactFormatTarZstd, ArtifactFormatZip}
+
+// Extension returns the file extension of artifacts in the format, such as
+// '.tar.gz'. An empty format is a gzip compressed tarball.
+func (f ArtifactFormat) Extension() string {
+	if f == "" {
+		return "." + string(ArtifactFormatTarGzip)
+	}
+	return "." + string(f)
+}
+
+// ArtifactLimits bounds the content fetched and extracted to produce an
+// artifact. The limits tighten the limits configured for the controller, they
+// cannot raise them.
+type ArtifactLimits struct {
+	// MaxCompressedSize is the largest size of the content downloaded from
+	// the remote source, such as the layers of an image or a Maven artifact
+	// file, for example "100Mi".
+	// +optional
+	MaxCompressedSize *resource.Quantity `json:"maxCompressedSize,omitempty"`
+
+	// MaxSize is the largest total size of the files extracted from the
+	// downloaded content, for example "1Gi".
+	// +optional
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+
+	// MaxFiles is the largest number of entries, including directories and
+	// symlinks, extracted from the downloaded content.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxFiles *int64 `json:"maxFiles,omitempty"`
+}
+
 // HasRevision returns true if the given revision matches the current Revision
 // of the Artifact.
 func (in *Artifact) HasRevision(revision string) bool {
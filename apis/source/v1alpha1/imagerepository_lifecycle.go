@@ -28,10 +28,12 @@ const (
 	ImageRepositoryConditionReady             = apis.ConditionReady
 	ImageRepositoryConditionImageResolved     = "ImageResolved"
 	ImageRepositoryConditionArtifactAvailable = "ArtifactAvailable"
+	ImageRepositoryConditionSignatureVerified = "SignatureVerified"
 )
 
 var imagerepositoryCondSet = apis.NewLivingConditionSet(
 	ImageRepositoryConditionImageResolved,
+	ImageRepositoryConditionSignatureVerified,
 	ImageRepositoryConditionArtifactAvailable,
 )
 
@@ -30,9 +30,22 @@ type ImageRepositorySpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Image is a reference to an image in a remote repository
+	// Image is a reference to an image in a remote repository. When TagPolicy
+	// is set, Image must name the repository without a tag or digest.
 	Image string `json:"image"`
 
+	// TagPolicy selects the tag to resolve from the tags available in the
+	// image repository, rather than using a fixed tag or digest.
+	// +optional
+	TagPolicy *ImageTagPolicy `json:"tagPolicy,omitempty"`
+
+	// Platform selects the image for a platform, such as "linux/arm64" or
+	// "linux/arm/v7", when Image resolves to a multi-platform image index. The
+	// digests of both the index and the selected image are recorded in the
+	// artifact revision. Images that are not an index are used as is.
+	// +optional
+	Platform string `json:"platform,omitempty"`
+
 	// The interval at which to check for repository updates.
 	Interval metav1.Duration `json:"interval,omitempty"`
 
@@ -46,8 +59,144 @@ type ImageRepositorySpec struct {
 	// https://kubernetes.io/docs/tasks/configure-pod-container/configure-service-account/#add-imagepullsecrets-to-a-service-account
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Verify requires the resolved image to carry a trusted cosign signature
+	// before it is pulled and packaged as an artifact.
+	// +optional
+	Verify *ImageVerification `json:"verify,omitempty"`
+
+	// RevisionHistoryLimit is the number of artifact revisions to retain,
+	// including the current revision. Older revisions are removed from the
+	// artifact server. Defaults to the limit configured for the controller.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// Format is the archive format the source is packaged as, one of
+	// "tar.gz", "tar.zst" or "zip". Defaults to "tar.gz".
+	// +optional
+	Format ArtifactFormat `json:"format,omitempty"`
+
+	// Limits bounds the content fetched and extracted to produce the
+	// artifact, exceeding a limit fails the artifact with the
+	// ArtifactTooLarge reason.
+	// +optional
+	Limits *ArtifactLimits `json:"limits,omitempty"`
+
+	// Path is a directory within the image whose content is packaged in place
+	// of the whole image. Include and Ignore patterns are relative to it.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Include lists gitignore-style patterns selecting the files packaged
+	// from the image, files within a matching directory are also selected.
+	// All files are selected when empty.
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// Ignore lists gitignore-style patterns, like those of a .gitignore or
+	// .sourceignore file, excluding files from the artifact. Patterns
+	// prefixed with "!" select files excluded by an earlier pattern.
+	// +optional
+	Ignore []string `json:"ignore,omitempty"`
+
+	// LayerSelector selects a single layer of the image to package, such as
+	// the content layer of an OCI artifact. All layers of the image are
+	// flattened, later layers replacing the files of earlier layers, when not
+	// set.
+	// +optional
+	LayerSelector *ImageLayerSelector `json:"layerSelector,omitempty"`
+}
+
+// ImageTagPolicy selects a tag from the tags available in an image repository
+type ImageTagPolicy struct {
+	// SemVer is a semantic version constraint, such as ">=1.4 <2". The highest
+	// tag satisfying the constraint is selected. Tags that are not valid
+	// semantic versions are ignored.
+	// +required
+	SemVer string `json:"semver"`
+
+	// Prerelease allows tags with a prerelease version to be selected. When
+	// not set, prerelease tags are only selected if the constraint itself
+	// contains a prerelease.
+	// +optional
+	Prerelease *ImageTagPrereleaseFilter `json:"prerelease,omitempty"`
 }
 
+// ImageTagPrereleaseFilter restricts the prerelease tags that may be selected
+type ImageTagPrereleaseFilter struct {
+	// Identifiers limits prerelease tags to those whose first prerelease
+	// identifier is listed, such as "rc" for "1.4.0-rc.1". All prereleases are
+	// allowed when empty.
+	// +optional
+	Identifiers []string `json:"identifiers,omitempty"`
+}
+
+// ImageVerification defines the cosign signatures trusted for an image
+type ImageVerification struct {
+	// SecretRef is a reference to a Secret in the same namespace holding the
+	// trusted material. Data keys ending in ".pub" are PEM encoded public keys,
+	// a signature made by any of the keys is accepted. Data keys ending in
+	// ".crt" are PEM encoded root certificates that keyless signing
+	// certificates must chain to. The data key "rekor.pub", or keys ending in
+	// ".rekor.pub", are PEM encoded public keys of Rekor transparency logs.
+	// +required
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+
+	// Keyless lists the identities trusted to create keyless signatures. A
+	// keyless signature is accepted when its signing certificate chains to a
+	// root certificate in the Secret and was issued to one of the identities.
+	// +optional
+	Keyless []ImageKeylessIdentity `json:"keyless,omitempty"`
+}
+
+// ImageKeylessIdentity is an identity trusted to create keyless signatures
+type ImageKeylessIdentity struct {
+	// Issuer is the OIDC issuer that authenticated the signer, such as
+	// "https://token.actions.githubusercontent.com".
+	// +required
+	Issuer string `json:"issuer"`
+
+	// Subject is the email address or URI of the signer recorded in the
+	// signing certificate.
+	// +required
+	Subject string `json:"subject"`
+}
+
+// ImageLayerSelector selects a layer of an image by its media type or title.
+// The first layer of the image matching all of the fields set is selected,
+// the first layer when none are set.
+type ImageLayerSelector struct {
+	// MediaType of the layer to package, such as
+	// "application/vnd.cncf.flux.content.v1.tar+gzip".
+	// +optional
+	MediaType string `json:"mediaType,omitempty"`
+
+	// Title of the layer to package, the file name recorded by its
+	// "org.opencontainers.image.title" annotation, such as "app.jar".
+	// +optional
+	Title string `json:"title,omitempty"`
+
+	// Operation is how the layer is packaged, "extract" to extract the
+	// content of a tarball layer, optionally gzip or zstd compressed, or
+	// "copy" to package the layer as a single file named by its
+	// "org.opencontainers.image.title" annotation, or by its digest. A copied
+	// layer is verified against its digest. Defaults to "extract".
+	// +kubebuilder:validation:Enum=extract;copy
+	// +optional
+	Operation ImageLayerOperation `json:"operation,omitempty"`
+}
+
+// ImageLayerOperation is how a selected image layer is packaged
+type ImageLayerOperation string
+
+const (
+	// ImageLayerOperationExtract extracts the content of a tarball layer
+	ImageLayerOperationExtract ImageLayerOperation = "extract"
+	// ImageLayerOperationCopy packages the layer as a single file
+	ImageLayerOperationCopy ImageLayerOperation = "copy"
+)
+
 // ImageRepositoryStatus defines the observed state of ImageRepository
 type ImageRepositoryStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -60,14 +209,27 @@ type ImageRepositoryStatus struct {
 	// +optional
 	URL string `json:"url,omitempty"`
 
+	// LatestURL is the signed download link for the latest alias of the
+	// artifact, which always resolves to the current artifact. Set only when
+	// artifact URLs are signed and served by the controller, the alias is not
+	// served from S3.
+	// +optional
+	LatestURL string `json:"latestURL,omitempty"`
+
 	// Artifact represents the output of the last successful repository sync.
 	// +optional
 	Artifact *Artifact `json:"artifact,omitempty"`
+
+	// Tag is the image tag selected by the tag policy during the last
+	// repository sync.
+	// +optional
+	Tag string `json:"tag,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+//+kubebuilder:printcolumn:name="Tag",type=string,JSONPath=`.status.tag`,priority=1
 //+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.artifact.url`
 //+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 //+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
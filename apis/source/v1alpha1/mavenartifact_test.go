@@ -23,6 +23,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 )
@@ -104,6 +105,8 @@ func TestMavenArtifactDefault(t *testing.T) {
 }
 
 func TestMavenArtifactValidate(t *testing.T) {
+	int64Ptr := func(i int64) *int64 { return &i }
+
 	tests := []struct {
 		name      string
 		seed      *MavenArtifact
@@ -196,8 +199,7 @@ func TestMavenArtifactValidate(t *testing.T) {
 			expected: field.ErrorList{},
 		},
 		{
-			// TODO this is valid, but not presently supported
-			name: "invalid version range",
+			name: "valid version range",
 			seed: &MavenArtifact{
 				Spec: MavenArtifactSpec{
 					Artifact: MavenArtifactType{
@@ -211,8 +213,25 @@ func TestMavenArtifactValidate(t *testing.T) {
 					Interval: metav1.Duration{Duration: time.Minute},
 				},
 			},
+			expected: field.ErrorList{},
+		},
+		{
+			name: "invalid version range",
+			seed: &MavenArtifact{
+				Spec: MavenArtifactSpec{
+					Artifact: MavenArtifactType{
+						GroupId:    "com.example",
+						ArtifactId: "my-artifact",
+						Version:    "[2.0,1.0)",
+					},
+					Repository: Repository{
+						URL: "https://repo1.maven.org/maven2",
+					},
+					Interval: metav1.Duration{Duration: time.Minute},
+				},
+			},
 			expected: field.ErrorList{
-				field.Invalid(field.NewPath("spec", "artifact", "version"), "[1.0,2.0)", ""),
+				field.Invalid(field.NewPath("spec", "artifact", "version"), "[2.0,1.0)", `invalid version range "[2.0,1.0)": range "[2.0,1.0)" defies version ordering`),
 			},
 		},
 		{
@@ -314,6 +333,112 @@ func TestMavenArtifactValidate(t *testing.T) {
 				field.Invalid(field.NewPath("spec", "timeout"), &metav1.Duration{}, ""),
 			},
 		},
+		{
+			name: "invalid revision history limit",
+			seed: &MavenArtifact{
+				Spec: MavenArtifactSpec{
+					Artifact: MavenArtifactType{
+						GroupId:    "com.example",
+						ArtifactId: "my-artifact",
+						Version:    "1.0.0",
+					},
+					Repository: Repository{
+						URL: "https://repo1.maven.org/maven2",
+					},
+					Interval:             metav1.Duration{Duration: time.Minute},
+					RevisionHistoryLimit: new(int32),
+				},
+			},
+			expected: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "revisionHistoryLimit"), int32(0), "must be at least 1"),
+			},
+		},
+		{
+			name: "valid format",
+			seed: &MavenArtifact{
+				Spec: MavenArtifactSpec{
+					Artifact: MavenArtifactType{
+						GroupId:    "com.example",
+						ArtifactId: "my-artifact",
+						Version:    "1.0.0",
+					},
+					Repository: Repository{
+						URL: "https://repo1.maven.org/maven2",
+					},
+					Interval: metav1.Duration{Duration: time.Minute},
+					Format:   ArtifactFormatZip,
+				},
+			},
+			expected: field.ErrorList{},
+		},
+		{
+			name: "invalid format",
+			seed: &MavenArtifact{
+				Spec: MavenArtifactSpec{
+					Artifact: MavenArtifactType{
+						GroupId:    "com.example",
+						ArtifactId: "my-artifact",
+						Version:    "1.0.0",
+					},
+					Repository: Repository{
+						URL: "https://repo1.maven.org/maven2",
+					},
+					Interval: metav1.Duration{Duration: time.Minute},
+					Format:   "rar",
+				},
+			},
+			expected: field.ErrorList{
+				field.NotSupported(field.NewPath("spec", "format"), ArtifactFormat("rar"), ArtifactFormats),
+			},
+		},
+		{
+			name: "valid limits",
+			seed: &MavenArtifact{
+				Spec: MavenArtifactSpec{
+					Artifact: MavenArtifactType{
+						GroupId:    "com.example",
+						ArtifactId: "my-artifact",
+						Version:    "1.0.0",
+					},
+					Repository: Repository{
+						URL: "https://repo1.maven.org/maven2",
+					},
+					Interval: metav1.Duration{Duration: time.Minute},
+					Limits: &ArtifactLimits{
+						MaxCompressedSize: resource.NewQuantity(100<<20, resource.BinarySI),
+						MaxSize:           resource.NewQuantity(1<<30, resource.BinarySI),
+						MaxFiles:          int64Ptr(1000),
+					},
+				},
+			},
+			expected: field.ErrorList{},
+		},
+		{
+			name: "invalid limits",
+			seed: &MavenArtifact{
+				Spec: MavenArtifactSpec{
+					Artifact: MavenArtifactType{
+						GroupId:    "com.example",
+						ArtifactId: "my-artifact",
+						Version:    "1.0.0",
+					},
+					Repository: Repository{
+						URL: "https://repo1.maven.org/maven2",
+					},
+					Interval: metav1.Duration{Duration: time.Minute},
+					Limits: &ArtifactLimits{
+						MaxCompressedSize: resource.NewQuantity(0, resource.BinarySI),
+						MaxSize:           resource.NewQuantity(-1, resource.BinarySI),
+						MaxFiles:          int64Ptr(0),
+					},
+				},
+			},
+			expected: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "limits", "maxCompressedSize"), "0", "must be greater than zero"),
+				field.Invalid(field.NewPath("spec", "limits", "maxSize"), "-1", "must be greater than zero"),
+				field.Invalid(field.NewPath("spec", "limits", "maxFiles"), int64(0), "must be at least 1"),
+			},
+		},
 		{
 			name: "invalid artifactId path traversal",
 			seed: &MavenArtifact{
@@ -56,8 +56,8 @@ type MavenArtifactType struct {
 
 	// Artifact Version
 	// The version element identifies the current version of the artifact.
-	// Supported values: "0.1.2" (version) and "RELEASE"
-	// Unsupported values: "LATEST", "SNAPSHOT" and Maven Version Ranges
+	// Supported values: "0.1.2" (version), "RELEASE", "LATEST", "SNAPSHOT" and
+	// Maven Version Ranges such as "[1.2,2.0)"
 	// https://maven.apache.org/enforcer/enforcer-rules/versionRanges.html
 	// +required
 	Version string `json:"version"`
@@ -96,6 +96,31 @@ type MavenArtifactSpec struct {
 	// Defaults to 'Interval' duration.
 	// +optional
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// TrustedKeysSecretRef can be given the name of a secret containing
+	// ASCII armored OpenPGP public keys. When set, the detached signature
+	// published at '<artifact>.asc' must be signed by one of the keys before
+	// the artifact is made available.
+	// +optional
+	TrustedKeysSecretRef *corev1.LocalObjectReference `json:"trustedKeysSecretRef,omitempty"`
+
+	// RevisionHistoryLimit is the number of artifact revisions to retain,
+	// including the current revision. Older revisions are removed from the
+	// artifact server. Defaults to the limit configured for the controller.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// Format is the archive format the source is packaged as, one of
+	// "tar.gz", "tar.zst" or "zip". Defaults to "tar.gz".
+	// +optional
+	Format ArtifactFormat `json:"format,omitempty"`
+
+	// Limits bounds the content fetched and extracted to produce the
+	// artifact, exceeding a limit fails the artifact with the
+	// ArtifactTooLarge reason.
+	// +optional
+	Limits *ArtifactLimits `json:"limits,omitempty"`
 }
 
 // MavenArtifactStatus defines the observed state of MavenArtifact
@@ -107,6 +132,13 @@ type MavenArtifactStatus struct {
 	// +optional
 	URL string `json:"url,omitempty"`
 
+	// LatestURL is the signed download link for the latest alias of the
+	// artifact, which always resolves to the current artifact. Set only when
+	// artifact URLs are signed and served by the controller, the alias is not
+	// served from S3.
+	// +optional
+	LatestURL string `json:"latestURL,omitempty"`
+
 	// Artifact represents the output of the last successful repository sync.
 	// +optional
 	Artifact *Artifact `json:"artifact,omitempty"`
@@ -20,12 +20,15 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/mavenmetadata"
 )
 
 // +kubebuilder:webhook:path=/validate-source-apps-tanzu-vmware-com-v1alpha1-mavenartifact,mutating=false,failurePolicy=fail,sideEffects=none,admissionReviewVersions=v1beta1,groups=source.apps.tanzu.vmware.com,resources=mavenartifacts,verbs=create;update,versions=v1alpha1,name=mavenartifacts.source.apps.tanzu.vmware.com
@@ -67,6 +70,15 @@ func (s *MavenArtifactSpec) validate(fldPath *field.Path) field.ErrorList {
 	if s.Timeout != nil && s.Timeout.Duration <= 0 {
 		errs = append(errs, field.Invalid(fldPath.Child("timeout"), s.Timeout, ""))
 	}
+	if s.RevisionHistoryLimit != nil && *s.RevisionHistoryLimit < 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("revisionHistoryLimit"), *s.RevisionHistoryLimit, "must be at least 1"))
+	}
+	if s.Format != "" && !slices.Contains(ArtifactFormats, s.Format) {
+		errs = append(errs, field.NotSupported(fldPath.Child("format"), s.Format, ArtifactFormats))
+	}
+	if s.Limits != nil {
+		errs = append(errs, s.Limits.validate(fldPath.Child("limits"))...)
+	}
 
 	return errs
 }
@@ -87,12 +99,12 @@ func (s *MavenArtifactType) validate(fldPath *field.Path) field.ErrorList {
 
 	if s.Version == "" {
 		errs = append(errs, field.Required(fldPath.Child("version"), ""))
-	} else if strings.HasPrefix(s.Version, "[") ||
-		strings.HasPrefix(s.Version, "(") {
-		// TODO remove this validation rule when version range is resolvable
-		errs = append(errs, field.Invalid(fldPath.Child("version"), s.Version, ""))
 	} else if containsPathTraversal(s.Version) {
 		errs = append(errs, field.Invalid(fldPath.Child("version"), s.Version, "must not contain path separators or \"..\""))
+	} else if mavenmetadata.IsVersionRange(s.Version) {
+		if _, err := mavenmetadata.ParseVersionRange(s.Version); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("version"), s.Version, err.Error()))
+		}
 	}
 
 	if s.Type != "" && containsPathTraversal(s.Type) {
@@ -113,6 +125,22 @@ func containsPathTraversal(value string) bool {
 	return value == ".." || strings.ContainsAny(value, "/\\")
 }
 
+func (s *ArtifactLimits) validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if s.MaxCompressedSize != nil && s.MaxCompressedSize.Sign() <= 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("maxCompressedSize"), s.MaxCompressedSize.String(), "must be greater than zero"))
+	}
+	if s.MaxSize != nil && s.MaxSize.Sign() <= 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("maxSize"), s.MaxSize.String(), "must be greater than zero"))
+	}
+	if s.MaxFiles != nil && *s.MaxFiles < 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("maxFiles"), *s.MaxFiles, "must be at least 1"))
+	}
+
+	return errs
+}
+
 func (s *Repository) validate(fldPath *field.Path) field.ErrorList {
 	errs := field.ErrorList{}
 
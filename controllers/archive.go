@@ -0,0 +1,331 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+)
+
+// archiveModTime is the modification time of every entry in a tarball, the
+// time a file was pulled or downloaded must not change the artifact
+var archiveModTime = time.Unix(0, 0)
+
+// errUnsafeSymlink is returned when packaging or extracting a symlink whose
+// target is outside of the root directory
+var errUnsafeSymlink = errors.New("symlink target is outside of the artifact")
+
+// archiveWriter writes the entries of an artifact in an archive format
+type archiveWriter interface {
+	// WriteDir adds a directory, the name does not end with a slash
+	WriteDir(name string, mode int64) error
+	// WriteSymlink adds a symlink to the target
+	WriteSymlink(name, target string) error
+	// WriteFile adds a regular file, reading size bytes of content from r
+	WriteFile(name string, mode int64, size int64, r io.Reader) error
+	// Close flushes the archive, the underlying writer is not closed
+	Close() error
+}
+
+// createArchive packages the directory as an archive in the format at name,
+// returning the SHA-1 checksum and the algorithm-prefixed digest of the
+// archive, along with a description of each file packaged for the artifact's
+// manifest.
+//
+// Directories, including empty directories, and symlinks are packaged as
+// such. Symlinks must resolve within the directory, an error wrapping
+// errUnsafeSymlink is returned otherwise.
+//
+// Packaging is reproducible, the same files always produce the same archive.
+// Entries are sorted by path, timestamps and ownership are cleared, modes are
+// normalized to 0755 for directories and executable files and 0644 for other
+// files, and compression headers carry no name or timestamp.
+func createArchive(dir, name string, format sourcev1alpha1.ArtifactFormat, digestAlgorithm DigestAlgorithm) (string, string, []ArtifactManifestFile, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", "", nil, err
+	}
+	paths := []string{}
+	err = filepath.WalkDir(root, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if fp == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, fp)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", "", nil, err
+	}
+	sort.Strings(paths)
+
+	file, err := os.Create(name)
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer file.Close()
+
+	// hash the archive as it is written, rather than reading it back
+	checksum := sha1.New()
+	digest := digestAlgorithm.New()
+
+	archive, err := newArchiveWriter(io.MultiWriter(file, checksum, digest), format)
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer archive.Close()
+
+	files := []ArtifactManifestFile{}
+	for _, p := range paths {
+		entry, err := addArchiveEntry(archive, root, p)
+		if err != nil {
+			return "", "", nil, err
+		}
+		if entry != nil {
+			files = append(files, *entry)
+		}
+	}
+
+	// flush the archive before summing
+	if err := archive.Close(); err != nil {
+		return "", "", nil, err
+	}
+
+	return fmt.Sprintf("%x", checksum.Sum(nil)), digestAlgorithm.Digest(digest), files, nil
+}
+
+// newArchiveWriter returns a writer for archives in the format, an empty
+// format writes a gzip compressed tarball
+func newArchiveWriter(w io.Writer, format sourcev1alpha1.ArtifactFormat) (archiveWriter, error) {
+	switch format {
+	case "", sourcev1alpha1.ArtifactFormatTarGzip:
+		gzipWriter := gzip.NewWriter(w)
+		gzipWriter.Header = gzip.Header{OS: 255}
+		return newTarWriter(gzipWriter), nil
+	case sourcev1alpha1.ArtifactFormatTarZstd:
+		// a single encoder goroutine keeps the output stable
+		zstdWriter, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return newTarWriter(zstdWriter), nil
+	case sourcev1alpha1.ArtifactFormatZip:
+		return &zipArchiveWriter{w: zip.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported artifact format %q", format)
+}
+
+// addArchiveEntry writes the file at name within the root to the archive,
+// returning its manifest entry. Directories have no manifest entry.
+func addArchiveEntry(archive archiveWriter, root, name string) (*ArtifactManifestFile, error) {
+	fp := filepath.Join(root, filepath.FromSlash(name))
+	info, err := os.Lstat(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := normalizedMode(info.Mode())
+	switch {
+	case info.IsDir():
+		return nil, archive.WriteDir(name, mode)
+
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(fp)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSymlink(root, name, target); err != nil {
+			return nil, err
+		}
+		target = filepath.ToSlash(target)
+		if err := archive.WriteSymlink(name, target); err != nil {
+			return nil, err
+		}
+		return &ArtifactManifestFile{
+			Path: name,
+			Mode: fmt.Sprintf("%04o", 0o777),
+			Link: target,
+		}, nil
+
+	case !info.Mode().IsRegular():
+		return nil, fmt.Errorf("unable to package %q, only regular files, directories and symlinks are supported", name)
+	}
+
+	file, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fileDigest := SHA256.New()
+	if err := archive.WriteFile(name, mode, info.Size(), io.TeeReader(file, fileDigest)); err != nil {
+		return nil, err
+	}
+	return &ArtifactManifestFile{
+		Path:   name,
+		Size:   info.Size(),
+		Mode:   fmt.Sprintf("%04o", mode),
+		Digest: SHA256.Digest(fileDigest),
+	}, nil
+}
+
+// tarArchiveWriter writes a tarball through a compressor
+type tarArchiveWriter struct {
+	compressor io.WriteCloser
+	w          *tar.Writer
+}
+
+func newTarWriter(compressor io.WriteCloser) *tarArchiveWriter {
+	return &tarArchiveWriter{compressor: compressor, w: tar.NewWriter(compressor)}
+}
+
+func (a *tarArchiveWriter) WriteDir(name string, mode int64) error {
+	return a.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     mode,
+		ModTime:  archiveModTime,
+	})
+}
+
+func (a *tarArchiveWriter) WriteSymlink(name, target string) error {
+	return a.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     name,
+		Linkname: target,
+		Mode:     0o777,
+		ModTime:  archiveModTime,
+	})
+}
+
+func (a *tarArchiveWriter) WriteFile(name string, mode int64, size int64, r io.Reader) error {
+	if err := a.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     mode,
+		ModTime:  archiveModTime,
+	}); err != nil {
+		return err
+	}
+	_, err := io.Copy(a.w, r)
+	return err
+}
+
+func (a *tarArchiveWriter) Close() error {
+	// flush the tar footer before the compressor's trailer
+	if err := a.w.Close(); err != nil {
+		return err
+	}
+	return a.compressor.Close()
+}
+
+// zipArchiveWriter writes a zip archive. Entries carry no timestamp.
+type zipArchiveWriter struct {
+	w *zip.Writer
+}
+
+func (a *zipArchiveWriter) WriteDir(name string, mode int64) error {
+	header := &zip.FileHeader{Name: name + "/", Method: zip.Store}
+	header.SetMode(fs.ModeDir | fs.FileMode(mode))
+	_, err := a.w.CreateHeader(header)
+	return err
+}
+
+func (a *zipArchiveWriter) WriteSymlink(name, target string) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Store}
+	header.SetMode(fs.ModeSymlink | 0o777)
+	w, err := a.w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, target)
+	return err
+}
+
+func (a *zipArchiveWriter) WriteFile(name string, mode int64, size int64, r io.Reader) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	header.SetMode(fs.FileMode(mode))
+	w, err := a.w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (a *zipArchiveWriter) Close() error {
+	return a.w.Close()
+}
+
+// checkSymlink returns an error wrapping errUnsafeSymlink when the target of
+// the symlink at name, relative to the root, resolves outside of the root.
+// Targets that do not exist are checked lexically, existing targets are
+// resolved through any further symlinks.
+func checkSymlink(root, name, target string) error {
+	resolved := path.Join(path.Dir(name), filepath.ToSlash(target))
+	if filepath.IsAbs(target) || path.IsAbs(target) || resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return fmt.Errorf("%w: %q links to %q", errUnsafeSymlink, name, target)
+	}
+	evaluated, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if !withinDir(root, evaluated) {
+		return fmt.Errorf("%w: %q links to %q", errUnsafeSymlink, name, target)
+	}
+	return nil
+}
+
+// withinDir returns true when the path is the directory or is beneath it
+func withinDir(dir, p string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(os.PathSeparator))
+}
+
+// normalizedMode returns 0755 for directories and files executable by anyone,
+// otherwise 0644
+func normalizedMode(mode fs.FileMode) int64 {
+	if mode.IsDir() || mode&0o111 != 0 {
+		return 0o755
+	}
+	return 0o644
+}
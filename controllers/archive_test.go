@@ -0,0 +1,373 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/zstd"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+)
+
+// TestCreateArchiveIsReproducible packages the same files written in a
+// different order, with different timestamps and permissions, and expects
+// byte-identical tarballs.
+func TestCreateArchiveIsReproducible(t *testing.T) {
+	files := []struct {
+		name     string
+		contents string
+		modes    [2]os.FileMode
+	}{
+		{name: "README.md", contents: "readme", modes: [2]os.FileMode{0o644, 0o600}},
+		{name: "bin/run.sh", contents: "#!/bin/sh", modes: [2]os.FileMode{0o755, 0o700}},
+		{name: "src/a.txt", contents: "a", modes: [2]os.FileMode{0o644, 0o640}},
+		{name: "src/b/c.txt", contents: "c", modes: [2]os.FileMode{0o664, 0o444}},
+		{name: "src.txt", contents: "src", modes: [2]os.FileMode{0o644, 0o644}},
+	}
+
+	tarballs := [2][]byte{}
+	checksums := [2]string{}
+	for run := range tarballs {
+		dir := t.TempDir()
+		modTime := time.Now().Add(time.Duration(run) * time.Hour)
+		for i := range files {
+			// write the files in the opposite order on the second run
+			f := files[i]
+			if run == 1 {
+				f = files[len(files)-1-i]
+			}
+			fp := filepath.Join(dir, filepath.FromSlash(f.name))
+			if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(fp, []byte(f.contents), f.modes[run]); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(fp, f.modes[run]); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(fp, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		name := filepath.Join(t.TempDir(), "artifact.tar.gz")
+		checksum, _, _, err := createArchive(dir, name, sourcev1alpha1.ArtifactFormatTarGzip, SHA256)
+		if err != nil {
+			t.Fatalf("createArchive() unexpected error: %v", err)
+		}
+		tarball, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tarballs[run] = tarball
+		checksums[run] = checksum
+	}
+
+	if !bytes.Equal(tarballs[0], tarballs[1]) {
+		t.Errorf("createArchive() expected byte-identical tarballs")
+	}
+	if checksums[0] != checksums[1] {
+		t.Errorf("createArchive() expected identical checksums, got %q and %q", checksums[0], checksums[1])
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(tarballs[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gzipReader.Name != "" || !gzipReader.ModTime.IsZero() {
+		t.Errorf("createArchive() expected an empty gzip header, got name %q and time %v", gzipReader.Name, gzipReader.ModTime)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	expected := []struct {
+		name string
+		mode int64
+	}{
+		{name: "README.md", mode: 0o644},
+		{name: "bin/", mode: 0o755},
+		{name: "bin/run.sh", mode: 0o755},
+		{name: "src/", mode: 0o755},
+		{name: "src.txt", mode: 0o644},
+		{name: "src/a.txt", mode: 0o644},
+		{name: "src/b/", mode: 0o755},
+		{name: "src/b/c.txt", mode: 0o644},
+	}
+	for _, e := range expected {
+		header, err := tarReader.Next()
+		if err != nil {
+			t.Fatalf("expected entry %q: %v", e.name, err)
+		}
+		if header.Name != e.name {
+			t.Errorf("expected entry %q, got %q", e.name, header.Name)
+		}
+		if header.Mode != e.mode {
+			t.Errorf("expected entry %q to have mode %04o, got %04o", e.name, e.mode, header.Mode)
+		}
+		if !header.ModTime.Equal(time.Unix(0, 0)) || header.Uid != 0 || header.Gid != 0 || header.Uname != "" || header.Gname != "" {
+			t.Errorf("expected entry %q to have no timestamp or owner, got %+v", e.name, header)
+		}
+	}
+	if _, err := tarReader.Next(); err != io.EOF {
+		t.Errorf("expected no further entries, got %v", err)
+	}
+}
+
+func TestCreateArchivePreservesDirectoriesAndSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"empty", "lib"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib", "app.jar"), []byte("jar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{
+		"app.jar":     "lib/app.jar",
+		"current":     "lib",
+		"lib/missing": "../missing.txt",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	name := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	_, _, files, err := createArchive(dir, name, sourcev1alpha1.ArtifactFormatTarGzip, SHA256)
+	if err != nil {
+		t.Fatalf("createArchive() unexpected error: %v", err)
+	}
+
+	tarball, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tarball.Close()
+	gzipReader, err := gzip.NewReader(tarball)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]byte{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[header.Name] = header.Typeflag
+	}
+	for name, typeflag := range map[string]byte{
+		"empty/":  tar.TypeDir,
+		"lib/":    tar.TypeDir,
+		"current": tar.TypeSymlink,
+		"run.sh":  tar.TypeReg,
+	} {
+		if entries[name] != typeflag {
+			t.Errorf("createArchive() expected entry %q of type %q, got %q", name, typeflag, entries[name])
+		}
+	}
+
+	expected := []ArtifactManifestFile{
+		{Path: "app.jar", Mode: "0777", Link: "lib/app.jar"},
+		{Path: "current", Mode: "0777", Link: "lib"},
+		{Path: "lib/app.jar", Size: 3, Mode: "0644", Digest: "sha256:0163f1eea7894350060624d315234d40c508ab251ba121714e234503045faadd"},
+		{Path: "lib/missing", Mode: "0777", Link: "../missing.txt"},
+		{Path: "run.sh", Size: 9, Mode: "0755", Digest: "sha256:3af71adb278ad4af33c144b78fa1ae708da03b773d98324ae991a7daedb53ca2"},
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("createArchive() expected manifest entries %+v, got %+v", expected, files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Errorf("createArchive() expected manifest entry %+v, got %+v", expected[i], files[i])
+		}
+	}
+}
+
+func TestCreateArchiveRejectsUnsafeSymlinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		links map[string]string
+	}{
+		{
+			name:  "absolute target",
+			links: map[string]string{"link": "/etc/passwd"},
+		},
+		{
+			name:  "parent directory target",
+			links: map[string]string{"link": "../outside"},
+		},
+		{
+			name:  "nested parent directory target",
+			links: map[string]string{"sub/link": "../../outside"},
+		},
+		{
+			name: "chained symlinks",
+			links: map[string]string{
+				"sub/parent": "..",
+				"link":       "sub/parent/..",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for name, target := range tc.links {
+				if err := os.Symlink(target, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, _, _, err := createArchive(dir, filepath.Join(t.TempDir(), "artifact.tar.gz"), sourcev1alpha1.ArtifactFormatTarGzip, SHA256)
+			if !errors.Is(err, errUnsafeSymlink) {
+				t.Errorf("createArchive() expected an unsafe symlink error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestCreateArchiveFormats packages the same files in each format twice, with
+// different timestamps, and expects byte-identical archives holding the files.
+func TestCreateArchiveFormats(t *testing.T) {
+	type entry struct {
+		mode    int64
+		content string
+	}
+	expected := map[string]entry{
+		"empty/":      {mode: 0o755},
+		"lib/":        {mode: 0o755},
+		"lib/app.jar": {mode: 0o644, content: "jar"},
+		"app.jar":     {mode: 0o777, content: "lib/app.jar"},
+		"run.sh":      {mode: 0o755, content: "#!/bin/sh"},
+	}
+
+	for _, format := range []sourcev1alpha1.ArtifactFormat{sourcev1alpha1.ArtifactFormatTarGzip, sourcev1alpha1.ArtifactFormatTarZstd, sourcev1alpha1.ArtifactFormatZip} {
+		t.Run(string(format), func(t *testing.T) {
+			archives := [2][]byte{}
+			for run := range archives {
+				dir := t.TempDir()
+				for _, d := range []string{"empty", "lib"} {
+					if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := os.WriteFile(filepath.Join(dir, "lib", "app.jar"), []byte("jar"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh"), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink("lib/app.jar", filepath.Join(dir, "app.jar")); err != nil {
+					t.Fatal(err)
+				}
+				modTime := time.Now().Add(time.Duration(run) * time.Hour)
+				if err := os.Chtimes(filepath.Join(dir, "run.sh"), modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+
+				name := filepath.Join(t.TempDir(), "artifact"+format.Extension())
+				if _, _, _, err := createArchive(dir, name, format, SHA256); err != nil {
+					t.Fatalf("createArchive() unexpected error: %v", err)
+				}
+				archive, err := os.ReadFile(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				archives[run] = archive
+			}
+			if !bytes.Equal(archives[0], archives[1]) {
+				t.Errorf("createArchive() expected byte-identical archives")
+			}
+
+			actual := map[string]entry{}
+			if format == sourcev1alpha1.ArtifactFormatZip {
+				zipReader, err := zip.NewReader(bytes.NewReader(archives[0]), int64(len(archives[0])))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, f := range zipReader.File {
+					r, err := f.Open()
+					if err != nil {
+						t.Fatal(err)
+					}
+					content, err := io.ReadAll(r)
+					r.Close()
+					if err != nil {
+						t.Fatal(err)
+					}
+					actual[f.Name] = entry{mode: int64(f.Mode().Perm()), content: string(content)}
+				}
+			} else {
+				var r io.Reader
+				if format == sourcev1alpha1.ArtifactFormatTarZstd {
+					zstdReader, err := zstd.NewReader(bytes.NewReader(archives[0]))
+					if err != nil {
+						t.Fatal(err)
+					}
+					defer zstdReader.Close()
+					r = zstdReader
+				} else {
+					gzipReader, err := gzip.NewReader(bytes.NewReader(archives[0]))
+					if err != nil {
+						t.Fatal(err)
+					}
+					r = gzipReader
+				}
+				tarReader := tar.NewReader(r)
+				for {
+					header, err := tarReader.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+					content, err := io.ReadAll(tarReader)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if header.Typeflag == tar.TypeSymlink {
+						content = []byte(header.Linkname)
+					}
+					actual[header.Name] = entry{mode: header.Mode, content: string(content)}
+				}
+			}
+			if diff := cmp.Diff(expected, actual, cmp.AllowUnexported(entry{})); diff != "" {
+				t.Errorf("createArchive() entries (-expected, +actual): %s", diff)
+			}
+		})
+	}
+}
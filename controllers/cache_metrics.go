@@ -0,0 +1,81 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+)
+
+var cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "source_controller_cache_requests_total",
+	Help: "Lookups of downloaded and packaged content in the cache.",
+}, []string{"content", "result"})
+
+const (
+	// cacheContentLayer labels lookups of image layers
+	cacheContentLayer = "layer"
+	// cacheContentImageArtifact labels lookups of artifacts packaged from
+	// images
+	cacheContentImageArtifact = "imagerepository"
+	// cacheContentMavenArtifact labels lookups of Maven artifact files
+	cacheContentMavenArtifact = "mavenartifact"
+
+	cacheHit  = "hit"
+	cacheMiss = "miss"
+)
+
+func init() {
+	metrics.Registry.MustRegister(cacheRequests)
+}
+
+var (
+	cacheSizeDesc = prometheus.NewDesc("source_controller_cache_size_bytes",
+		"Total size of the files in the cache.", nil, nil)
+	cacheFilesDesc = prometheus.NewDesc("source_controller_cache_files",
+		"Number of files in the cache.", nil, nil)
+	cacheEvictedBytesDesc = prometheus.NewDesc("source_controller_cache_evicted_bytes_total",
+		"Bytes evicted from the cache, the least recently used files are evicted once the cache exceeds its size.", nil, nil)
+	cacheEvictedFilesDesc = prometheus.NewDesc("source_controller_cache_evicted_files_total",
+		"Files evicted from the cache.", nil, nil)
+)
+
+// CacheCollector exposes the size of the cache and the files evicted from it
+func CacheCollector(cache *blobcache.Cache) prometheus.Collector {
+	return &cacheCollector{cache: cache}
+}
+
+type cacheCollector struct {
+	cache *blobcache.Cache
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheSizeDesc
+	ch <- cacheFilesDesc
+	ch <- cacheEvictedBytesDesc
+	ch <- cacheEvictedFilesDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	evicted := c.cache.Evicted()
+	ch <- prometheus.MustNewConstMetric(cacheSizeDesc, prometheus.GaugeValue, float64(c.cache.Size()))
+	ch <- prometheus.MustNewConstMetric(cacheFilesDesc, prometheus.GaugeValue, float64(c.cache.Len()))
+	ch <- prometheus.MustNewConstMetric(cacheEvictedBytesDesc, prometheus.CounterValue, float64(evicted.Bytes))
+	ch <- prometheus.MustNewConstMetric(cacheEvictedFilesDesc, prometheus.CounterValue, float64(evicted.Files))
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// DigestAlgorithm is the hash function used to compute the digest of an artifact
+type DigestAlgorithm string
+
+const (
+	SHA512 DigestAlgorithm = "sha512"
+	SHA256 DigestAlgorithm = "sha256"
+	SHA1   DigestAlgorithm = "sha1"
+	MD5    DigestAlgorithm = "md5"
+)
+
+// checksumAlgorithms are the algorithms a Maven repository may publish checksum
+// files for, from strongest to weakest
+var checksumAlgorithms = []DigestAlgorithm{SHA512, SHA256, SHA1, MD5}
+
+// ParseDigestAlgorithm returns the DigestAlgorithm for the name, or an error if
+// the algorithm is not supported.
+func ParseDigestAlgorithm(name string) (DigestAlgorithm, error) {
+	switch a := DigestAlgorithm(strings.ToLower(name)); a {
+	case SHA256, SHA512:
+		return a, nil
+	}
+	return "", fmt.Errorf("unsupported digest algorithm %q, must be one of %q or %q", name, SHA256, SHA512)
+}
+
+// ParseChecksumFloor returns the weakest DigestAlgorithm that is acceptable when
+// verifying a download against a checksum file, or an error if the algorithm
+// is not supported.
+func ParseChecksumFloor(name string) (DigestAlgorithm, error) {
+	a := DigestAlgorithm(strings.ToLower(name))
+	for _, supported := range checksumAlgorithms {
+		if a == supported {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported checksum algorithm %q, must be one of %q", name, checksumAlgorithms)
+}
+
+// checksumAlgorithmsDownTo returns the checksum algorithms from strongest to
+// weakest, stopping at the floor.
+func checksumAlgorithmsDownTo(floor DigestAlgorithm) []DigestAlgorithm {
+	for i, a := range checksumAlgorithms {
+		if a == floor {
+			return checksumAlgorithms[:i+1]
+		}
+	}
+	return checksumAlgorithms
+}
+
+// New returns a hash for the algorithm
+func (a DigestAlgorithm) New() hash.Hash {
+	switch a {
+	case SHA512:
+		return sha512.New()
+	case SHA1:
+		return sha1.New()
+	case MD5:
+		return md5.New()
+	}
+	return sha256.New()
+}
+
+// Digest formats the hash sum in the form of '<algorithm>:<hex checksum>'
+func (a DigestAlgorithm) Digest(h hash.Hash) string {
+	return fmt.Sprintf("%s:%x", a, h.Sum(nil))
+}
+
+// Matches returns true if the digest was computed with this algorithm.
+func (a DigestAlgorithm) Matches(digest string) bool {
+	return strings.HasPrefix(digest, string(a)+":")
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+)
+
+// downloadRetries bounds the requests made for each file downloaded from a
+// Maven repository
+var downloadRetries = retryPolicy{
+	Attempts:   4,
+	Backoff:    time.Second,
+	MaxBackoff: 30 * time.Second,
+}
+
+// retryPolicy retries transient download failures with an exponential backoff
+type retryPolicy struct {
+	// Attempts is the maximum number of attempts, including the first
+	Attempts int
+	// Backoff is the delay before the first retry, doubled for each retry
+	// after it. A random jitter of up to half the delay is subtracted so
+	// resources failing together do not retry together.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between attempts. A server asking to wait
+	// longer with Retry-After is not retried, the error is returned instead
+	// and the resource is requeued once the delay elapses.
+	MaxBackoff time.Duration
+}
+
+// retryableError is a failure an attempt may recover from when retried
+type retryableError struct {
+	err error
+	// retryAfter is the delay asked for by the server, if any
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// do calls attempt until it succeeds, fails with an error that is not
+// retryable or the attempts are exhausted. The last error is returned
+// without the retryable marker.
+func (p retryPolicy) do(ctx context.Context, attempt func() error) error {
+	log := logr.FromContextOrDiscard(ctx)
+	for i := 1; ; i++ {
+		err := attempt()
+		retryable, ok := err.(*retryableError)
+		if !ok {
+			return err
+		}
+		if i >= p.Attempts || retryable.retryAfter > p.MaxBackoff || ctx.Err() != nil {
+			return retryable.err
+		}
+		delay := retryable.retryAfter
+		if delay == 0 {
+			delay = p.backoff(i)
+		}
+		log.Info("retrying download", "attempt", i, "delay", delay, "error", retryable.err.Error())
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retryable.err
+		case <-timer.C:
+		}
+	}
+}
+
+// requeueAfterRetry adapts sync to requeue the resource once the delay asked
+// for by the server of a failed download elapses, rather than failing the
+// reconcile to be retried right away.
+func requeueAfterRetry(sync func(context.Context, *sourcev1alpha1.MavenArtifact) error) func(context.Context, *sourcev1alpha1.MavenArtifact) (controllerruntime.Result, error) {
+	return func(ctx context.Context, parent *sourcev1alpha1.MavenArtifact) (controllerruntime.Result, error) {
+		err := sync(ctx, parent)
+		var dlerr *downloadError
+		if errors.As(err, &dlerr) && dlerr.retryAfter > 0 {
+			logr.FromContextOrDiscard(ctx).Info("requeueing download", "delay", dlerr.retryAfter, "error", dlerr.err.Error())
+			return controllerruntime.Result{RequeueAfter: dlerr.retryAfter}, nil
+		}
+		return controllerruntime.Result{}, err
+	}
+}
+
+// backoff returns the delay before the retry following the attempt
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay - time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// responseError returns the download error for a response with an
+// unsuccessful status, retryable when the server is overloaded or failing
+func responseError(url string, response *http.Response) error {
+	err := &downloadError{err: fmt.Errorf("Error received HTTP status %v getting %q", response.StatusCode, url), httpStatuscode: response.StatusCode}
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		err.retryAfter = retryAfter(response)
+		return &retryableError{err: err, retryAfter: err.retryAfter}
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return &retryableError{err: err}
+	}
+	return err
+}
+
+// retryAfter parses the Retry-After header of the response, either a number
+// of seconds or a date
+func retryAfter(response *http.Response) time.Duration {
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// transientError marks err as retryable when its cause is a network failure
+// an attempt may recover from: a timeout, a connection reset or refused, or a
+// response cut short. Every error of the client is a *url.Error, which is
+// unwrapped so failures such as an untrusted certificate, a redirect refused
+// by the client or a malformed URL are not retried.
+func transientError(err, cause error) error {
+	var urlErr *url.Error
+	if errors.As(cause, &urlErr) {
+		cause = urlErr.Err
+	}
+	if errors.Is(cause, context.Canceled) || errors.Is(cause, context.DeadlineExceeded) {
+		return err
+	}
+	var netErr net.Error
+	if errors.As(cause, &netErr) && netErr.Timeout() {
+		return &retryableError{err: err}
+	}
+	if errors.Is(cause, syscall.ECONNRESET) || errors.Is(cause, syscall.ECONNREFUSED) || errors.Is(cause, io.ErrUnexpectedEOF) {
+		return &retryableError{err: err}
+	}
+	return err
+}
@@ -0,0 +1,361 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"reconciler.io/runtime/reconcilers"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+)
+
+// withDownloadRetries replaces the retry policy for the duration of the test
+func withDownloadRetries(t *testing.T, policy retryPolicy) {
+	original := downloadRetries
+	downloadRetries = policy
+	t.Cleanup(func() {
+		downloadRetries = original
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := retryPolicy{Attempts: 10, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, expected := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		9: 5 * time.Second,
+	} {
+		for i := 0; i < 10; i++ {
+			if actual := policy.backoff(attempt); actual < expected/2 || actual > expected {
+				t.Errorf("expected the backoff after attempt %d to be within [%s, %s], got %s", attempt, expected/2, expected, actual)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	response := func(value string) *http.Response {
+		return &http.Response{Header: http.Header{"Retry-After": []string{value}}}
+	}
+	if actual := retryAfter(response("120")); actual != 2*time.Minute {
+		t.Errorf("expected a delay of 2m, got %s", actual)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if actual := retryAfter(response(date)); actual < 59*time.Minute || actual > time.Hour {
+		t.Errorf("expected a delay of about 1h, got %s", actual)
+	}
+	for _, value := range []string{"", "-1", "soon", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)} {
+		if actual := retryAfter(response(value)); actual != 0 {
+			t.Errorf("expected no delay for %q, got %s", value, actual)
+		}
+	}
+}
+
+func TestDownloadRetries(t *testing.T) {
+	withDownloadRetries(t, retryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Second})
+	ctx := reconcilers.WithStash(context.Background())
+
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		requests   int
+		statusCode int
+		// requeueAfter is the delay before the resource is reconciled again
+		requeueAfter time.Duration
+	}{
+		{
+			name:     "recovers",
+			statuses: []int{http.StatusBadGateway, http.StatusInternalServerError},
+			requests: 3,
+		},
+		{
+			name:       "respects retry after",
+			statuses:   []int{http.StatusTooManyRequests},
+			retryAfter: "1",
+			requests:   2,
+		},
+		{
+			name:       "exhausted",
+			statuses:   []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			requests:   3,
+			statusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "retry after exceeds the backoff",
+			statuses:     []int{http.StatusTooManyRequests},
+			retryAfter:   "60",
+			requests:     1,
+			statusCode:   http.StatusTooManyRequests,
+			requeueAfter: time.Minute,
+		},
+		{
+			name:       "not retryable",
+			statuses:   []int{http.StatusNotFound},
+			requests:   1,
+			statusCode: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []time.Time{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, time.Now())
+				if len(requests) <= len(tc.statuses) {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.statuses[len(requests)-1])
+					return
+				}
+				_, _ = w.Write([]byte("content"))
+			}))
+			defer server.Close()
+
+			content, err := download(ctx, server.URL+"/maven-metadata.xml", server.Client())
+			if len(requests) != tc.requests {
+				t.Errorf("expected %d requests, got %d", tc.requests, len(requests))
+			}
+			if tc.statusCode != 0 {
+				dlerr, ok := err.(*downloadError)
+				if !ok || dlerr.httpStatuscode != tc.statusCode {
+					t.Fatalf("expected a download error with status %d, got %v", tc.statusCode, err)
+				}
+				result, rerr := requeueAfterRetry(func(context.Context, *sourcev1alpha1.MavenArtifact) error {
+					return dlerr
+				})(ctx, &sourcev1alpha1.MavenArtifact{})
+				if result.RequeueAfter != tc.requeueAfter {
+					t.Errorf("expected the resource to be requeued after %s, got %s", tc.requeueAfter, result.RequeueAfter)
+				}
+				if tc.requeueAfter == 0 && rerr != err {
+					t.Errorf("expected the download error to be returned, got %v", rerr)
+				}
+				if tc.requeueAfter != 0 && rerr != nil {
+					t.Errorf("expected no error when the resource is requeued, got %v", rerr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(content) != "content" {
+				t.Errorf("expected the content to be downloaded, got %q", content)
+			}
+			if tc.retryAfter != "" {
+				seconds, _ := strconv.Atoi(tc.retryAfter)
+				if delay := requests[1].Sub(requests[0]); delay < time.Duration(seconds)*time.Second {
+					t.Errorf("expected the request to be retried after %ss, got %s", tc.retryAfter, delay)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadRetriesTransientErrors(t *testing.T) {
+	withDownloadRetries(t, retryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Second})
+	ctx := reconcilers.WithStash(context.Background())
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		tls      bool
+		client   func(server *httptest.Server) *http.Client
+		requests int
+	}{
+		{
+			name: "connection reset",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				conn, _, err := http.NewResponseController(w).Hijack()
+				if err != nil {
+					return
+				}
+				// close the connection with a reset
+				_ = conn.(*net.TCPConn).SetLinger(0)
+				conn.Close()
+			},
+			client: func(server *httptest.Server) *http.Client {
+				return server.Client()
+			},
+			requests: 3,
+		},
+		{
+			name: "untrusted certificate",
+			tls:  true,
+			client: func(server *httptest.Server) *http.Client {
+				return &http.Client{}
+			},
+			requests: 1,
+		},
+		{
+			name: "redirect refused",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/other/maven-metadata.xml", http.StatusFound)
+			},
+			client: func(server *httptest.Server) *http.Client {
+				client := server.Client()
+				client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+					return errors.New("redirect refused")
+				}
+				return client
+			},
+			requests: 1,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := tc.handler
+			if handler == nil {
+				handler = func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write([]byte("content"))
+				}
+			}
+			server := httptest.NewUnstartedServer(handler)
+			// connections are counted as a request failing the TLS handshake
+			// never reaches the handler, each request opens a connection
+			server.Config.SetKeepAlivesEnabled(false)
+			var m sync.Mutex
+			requests := 0
+			server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					m.Lock()
+					requests++
+					m.Unlock()
+				}
+			}
+			server.Config.ErrorLog = log.New(io.Discard, "", 0)
+			if tc.tls {
+				server.StartTLS()
+			} else {
+				server.Start()
+			}
+			defer server.Close()
+
+			if _, err := download(ctx, server.URL+"/maven-metadata.xml", tc.client(server)); err == nil {
+				t.Fatalf("expected an error")
+			}
+			m.Lock()
+			defer m.Unlock()
+			if requests != tc.requests {
+				t.Errorf("expected %d requests, got %d", tc.requests, requests)
+			}
+		})
+	}
+}
+
+func TestDownloadArtifactResumes(t *testing.T) {
+	withDownloadRetries(t, retryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Second})
+	ctx := reconcilers.WithStash(context.Background())
+	content := strings.Repeat("jar content ", 1000)
+	checksum := remoteChecksum{algorithm: SHA1, checksum: fmt.Sprintf("%x", sha1.Sum([]byte(content)))}
+
+	tests := []struct {
+		name string
+		// ranges served by the repository
+		ranges bool
+		etag   string
+		// dropped is the number of bytes sent before the connection drops
+		dropped int
+		// unsatisfiable refuses the range without the size of the file
+		unsatisfiable bool
+		// expected are the ranges requested
+		expected []string
+	}{
+		{name: "resumed", ranges: true, etag: `"v1"`},
+		{name: "resumed without validator", ranges: true},
+		{name: "content changed", ranges: true, etag: `"v2"`},
+		{name: "ranges not supported"},
+		{name: "dropped after every byte", ranges: true, etag: `"v1"`, dropped: len(content), expected: []string{"", fmt.Sprintf("bytes=%d-", len(content))}},
+		{name: "range not satisfiable", ranges: true, etag: `"v1"`, unsatisfiable: true, expected: []string{"", fmt.Sprintf("bytes=%d-", len(content)/2), ""}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.dropped == 0 {
+				tc.dropped = len(content) / 2
+			}
+			if tc.expected == nil {
+				tc.expected = []string{"", fmt.Sprintf("bytes=%d-", len(content)/2)}
+			}
+			ranges := []string{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				etag := tc.etag
+				if len(ranges) == 1 && etag != "" {
+					etag = `"v1"`
+				}
+				if etag != "" {
+					w.Header().Set("ETag", etag)
+				}
+				if len(ranges) == 1 {
+					// the connection drops before the end of the chunked
+					// response, possibly after every byte of the file
+					_, _ = w.Write([]byte(content[:tc.dropped]))
+					w.(http.Flusher).Flush()
+					panic(http.ErrAbortHandler)
+				}
+				if ifRange := r.Header.Get("If-Range"); r.Header.Get("Range") != "" && tc.ranges && (ifRange == "" || ifRange == etag) {
+					var start int
+					fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start)
+					if tc.unsatisfiable {
+						w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+						return
+					}
+					if start >= len(content) {
+						w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", len(content)))
+						w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+						return
+					}
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+					w.WriteHeader(http.StatusPartialContent)
+					_, _ = w.Write([]byte(content[start:]))
+					return
+				}
+				_, _ = w.Write([]byte(content))
+			}))
+			defer server.Close()
+
+			dir, _, err := downloadArtifact(ctx, server.URL+"/app-1.0.0.jar", t.TempDir(), "app-1.0.0.jar", checksum, server.Client(), ArtifactLimits{}, nil, "")
+			if err != nil {
+				t.Fatalf("unexpected error downloading artifact: %v", err)
+			}
+			actual, _ := os.ReadFile(path.Join(dir, "app-1.0.0.jar"))
+			if string(actual) != content {
+				t.Errorf("expected the artifact to be downloaded, got %d bytes", len(actual))
+			}
+			if fmt.Sprint(ranges) != fmt.Sprint(tc.expected) {
+				t.Errorf("expected the ranges %q to be requested, got %q", tc.expected, ranges)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+)
+
+// CachePackagedImage caches the file at name as the artifact packaged from
+// the image for the artifact file name, as if pulled by another resource
+func CachePackagedImage(cache *blobcache.Cache, artifactFilename string, digestAlgorithm DigestAlgorithm, name, checksum, digest string) error {
+	return storePackagedImage(cache, packagedImageCacheKey(artifactFilename, digestAlgorithm, ArtifactLimits{}), name, &packagedImage{Checksum: checksum, Digest: digest})
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+)
+
+// cachedLayer reads the compressed content of the layer from the cache, the
+// content downloaded is added to the cache by digest once verified. Layers
+// are not cached when the cache is nil.
+func cachedLayer(layer v1.Layer, cache *blobcache.Cache) (v1.Layer, error) {
+	if cache == nil {
+		return layer, nil
+	}
+	return partial.CompressedToLayer(&blobCachedLayer{layer: layer, cache: cache})
+}
+
+type blobCachedLayer struct {
+	layer v1.Layer
+	cache *blobcache.Cache
+}
+
+func (l *blobCachedLayer) Digest() (v1.Hash, error) {
+	return l.layer.Digest()
+}
+
+func (l *blobCachedLayer) Size() (int64, error) {
+	return l.layer.Size()
+}
+
+func (l *blobCachedLayer) MediaType() (types.MediaType, error) {
+	return l.layer.MediaType()
+}
+
+func (l *blobCachedLayer) Compressed() (io.ReadCloser, error) {
+	digest, err := l.layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	file, err := l.cache.Open(digest.String())
+	if err == nil {
+		cacheRequests.WithLabelValues(cacheContentLayer, cacheHit).Inc()
+		return file, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	cacheRequests.WithLabelValues(cacheContentLayer, cacheMiss).Inc()
+	hasher, err := v1.Hasher(digest.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := l.cache.Create(digest.String())
+	if err != nil {
+		return nil, err
+	}
+	content, err := l.layer.Compressed()
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+	return &cachingReader{content: content, writer: writer, hasher: hasher, digest: digest}, nil
+}
+
+// cachingReader writes the content read to the cache, committing it once read
+// to the end when it matches the digest
+type cachingReader struct {
+	content io.ReadCloser
+	writer  *blobcache.Writer
+	hasher  hash.Hash
+	digest  v1.Hash
+	// done is set once the content is committed, or can no longer be
+	done bool
+}
+
+func (r *cachingReader) Read(p []byte) (int, error) {
+	n, err := r.content.Read(p)
+	if r.done {
+		return n, err
+	}
+	if n > 0 {
+		r.hasher.Write(p[:n])
+		if _, err := r.writer.Write(p[:n]); err != nil {
+			// a layer that cannot be cached is still read
+			r.done = true
+		}
+	}
+	if err != nil {
+		r.done = true
+		if err == io.EOF && hex.EncodeToString(r.hasher.Sum(nil)) == r.digest.Hex {
+			_ = r.writer.Commit()
+		}
+	}
+	return n, err
+}
+
+func (r *cachingReader) Close() error {
+	r.writer.Close()
+	return r.content.Close()
+}
+
+// packagedImage describes an artifact packaged from an image, reused by
+// resources packaging the same image in the same way
+type packagedImage struct {
+	Checksum string                 `json:"checksum"`
+	Digest   string                 `json:"digest"`
+	Files    []ArtifactManifestFile `json:"files"`
+}
+
+// packagedImageCacheKey identifies the artifact packaged from an image. The
+// artifact file name holds the digest of the image and of the filters, the
+// limits are part of the key as content within the limits of one resource
+// may exceed the limits of another.
+func packagedImageCacheKey(artifactFilename string, digestAlgorithm DigestAlgorithm, limits ArtifactLimits) string {
+	return fmt.Sprintf("imagerepository/%s/%s/%d-%d-%d", artifactFilename, digestAlgorithm, limits.MaxCompressedSize, limits.MaxSize, limits.MaxFiles)
+}
+
+// loadPackagedImage links the cached artifact for the key at name, returning
+// an error satisfying errors.Is(err, fs.ErrNotExist) when it is not cached
+func loadPackagedImage(cache *blobcache.Cache, key, name string) (*packagedImage, error) {
+	if cache == nil {
+		return nil, fs.ErrNotExist
+	}
+	file, err := cache.Open(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		cacheRequests.WithLabelValues(cacheContentImageArtifact, cacheMiss).Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	packaged := &packagedImage{}
+	if err := json.NewDecoder(file).Decode(packaged); err != nil {
+		return nil, err
+	}
+	if err := cache.Link(key+"/"+packaged.Checksum, name); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			cacheRequests.WithLabelValues(cacheContentImageArtifact, cacheMiss).Inc()
+		}
+		return nil, err
+	}
+	cacheRequests.WithLabelValues(cacheContentImageArtifact, cacheHit).Inc()
+	return packaged, nil
+}
+
+// storePackagedImage adds the artifact at name to the cache for the key. The
+// artifact is cached by its checksum, then described at the key, a
+// description never refers to a different artifact.
+func storePackagedImage(cache *blobcache.Cache, key, name string, packaged *packagedImage) error {
+	if cache == nil {
+		return nil
+	}
+	if err := cache.Put(key+"/"+packaged.Checksum, name); err != nil {
+		return err
+	}
+	content, err := json.Marshal(packaged)
+	if err != nil {
+		return err
+	}
+	writer, err := cache.Create(key)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+	if _, err := writer.Write(content); err != nil {
+		return err
+	}
+	return writer.Commit()
+}
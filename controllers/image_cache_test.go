@@ -0,0 +1,191 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+)
+
+func TestPullImageCachesLayers(t *testing.T) {
+	blobsServed := true
+	handler := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !blobsServed && r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			http.Error(w, "blob requested", http.StatusInternalServerError)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer registry.Close()
+
+	image := pushTestImage(t, registry, "image",
+		mutate.Addendum{
+			Layer: static.NewLayer(gzipTestTar(t, []tarEntry{
+				{name: "image.txt", contents: "image", typeflag: tar.TypeReg},
+			}), types.OCILayer),
+		},
+		mutate.Addendum{
+			Layer: static.NewLayer([]byte("key: value\n"), orasFileMediaType),
+			Annotations: map[string]string{
+				ociTitleAnnotation: "values.yaml",
+			},
+		},
+	)
+	cache, err := blobcache.New(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selectors := []*sourcev1alpha1.ImageLayerSelector{
+		{MediaType: string(types.OCILayer)},
+		{MediaType: orasFileMediaType, Operation: sourcev1alpha1.ImageLayerOperationCopy},
+	}
+
+	for _, selector := range selectors {
+		if err := pullImage(image, t.TempDir(), selector, ArtifactLimits{}, cache, remote.WithTransport(registry.Client().Transport)); err != nil {
+			t.Fatalf("unexpected error pulling image: %v", err)
+		}
+	}
+	size := cache.Size()
+	if size == 0 {
+		t.Fatalf("expected the layers to be cached")
+	}
+
+	// the manifest is still fetched from the registry
+	blobsServed = false
+	for _, selector := range selectors {
+		dir := t.TempDir()
+		if err := pullImage(image, dir, selector, ArtifactLimits{}, cache, remote.WithTransport(registry.Client().Transport)); err != nil {
+			t.Fatalf("expected the layers to be read from the cache: %v", err)
+		}
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 1 {
+			t.Errorf("expected a single file to be pulled, got %d", len(entries))
+		}
+	}
+	if cache.Size() != size {
+		t.Errorf("expected the cache size to remain %d, got %d", size, cache.Size())
+	}
+}
+
+func TestCachingReaderVerifiesDigest(t *testing.T) {
+	cache, err := blobcache.New(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the layer claims the digest of other content
+	expected, _ := static.NewLayer([]byte("original"), orasFileMediaType).Digest()
+	layer, err := cachedLayer(&digestLayer{Layer: static.NewLayer([]byte("tampered"), orasFileMediaType), digest: expected}, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := layer.Compressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(content); err != nil {
+		t.Fatal(err)
+	}
+	content.Close()
+	if _, err := cache.Open(expected.String()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected content not matching the digest not to be cached, got %v", err)
+	}
+	if cache.Size() != 0 {
+		t.Errorf("expected an empty cache, got %d bytes", cache.Size())
+	}
+}
+
+// digestLayer is a layer with the digest of other content
+type digestLayer struct {
+	v1.Layer
+	digest v1.Hash
+}
+
+func (l *digestLayer) Digest() (v1.Hash, error) {
+	return l.digest, nil
+}
+
+func TestPackagedImageCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := blobcache.New(filepath.Join(dir, "cache"), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "artifact.tar.gz")
+	if err := os.WriteFile(name, []byte("artifact"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	key := packagedImageCacheKey("0123.tar.gz", SHA256, ArtifactLimits{MaxFiles: 10})
+	packaged := &packagedImage{
+		Checksum: "4567",
+		Digest:   "sha256:4567",
+		Files:    []ArtifactManifestFile{{Path: "app.yaml", Size: 8, Mode: "0644", Digest: "sha256:89ab"}},
+	}
+
+	if _, err := loadPackagedImage(cache, key, filepath.Join(dir, "missing.tar.gz")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+	if _, err := loadPackagedImage(nil, key, filepath.Join(dir, "missing.tar.gz")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a not exist error without a cache, got %v", err)
+	}
+	if err := storePackagedImage(cache, key, name, packaged); err != nil {
+		t.Fatalf("unexpected error caching artifact: %v", err)
+	}
+
+	loaded := filepath.Join(dir, "loaded.tar.gz")
+	actual, err := loadPackagedImage(cache, key, loaded)
+	if err != nil {
+		t.Fatalf("unexpected error loading artifact: %v", err)
+	}
+	if diff := cmp.Diff(packaged, actual); diff != "" {
+		t.Errorf("loadPackagedImage() (-expected, +actual): %s", diff)
+	}
+	original, _ := os.Stat(name)
+	linked, err := os.Stat(loaded)
+	if err != nil {
+		t.Fatalf("expected the artifact to be linked: %v", err)
+	}
+	if !os.SameFile(original, linked) {
+		t.Errorf("expected the artifact to be hard linked from the cache")
+	}
+
+	// resources with other limits package the image themselves
+	if _, err := loadPackagedImage(cache, packagedImageCacheKey("0123.tar.gz", SHA256, ArtifactLimits{}), filepath.Join(dir, "other.tar.gz")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not exist error for other limits, got %v", err)
+	}
+}
@@ -0,0 +1,431 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+)
+
+// errLayerNotFound is returned when an image has no layer selected by the
+// layer selector
+var errLayerNotFound = errors.New("layer not found")
+
+// ociTitleAnnotation names the file held by a layer of an OCI artifact
+const ociTitleAnnotation = "org.opencontainers.image.title"
+
+// errUnsupportedEntry is returned when an image layer has an entry that
+// cannot be extracted, such as a device or a named pipe
+var errUnsupportedEntry = errors.New("unsupported archive entry")
+
+const (
+	// whiteoutPrefix marks an entry of an image layer removing the file of
+	// the same name extracted from earlier layers
+	whiteoutPrefix = ".wh."
+	// whiteoutOpaque marks a directory of an image layer whose content
+	// extracted from earlier layers is removed
+	whiteoutOpaque = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// pullImage extracts the layers of the image into the directory in order,
+// later layers replacing, or deleting with whiteouts, the files of earlier
+// layers. When a layer selector is set only the selected layer is
+// extracted, or copied into the directory as a file. The size of the layers
+// is checked against the limits before they are downloaded, and the
+// extracted content as it is written. Layers are read from, and added to,
+// the cache when not nil.
+func pullImage(imageRef, dir string, selector *sourcev1alpha1.ImageLayerSelector, limits ArtifactLimits, cache *blobcache.Cache, options ...remote.Option) error {
+	ref, err := name.ParseReference(imageRef, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	image, err := remote.Image(ref, options...)
+	if err != nil {
+		return err
+	}
+	var layers []v1.Layer
+	var selected v1.Descriptor
+	if selector != nil {
+		layer, descriptor, err := selectLayer(image, selector)
+		if err != nil {
+			return err
+		}
+		layers, selected = []v1.Layer{layer}, descriptor
+	} else {
+		layers, err = image.Layers()
+		if err != nil {
+			return err
+		}
+	}
+	// layers are verified against the size in the manifest as they are
+	// downloaded, a registry cannot serve more content than declared
+	size := int64(0)
+	for _, layer := range layers {
+		layerSize, err := layer.Size()
+		if err != nil {
+			return err
+		}
+		size += layerSize
+	}
+	if err := limits.checkCompressedSize(size); err != nil {
+		return err
+	}
+	for i := range layers {
+		if layers[i], err = cachedLayer(layers[i], cache); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	extraction := newExtraction(limits)
+	if selector != nil && selector.Operation == sourcev1alpha1.ImageLayerOperationCopy {
+		return copyLayer(layers[0], selected, dir, extraction)
+	}
+	for _, layer := range layers {
+		if err := extractLayer(layer, dir, extraction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectLayer returns the first layer of the image matching the media type
+// and title of the selector
+func selectLayer(image v1.Image, selector *sourcev1alpha1.ImageLayerSelector) (v1.Layer, v1.Descriptor, error) {
+	manifest, err := image.Manifest()
+	if err != nil {
+		return nil, v1.Descriptor{}, err
+	}
+	for _, descriptor := range manifest.Layers {
+		if selector.MediaType != "" && string(descriptor.MediaType) != selector.MediaType {
+			continue
+		}
+		if selector.Title != "" && descriptor.Annotations[ociTitleAnnotation] != selector.Title {
+			continue
+		}
+		layer, err := image.LayerByDigest(descriptor.Digest)
+		if err != nil {
+			return nil, v1.Descriptor{}, err
+		}
+		return layer, descriptor, nil
+	}
+	return nil, v1.Descriptor{}, fmt.Errorf("%w: no layer has %s", errLayerNotFound, describeLayerSelector(selector))
+}
+
+// describeLayerSelector describes the layers matched by the selector
+func describeLayerSelector(selector *sourcev1alpha1.ImageLayerSelector) string {
+	criteria := []string{}
+	if selector.MediaType != "" {
+		criteria = append(criteria, fmt.Sprintf("media type %q", selector.MediaType))
+	}
+	if selector.Title != "" {
+		criteria = append(criteria, fmt.Sprintf("title %q", selector.Title))
+	}
+	if len(criteria) == 0 {
+		return "any media type"
+	}
+	return strings.Join(criteria, " and ")
+}
+
+// copyLayer writes the layer, as stored in the registry, into the directory
+// as a file named by the title annotation of the layer, or by its digest. The
+// content is verified against the digest of the layer as it is written.
+func copyLayer(layer v1.Layer, descriptor v1.Descriptor, dir string, extraction *extraction) error {
+	name := descriptor.Annotations[ociTitleAnnotation]
+	if name == "" {
+		name = descriptor.Digest.Hex
+	}
+	filePath, err := safeArchiveEntryPath(dir, name)
+	if err != nil {
+		return err
+	}
+	if filePath == dir {
+		return fmt.Errorf("layer title %q is not a file name", name)
+	}
+	if err := extraction.addEntry(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+	hasher, err := v1.Hasher(descriptor.Digest.Algorithm)
+	if err != nil {
+		return err
+	}
+	content, err := layer.Compressed()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	if err := extractTarFile(io.TeeReader(content, hasher), filePath, 0o644, extraction); err != nil {
+		return fmt.Errorf("unable to copy layer %q: %w", descriptor.Digest, err)
+	}
+	if actual := hex.EncodeToString(hasher.Sum(nil)); actual != descriptor.Digest.Hex {
+		return fmt.Errorf("digest %s:%s of layer %q does not match", descriptor.Digest.Algorithm, actual, descriptor.Digest)
+	}
+	return nil
+}
+
+func extractLayer(layer v1.Layer, dir string, extraction *extraction) error {
+	content, err := layer.Uncompressed()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	if err := extractTar(content, dir, extraction); err != nil {
+		return err
+	}
+	// the layer is verified, and cached, once read to the end
+	_, err = io.Copy(io.Discard, content)
+	return err
+}
+
+// extractTar writes the entries of the tarball, an image layer, into destDir.
+// Entries outside of destDir, or written through a symlink leading outside of
+// it, and symlinks to targets outside of destDir are rejected. Hard links are
+// recreated to files extracted before them. OCI whiteouts remove the files,
+// or the content of the directory, extracted from earlier layers. Entries of
+// any other type are rejected.
+func extractTar(r io.Reader, destDir string, extraction *extraction) error {
+	// entries of the layer are not removed by the whiteouts of the layer
+	extracted := map[string]bool{}
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeGNUSparse, tar.TypeSymlink, tar.TypeLink:
+		case tar.TypeXGlobalHeader:
+			// metadata of the archive rather than an entry
+			continue
+		default:
+			return fmt.Errorf("%w: archive entry %q has type %q", errUnsupportedEntry, header.Name, header.Typeflag)
+		}
+		filePath, err := safeArchiveEntryPath(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+		if filePath == destDir {
+			// the root of the layer
+			continue
+		}
+		if err := checkEntryParent(destDir, filePath, header.Name); err != nil {
+			return err
+		}
+		if base := filepath.Base(filePath); strings.HasPrefix(base, whiteoutPrefix) {
+			if err := applyWhiteout(destDir, filePath, header.Name, extracted); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := extraction.addEntry(); err != nil {
+			return err
+		}
+		for p := filePath; p != destDir; p = filepath.Dir(p) {
+			extracted[p] = true
+		}
+
+		// later layers replace the entries of earlier layers, a symlink is
+		// replaced rather than written through
+		if err := removeReplacedEntry(filePath, header.Typeflag == tar.TypeDir); err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(filePath, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			name, err := filepath.Rel(destDir, filePath)
+			if err != nil {
+				return err
+			}
+			if err := checkSymlink(destDir, filepath.ToSlash(name), header.Linkname); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, filePath); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			if err := extractTarLink(destDir, filePath, header, extraction); err != nil {
+				return err
+			}
+		default:
+			if err := extractTarFile(tarReader, filePath, header.FileInfo().Mode().Perm()|0o600, extraction); err != nil {
+				return fmt.Errorf("unable to extract %q: %w", header.Name, err)
+			}
+		}
+	}
+}
+
+// extractTarLink links filePath to the file already extracted at the link
+// target of the header. The linked content is counted towards the limits of
+// the extraction, it is packaged as a copy.
+func extractTarLink(destDir, filePath string, header *tar.Header, extraction *extraction) error {
+	target, err := safeArchiveEntryPath(destDir, header.Linkname)
+	if err != nil {
+		return err
+	}
+	if err := checkEntryParent(destDir, target, header.Linkname); err != nil {
+		return err
+	}
+	info, err := os.Lstat(target)
+	if err != nil {
+		return fmt.Errorf("unable to extract %q: hard link target %q: %w", header.Name, header.Linkname, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: archive entry %q is a hard link to %q, which is not a file", errUnsupportedEntry, header.Name, header.Linkname)
+	}
+	if err := extraction.addSize(info.Size()); err != nil {
+		return err
+	}
+	return os.Link(target, filePath)
+}
+
+// applyWhiteout removes the file hidden by the whiteout at filePath, or the
+// content of the directory of an opaque whiteout, unless it was extracted
+// from the same layer
+func applyWhiteout(destDir, filePath, name string, extracted map[string]bool) error {
+	dir, base := filepath.Split(filePath)
+	dir = filepath.Clean(dir)
+	if base == whiteoutOpaque {
+		return removeEarlierEntries(dir, extracted)
+	}
+	hidden := strings.TrimPrefix(base, whiteoutPrefix)
+	if hidden == "" || hidden == "." || hidden == ".." {
+		return fmt.Errorf("archive entry %q is not a valid whiteout", name)
+	}
+	hiddenPath := filepath.Join(dir, hidden)
+	if !extracted[hiddenPath] {
+		return os.RemoveAll(hiddenPath)
+	}
+	// a directory of the layer hides the content of the directory of
+	// earlier layers it replaces
+	if info, err := os.Lstat(hiddenPath); err != nil || !info.IsDir() {
+		return err
+	}
+	return removeEarlierEntries(hiddenPath, extracted)
+}
+
+// removeReplacedEntry removes the entry at filePath replaced by an entry of a
+// later layer. The content of a directory replaced by a directory is merged,
+// rather than removed.
+func removeReplacedEntry(filePath string, dir bool) error {
+	info, err := os.Lstat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return os.Remove(filePath)
+	}
+	if dir {
+		return nil
+	}
+	return os.RemoveAll(filePath)
+}
+
+// removeEarlierEntries removes the content of the directory not extracted
+// from the current layer
+func removeEarlierEntries(dir string, extracted map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		entryPath := filepath.Join(dir, entry.Name())
+		if !extracted[entryPath] {
+			if err := os.RemoveAll(entryPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if entry.IsDir() {
+			if err := removeEarlierEntries(entryPath, extracted); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func extractTarFile(r io.Reader, filePath string, mode fs.FileMode, extraction *extraction) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := extraction.copy(file, r); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// checkEntryParent rejects an archive entry at filePath within destDir that
+// would be written through a symlink leading outside of destDir. The nearest
+// existing parent of the entry is resolved, parents that do not exist yet
+// are created beneath it.
+func checkEntryParent(destDir, filePath, name string) error {
+	root, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return err
+	}
+	for parent := filepath.Dir(filePath); ; parent = filepath.Dir(parent) {
+		resolved, err := filepath.EvalSymlinks(parent)
+		if errors.Is(err, fs.ErrNotExist) && withinDir(destDir, parent) && parent != destDir {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !withinDir(root, resolved) {
+			return fmt.Errorf("%w: archive entry %q is written through a symlink", errUnsafeSymlink, name)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+)
+
+const (
+	fluxContentMediaType = "application/vnd.cncf.flux.content.v1.tar+gzip"
+	orasFileMediaType    = "application/vnd.example.config.v1+yaml"
+)
+
+// pushTestImage pushes an OCI image with the layers to the registry
+func pushTestImage(t *testing.T, registry *httptest.Server, repository string, layers ...mutate.Addendum) string {
+	t.Helper()
+	host := strings.TrimPrefix(registry.URL, "http://")
+	tag, err := name.NewTag(host+"/"+repository+":latest", name.WeakValidation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := mutate.Append(mutate.MediaType(empty.Image, types.OCIManifestSchema1), layers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(tag, image, remote.WithTransport(registry.Client().Transport)); err != nil {
+		t.Fatal(err)
+	}
+	return tag.String()
+}
+
+func gzipTestTar(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	if _, err := io.Copy(gw, writeTestTar(t, entries)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPullImage(t *testing.T) {
+	registry := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer registry.Close()
+
+	imageLayer := mutate.Addendum{
+		Layer: static.NewLayer(gzipTestTar(t, []tarEntry{
+			{name: "image.txt", contents: "image", typeflag: tar.TypeReg},
+			{name: "config/", typeflag: tar.TypeDir},
+		}), types.OCILayer),
+	}
+	contentLayer := mutate.Addendum{
+		Layer: static.NewLayer(gzipTestTar(t, []tarEntry{
+			{name: "config/app.yaml", contents: "app", typeflag: tar.TypeReg},
+		}), fluxContentMediaType),
+	}
+	fileLayer := mutate.Addendum{
+		Layer: static.NewLayer([]byte("key: value\n"), orasFileMediaType),
+		Annotations: map[string]string{
+			ociTitleAnnotation: "values.yaml",
+		},
+	}
+	image := pushTestImage(t, registry, "image", imageLayer, contentLayer)
+	baseLayer := mutate.Addendum{
+		Layer: static.NewLayer(gzipTestTar(t, []tarEntry{
+			{name: "deleted.txt", contents: "deleted", typeflag: tar.TypeReg},
+			{name: "config/", typeflag: tar.TypeDir},
+			{name: "config/stale.yaml", contents: "stale", typeflag: tar.TypeReg},
+			{name: "config/nested/stale.yaml", contents: "stale", typeflag: tar.TypeReg},
+			{name: "lib/app.jar", contents: "jar", typeflag: tar.TypeReg},
+		}), types.OCILayer),
+	}
+	deleteLayer := mutate.Addendum{
+		Layer: static.NewLayer(gzipTestTar(t, []tarEntry{
+			{name: ".wh.deleted.txt", typeflag: tar.TypeReg},
+			{name: "config/.wh..wh..opq", typeflag: tar.TypeReg},
+			{name: "config/app.yaml", contents: "app", typeflag: tar.TypeReg},
+			{name: "app.jar", linkname: "lib/app.jar", typeflag: tar.TypeLink},
+			{name: "lib/.wh.missing.jar", typeflag: tar.TypeReg},
+		}), types.OCILayer),
+	}
+	layered := pushTestImage(t, registry, "layered", baseLayer, deleteLayer)
+	replaceLayer := mutate.Addendum{
+		Layer: static.NewLayer(gzipTestTar(t, []tarEntry{
+			{name: "config", contents: "config", typeflag: tar.TypeReg},
+			{name: "deleted.txt/", typeflag: tar.TypeDir},
+			{name: "deleted.txt/restored.txt", contents: "restored", typeflag: tar.TypeReg},
+			{name: "lib/", typeflag: tar.TypeDir},
+			{name: "lib/app.jar", linkname: "../deleted.txt/restored.txt", typeflag: tar.TypeSymlink},
+			{name: "lib/new.jar", contents: "new", typeflag: tar.TypeReg},
+			{name: ".wh.lib", typeflag: tar.TypeReg},
+		}), types.OCILayer),
+	}
+	replaced := pushTestImage(t, registry, "replaced", baseLayer, replaceLayer)
+	artifact := pushTestImage(t, registry, "artifact", imageLayer, contentLayer, fileLayer)
+
+	tests := []struct {
+		name        string
+		image       string
+		selector    *sourcev1alpha1.ImageLayerSelector
+		expected    map[string]string
+		expectedErr error
+	}{
+		{
+			name:  "flatten layers",
+			image: image,
+			expected: map[string]string{
+				"image.txt":       "image",
+				"config/app.yaml": "app",
+			},
+		},
+		{
+			name:  "flatten layers with whiteouts and hard links",
+			image: layered,
+			expected: map[string]string{
+				"config/app.yaml": "app",
+				"lib/app.jar":     "jar",
+				"app.jar":         "jar",
+			},
+		},
+		{
+			name:  "flatten layers replacing and deleting files and directories",
+			image: replaced,
+			expected: map[string]string{
+				"config":                   "config",
+				"deleted.txt/restored.txt": "restored",
+				"lib/app.jar":              "restored",
+				"lib/new.jar":              "new",
+			},
+		},
+		{
+			name:     "extract layer",
+			image:    artifact,
+			selector: &sourcev1alpha1.ImageLayerSelector{MediaType: fluxContentMediaType},
+			expected: map[string]string{
+				"config/app.yaml": "app",
+			},
+		},
+		{
+			name:     "copy layer",
+			image:    artifact,
+			selector: &sourcev1alpha1.ImageLayerSelector{MediaType: orasFileMediaType, Operation: sourcev1alpha1.ImageLayerOperationCopy},
+			expected: map[string]string{
+				"values.yaml": "key: value\n",
+			},
+		},
+		{
+			name:     "copy layer by title",
+			image:    artifact,
+			selector: &sourcev1alpha1.ImageLayerSelector{Title: "values.yaml", Operation: sourcev1alpha1.ImageLayerOperationCopy},
+			expected: map[string]string{
+				"values.yaml": "key: value\n",
+			},
+		},
+		{
+			name:        "layer not found",
+			image:       artifact,
+			selector:    &sourcev1alpha1.ImageLayerSelector{MediaType: "application/vnd.example.missing"},
+			expectedErr: errLayerNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "artifact")
+			err := pullImage(tc.image, dir, tc.selector, ArtifactLimits{}, nil, remote.WithTransport(registry.Client().Transport))
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error pulling image: %v", err)
+			}
+			actual := map[string]string{}
+			err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				content, err := os.ReadFile(p)
+				if err != nil {
+					return err
+				}
+				rel, _ := filepath.Rel(dir, p)
+				actual[filepath.ToSlash(rel)] = string(content)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("pullImage() (-expected, +actual): %s", diff)
+			}
+		})
+	}
+}
+
+func TestSelectLayer(t *testing.T) {
+	image, err := mutate.Append(mutate.MediaType(empty.Image, types.OCIManifestSchema1),
+		mutate.Addendum{Layer: static.NewLayer([]byte("first"), orasFileMediaType), Annotations: map[string]string{ociTitleAnnotation: "first.yaml"}},
+		mutate.Addendum{Layer: static.NewLayer([]byte("second"), orasFileMediaType), Annotations: map[string]string{ociTitleAnnotation: "second.yaml"}},
+		mutate.Addendum{Layer: static.NewLayer([]byte("third"), types.OCILayer)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		selector    *sourcev1alpha1.ImageLayerSelector
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "first layer",
+			selector: &sourcev1alpha1.ImageLayerSelector{},
+			expected: "first",
+		},
+		{
+			name:     "media type",
+			selector: &sourcev1alpha1.ImageLayerSelector{MediaType: string(types.OCILayer)},
+			expected: "third",
+		},
+		{
+			name:     "title",
+			selector: &sourcev1alpha1.ImageLayerSelector{Title: "second.yaml"},
+			expected: "second",
+		},
+		{
+			name:        "media type and title",
+			selector:    &sourcev1alpha1.ImageLayerSelector{MediaType: string(types.OCILayer), Title: "second.yaml"},
+			expectedErr: `layer not found: no layer has media type "application/vnd.oci.image.layer.v1.tar+gzip" and title "second.yaml"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			layer, descriptor, err := selectLayer(image, tc.selector)
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error selecting layer: %v", err)
+			}
+			digest, _ := layer.Digest()
+			if digest != descriptor.Digest {
+				t.Errorf("expected the layer of the descriptor, got %s", digest)
+			}
+			expected, _ := static.NewLayer([]byte(tc.expected), types.OCILayer).Digest()
+			if digest != expected {
+				t.Errorf("expected layer %s, got %s", expected, digest)
+			}
+		})
+	}
+}
+
+func TestCopyLayerVerifiesDigest(t *testing.T) {
+	layer := static.NewLayer([]byte("tampered"), orasFileMediaType)
+	expected, _ := static.NewLayer([]byte("original"), orasFileMediaType).Digest()
+	descriptor := v1.Descriptor{
+		MediaType:   orasFileMediaType,
+		Digest:      expected,
+		Annotations: map[string]string{ociTitleAnnotation: "app.bin"},
+	}
+
+	err := copyLayer(layer, descriptor, t.TempDir(), newExtraction(ArtifactLimits{}))
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("expected a digest mismatch error, got %v", err)
+	}
+}
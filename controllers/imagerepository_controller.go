@@ -17,10 +17,10 @@ limitations under the License.
 package controllers
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"context"
-	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -30,11 +30,11 @@ import (
 	"path/filepath"
 	"strings"
 
-	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
-	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/Masterminds/semver/v3"
 	"github.com/go-logr/logr"
 	"github.com/google/go-containerregistry/pkg/authn/k8schain"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
@@ -47,6 +47,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 
 	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/pathfilter"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/signedurl"
 )
 
 //+kubebuilder:rbac:groups=source.apps.tanzu.vmware.com,resources=imagerepositories,verbs=get;list;watch;create;update;patch;delete
@@ -55,7 +57,7 @@ import (
 //+kubebuilder:rbac:groups=core;events.k8s.io,resources=events,verbs=get;list;watch;create;update;patch;delete
 
 // ImageRepositoryReconciler reconciles a ImageRepository object
-func ImageRepositoryReconciler(c reconcilers.Config, httpRootDir, httpHost string, now func() metav1.Time, certs []Cert) *reconcilers.ResourceReconciler[*sourcev1alpha1.ImageRepository] {
+func ImageRepositoryReconciler(c reconcilers.Config, storage *ArtifactStorage, digestAlgorithm DigestAlgorithm, now func() metav1.Time, certs []Cert) *reconcilers.ResourceReconciler[*sourcev1alpha1.ImageRepository] {
 	return &reconcilers.ResourceReconciler[*sourcev1alpha1.ImageRepository]{
 		Reconciler: &reconcilers.WithFinalizer[*sourcev1alpha1.ImageRepository]{
 			Finalizer: sourcev1alpha1.Group + "/finalizer",
@@ -63,8 +65,9 @@ func ImageRepositoryReconciler(c reconcilers.Config, httpRootDir, httpHost strin
 				ImageRepositoryTransportSyncReconciler(certs),
 				ImageRepositoryImagePullSecretsSyncReconciler(),
 				ImageRepositoryImageDigestSyncReconciler(),
-				ImageRepositoryPullImageSyncReconciler(httpRootDir, httpHost, now),
-				ImageRepositoryIntervalReconciler(),
+				ImageRepositorySignatureSyncReconciler(),
+				ImageRepositoryPullImageSyncReconciler(storage, digestAlgorithm, now),
+				ImageRepositoryIntervalReconciler(storage),
 			},
 		},
 
@@ -155,11 +158,24 @@ func ImageRepositoryImageDigestSyncReconciler() reconcilers.SubReconciler[*sourc
 		Sync: func(ctx context.Context, parent *sourcev1alpha1.ImageRepository) error {
 			log := logr.FromContextOrDiscard(ctx)
 
-			_, err := name.NewDigest(parent.Spec.Image, name.WeakValidation)
-			if err == nil {
-				// image already resolved to digest
-				StashImageRef(ctx, parent.Spec.Image)
-				return nil
+			if parent.Spec.TagPolicy == nil {
+				parent.Status.Tag = ""
+
+				_, err := name.NewDigest(parent.Spec.Image, name.WeakValidation)
+				if err == nil && parent.Spec.Platform == "" {
+					// image already resolved to digest
+					StashImageRef(ctx, parent.Spec.Image)
+					return nil
+				}
+			}
+			var platform *v1.Platform
+			if parent.Spec.Platform != "" {
+				var err error
+				platform, err = v1.ParsePlatform(parent.Spec.Platform)
+				if err != nil || platform.OS == "" || platform.Architecture == "" {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "InvalidPlatform", "Platform %q must be of the form os/arch[/variant]", parent.Spec.Platform)
+					return nil
+				}
 			}
 
 			// resolve tagged image to digest
@@ -171,34 +187,256 @@ func ImageRepositoryImageDigestSyncReconciler() reconcilers.SubReconciler[*sourc
 			if err != nil {
 				return err
 			}
-			tag, err := name.NewTag(parent.Spec.Image, name.WeakValidation)
-			if err != nil {
-				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "MalformedRepository", "Image name %q failed validation: %s", parent.Spec.Image, err)
+			remoteOptions := []remote.Option{
+				remote.WithTransport(RetrieveHttpRoundTripper(ctx)),
+				remote.WithContext(ctx),
+				remote.WithAuthFromKeychain(keychain),
+			}
+
+			// the repository and tag the digest is resolved for
+			var repository string
+			var ref name.Reference
+			if parent.Spec.TagPolicy != nil {
+				repo, err := name.NewRepository(parent.Spec.Image, name.WeakValidation)
+				if err != nil {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "MalformedRepository", "Image name %q failed validation: %s", parent.Spec.Image, err)
+					return nil
+				}
+				constraint, err := semver.NewConstraint(parent.Spec.TagPolicy.SemVer)
+				if err != nil {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "InvalidTagPolicy", "Tag policy semver %q is invalid: %s", parent.Spec.TagPolicy.SemVer, err)
+					return nil
+				}
+				tags, err := remote.List(repo, remoteOptions...)
+				if err != nil {
+					log.Error(err, "unable to list image tags", "image", parent.Spec.Image)
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "RemoteError", "Unable to list tags for repository %q: %s", parent.Spec.Image, err)
+					return nil
+				}
+				selected := selectImageTag(constraint, parent.Spec.TagPolicy.Prerelease, tags)
+				if selected == "" {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "NoMatchingTag", "No tag in repository %q matches semver %q", parent.Spec.Image, parent.Spec.TagPolicy.SemVer)
+					return nil
+				}
+				parent.Status.Tag = selected
+				tag := repo.Tag(selected)
+				repository, ref = tag.Name(), tag
+			} else if digest, err := name.NewDigest(parent.Spec.Image, name.WeakValidation); err == nil {
+				// a digest is only resolved further to select a platform
+				repository, ref = digest.Context().Name(), digest
+			} else {
+				tag, err := name.NewTag(parent.Spec.Image, name.WeakValidation)
+				if err != nil {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "MalformedRepository", "Image name %q failed validation: %s", parent.Spec.Image, err)
+					return nil
+				}
+				repository, ref = tag.Name(), tag
+			}
+			if platform == nil {
+				image, err := remote.Head(ref, remoteOptions...)
+				if err != nil {
+					// TODO(scothis) handle 403s and 404s as special errors
+					log.Error(err, "unable to resolve image tag to a digest", "image", parent.Spec.Image)
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "RemoteError", "Unable to resolve image with tag %q to a digest: %s", parent.Spec.Image, err)
+					return nil
+				}
+
+				StashImageRef(ctx, fmt.Sprintf("%s@%s", repository, image.Digest))
+
 				return nil
 			}
-			image, err := remote.Head(tag, remote.WithTransport(RetrieveHttpRoundTripper(ctx)), remote.WithContext(ctx), remote.WithAuthFromKeychain(keychain))
+
+			// select the image for the platform from an index
+			descriptor, err := remote.Get(ref, remoteOptions...)
 			if err != nil {
-				// TODO(scothis) handle 403s and 404s as special errors
 				log.Error(err, "unable to resolve image tag to a digest", "image", parent.Spec.Image)
 				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "RemoteError", "Unable to resolve image with tag %q to a digest: %s", parent.Spec.Image, err)
 				return nil
 			}
+			if !descriptor.MediaType.IsIndex() {
+				StashImageRef(ctx, fmt.Sprintf("%s@%s", repository, descriptor.Digest))
+				return nil
+			}
+			index, err := descriptor.ImageIndex()
+			if err != nil {
+				return err
+			}
+			indexManifest, err := index.IndexManifest()
+			if err != nil {
+				log.Error(err, "unable to read image index", "image", parent.Spec.Image)
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "RemoteError", "Unable to read image index %q: %s", parent.Spec.Image, err)
+				return nil
+			}
+			manifest := selectPlatformManifest(indexManifest, *platform)
+			if manifest == nil {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "PlatformNotFound", "Image index %q has no image for platform %q", parent.Spec.Image, parent.Spec.Platform)
+				return nil
+			}
 
-			StashImageRef(ctx, fmt.Sprintf("%s@%s", tag.Name(), image.Digest))
+			StashImageRef(ctx, fmt.Sprintf("%s@%s", repository, manifest.Digest))
+			StashImageIndexDigest(ctx, descriptor.Digest.String())
 
 			return nil
 		},
 	}
 }
 
-func ImageRepositoryPullImageSyncReconciler(httpRootDir, httpHost string, now func() metav1.Time) reconcilers.SubReconciler[*sourcev1alpha1.ImageRepository] {
+// selectPlatformManifest returns the first image of the index satisfying the
+// platform, or nil when no image matches. Nested indexes are not searched.
+func selectPlatformManifest(index *v1.IndexManifest, platform v1.Platform) *v1.Descriptor {
+	for i := range index.Manifests {
+		manifest := &index.Manifests[i]
+		if manifest.MediaType.IsIndex() || manifest.Platform == nil {
+			continue
+		}
+		if manifest.Platform.Satisfies(platform) {
+			return manifest
+		}
+	}
+	return nil
+}
+
+// imageRevision is the revision of the artifact packaged from the image. The
+// revision of an image selected from an index records the digest of the index
+// followed by the digest of the image, "<repository>@<index digest>/<image
+// digest>".
+func imageRevision(imageRef, indexDigest string) string {
+	if indexDigest == "" {
+		return imageRef
+	}
+	repository, digest, _ := strings.Cut(imageRef, "@")
+	return fmt.Sprintf("%s@%s/%s", repository, indexDigest, digest)
+}
+
+// selectImageTag returns the highest tag that is a semantic version satisfying
+// the constraint, or an empty string when no tag matches.
+func selectImageTag(constraint *semver.Constraints, prerelease *sourcev1alpha1.ImageTagPrereleaseFilter, tags []string) string {
+	var identifiers sets.Set[string]
+	if prerelease != nil {
+		// copy the constraint so the caller's value is not mutated
+		c := *constraint
+		c.IncludePrerelease = true
+		constraint = &c
+		identifiers = sets.New(prerelease.Identifiers...)
+	}
+
+	var selected *semver.Version
+	selectedTag := ""
+	for _, tag := range tags {
+		version, err := semver.NewVersion(tag)
+		if err != nil {
+			// not a semver tag
+			continue
+		}
+		if version.Prerelease() != "" && identifiers.Len() != 0 {
+			identifier, _, _ := strings.Cut(version.Prerelease(), ".")
+			if !identifiers.Has(identifier) {
+				continue
+			}
+		}
+		if !constraint.Check(version) {
+			continue
+		}
+		if selected == nil || version.GreaterThan(selected) {
+			selected = version
+			selectedTag = tag
+		}
+	}
+
+	return selectedTag
+}
+
+func ImageRepositorySignatureSyncReconciler() reconcilers.SubReconciler[*sourcev1alpha1.ImageRepository] {
+	return &reconcilers.SyncReconciler[*sourcev1alpha1.ImageRepository]{
+		Name: "ImageRepositorySignatureSyncReconciler",
+		Sync: func(ctx context.Context, parent *sourcev1alpha1.ImageRepository) error {
+			log := logr.FromContextOrDiscard(ctx)
+
+			if parent.Spec.Verify == nil {
+				parent.ManageConditions().MarkTrue(sourcev1alpha1.ImageRepositoryConditionSignatureVerified, "NotRequired", "")
+				return nil
+			}
+
+			imageRef := RetrieveImageRef(ctx)
+			if imageRef == "" {
+				return nil
+			}
+			digest, err := name.NewDigest(imageRef, name.WeakValidation)
+			if err != nil {
+				// the malformed digest is reported when pulling the image
+				return nil
+			}
+			// a signed index covers the images it lists, an image selected from
+			// an unsigned index may be signed itself
+			digests := []name.Digest{digest}
+			if indexDigest := RetrieveImageIndexDigest(ctx); indexDigest != "" {
+				digests = []name.Digest{digest.Context().Digest(indexDigest), digest}
+			}
+
+			// lookup trusted keys and certificates
+			c := reconcilers.RetrieveConfigOrDie(ctx)
+			secretName := parent.Spec.Verify.SecretRef.Name
+			secret := corev1.Secret{}
+			if err := c.TrackAndGet(ctx, types.NamespacedName{Namespace: parent.Namespace, Name: secretName}, &secret); err != nil {
+				if apierrs.IsNotFound(err) {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionSignatureVerified, "SecretMissing", "Secret %q not found in namespace %q", secretName, parent.Namespace)
+					return nil
+				}
+				return err
+			}
+			verifier, err := newCosignVerifier(secret, parent.Spec.Verify.Keyless)
+			if err != nil {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionSignatureVerified, "InvalidSecret", "Secret %q cannot be used to verify signatures: %s", secretName, err)
+				return nil
+			}
+
+			pullSecrets := RetrieveImagePullSecrets(ctx)
+			if pullSecrets == nil {
+				return nil
+			}
+			keychain, err := k8schain.NewFromPullSecrets(ctx, pullSecrets)
+			if err != nil {
+				return err
+			}
+			var verifyErr error
+			for _, digest := range digests {
+				signatures, err := fetchCosignSignatures(digest,
+					remote.WithTransport(RetrieveHttpRoundTripper(ctx)),
+					remote.WithContext(ctx),
+					remote.WithAuthFromKeychain(keychain),
+				)
+				if errors.Is(err, errNoSignatures) {
+					continue
+				}
+				if err != nil {
+					log.Error(err, "unable to fetch image signatures", "image", imageRef)
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionSignatureVerified, "RemoteError", "Unable to fetch signatures for image %q: %s", imageRef, err)
+					return nil
+				}
+				if verifyErr = verifier.Verify(digest, signatures); verifyErr == nil {
+					parent.ManageConditions().MarkTrue(sourcev1alpha1.ImageRepositoryConditionSignatureVerified, "Verified", "")
+					return nil
+				}
+			}
+			if verifyErr != nil {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionSignatureVerified, "VerificationFailed", "Unable to verify signatures for image %q: %s", imageRef, verifyErr)
+				return nil
+			}
+			parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionSignatureVerified, "SignatureMissing", "No cosign signatures found for image %q", imageRef)
+
+			return nil
+		},
+	}
+}
+
+func ImageRepositoryPullImageSyncReconciler(storage *ArtifactStorage, digestAlgorithm DigestAlgorithm, now func() metav1.Time) reconcilers.SubReconciler[*sourcev1alpha1.ImageRepository] {
 	return &reconcilers.SyncReconciler[*sourcev1alpha1.ImageRepository]{
 		Name: "ImageRepositoryPullImageSyncReconciler",
 		Finalize: func(ctx context.Context, parent *sourcev1alpha1.ImageRepository) error {
 			log := logr.FromContextOrDiscard(ctx)
-			dir := path.Join(httpRootDir, "imagerepository", parent.Namespace, parent.Name)
+			dir := path.Join("imagerepository", parent.Namespace, parent.Name)
 			log.Info("remove artifacts", "dir", dir)
-			return os.RemoveAll(dir)
+			return storage.Remove(ctx, dir)
 		},
 		Sync: func(ctx context.Context, parent *sourcev1alpha1.ImageRepository) error {
 			log := logr.FromContextOrDiscard(ctx)
@@ -207,27 +445,55 @@ func ImageRepositoryPullImageSyncReconciler(httpRootDir, httpHost string, now fu
 			if imageRef == "" {
 				return nil
 			}
+			if apis.ConditionIsFalse(parent.ManageConditions().GetCondition(sourcev1alpha1.ImageRepositoryConditionSignatureVerified)) {
+				// never package an image whose signature is not trusted
+				return nil
+			}
 			digest, err := name.NewDigest(imageRef, name.WeakValidation)
 			if err != nil {
 				log.Error(err, "unable to parse image digest", "image", imageRef)
 				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "MalformedDigest", "Image reference %q is not a valid digest: %s", imageRef, err)
 				return nil
 			}
-			_, digestHex, _ := strings.Cut(digest.DigestStr(), ":")
-			artifactTgzFilename := fmt.Sprintf("%s.tar.gz", digestHex)
-			if !path.IsAbs(httpRootDir) {
-				httpRootDir = path.Join(httpRootDir)
+			filter, err := pathfilter.New(parent.Spec.Include, parent.Spec.Ignore)
+			if err != nil {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "InvalidFilter", "Include and ignore patterns are invalid: %s", err)
+				return nil
+			}
+			if parent.Spec.Path != "" && !filepath.IsLocal(filepath.FromSlash(parent.Spec.Path)) {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "InvalidPath", "Path %q must be relative to the image and must not contain \"..\"", parent.Spec.Path)
+				return nil
 			}
+			revision := imageRevision(imageRef, RetrieveImageIndexDigest(ctx))
+			_, digestHex, _ := strings.Cut(digest.DigestStr(), ":")
+			artifactTgzFilename := digestHex + artifactFilterSuffix(&parent.Spec) + parent.Spec.Format.Extension()
 			httpPath := fmt.Sprintf("imagerepository/%s/%s/%s", parent.Namespace, parent.Name, artifactTgzFilename)
-			httpUrl := fmt.Sprintf("http://%s/%s", httpHost, httpPath)
+			httpUrl, err := storage.URL(ctx, httpPath, parent.Status.URL)
+			if err != nil {
+				return err
+			}
 
-			if _, err := os.Stat(path.Join(httpRootDir, httpPath)); err == nil && httpUrl == parent.Status.URL && httpUrl == parent.Status.Artifact.URL {
+			if _, err := storage.Backend.Stat(ctx, httpPath); err == nil && signedurl.Unsigned(httpUrl) == signedurl.Unsigned(parent.Status.URL) && signedurl.Unsigned(httpUrl) == signedurl.Unsigned(parent.Status.Artifact.URL) && digestAlgorithm.Matches(parent.Status.Artifact.Digest) && parent.Status.Artifact.Revision == revision {
 				log.Info("artifact already exists, skipping", "image", imageRef)
+				// signed URLs are refreshed before they expire
+				parent.Status.URL = httpUrl
+				parent.Status.Artifact.URL = httpUrl
 				if apis.ConditionIsUnknown(parent.ManageConditions().GetCondition(sourcev1alpha1.ImageRepositoryConditionImageResolved)) {
 					// if we made it this far with the ImageResolved condition as Unknown, it's actually True
 					parent.ManageConditions().MarkTrue(sourcev1alpha1.ImageRepositoryConditionImageResolved, "Resolved", "")
 				}
 				parent.ManageConditions().MarkTrue(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "Available", "")
+				if err := storage.Index(ctx, parent.Status.Artifact); err != nil {
+					return err
+				}
+				latestUrl, err := storage.LatestURL(ctx, parent.Status.Artifact, parent.Status.LatestURL)
+				if err != nil {
+					return err
+				}
+				parent.Status.LatestURL = latestUrl
+				if err := storage.Prune(ctx, httpPath, parent.Spec.RevisionHistoryLimit); err != nil {
+					log.Error(err, "unable to prune artifact revisions", "image", imageRef)
+				}
 				return nil
 			}
 
@@ -238,11 +504,54 @@ func ImageRepositoryPullImageSyncReconciler(httpRootDir, httpHost string, now fu
 			}
 			defer os.RemoveAll(dir)
 
-			log.Info("pulling image", "image", imageRef, "directory", dir)
-
 			artifactDir := path.Join(dir, "artifact")
 			artifactTgz := path.Join(dir, artifactTgzFilename)
 
+			// store the packaged artifact to be served
+			storeArtifact := func(packaged *packagedImage) error {
+				// the manifest is stored first, an artifact is never served
+				// without it
+				stored := now().Rfc3339Copy()
+				if err := storage.PutManifest(ctx, httpPath, &ArtifactManifest{Revision: revision, Stored: &stored, Files: packaged.Files}); err != nil {
+					return err
+				}
+
+				// store artifact.tgz to be served
+				if err := storage.Put(ctx, httpPath, artifactTgz, packaged.Digest); err != nil {
+					return err
+				}
+
+				parent.Status.Artifact = preserveArtifactLastUpdateTime(parent.Status.Artifact, &sourcev1alpha1.Artifact{
+					Checksum:       packaged.Checksum,
+					Digest:         packaged.Digest,
+					Revision:       revision,
+					Path:           httpPath,
+					URL:            httpUrl,
+					LastUpdateTime: now().Rfc3339Copy(),
+				})
+				parent.Status.URL = httpUrl
+
+				parent.ManageConditions().MarkTrue(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "Available", "")
+
+				// point the latest alias at the new artifact
+				if err := storage.Index(ctx, parent.Status.Artifact); err != nil {
+					return err
+				}
+				latestUrl, err := storage.LatestURL(ctx, parent.Status.Artifact, parent.Status.LatestURL)
+				if err != nil {
+					return err
+				}
+				parent.Status.LatestURL = latestUrl
+
+				// older revisions are retained for consumers that have not yet
+				// observed the new artifact, pruning is retried on the next reconcile
+				if err := storage.Prune(ctx, httpPath, parent.Spec.RevisionHistoryLimit); err != nil {
+					log.Error(err, "unable to prune artifact revisions", "image", imageRef)
+				}
+
+				return nil
+			}
+
 			pullSecrets := RetrieveImagePullSecrets(ctx)
 			if pullSecrets == nil {
 				return nil
@@ -251,69 +560,149 @@ func ImageRepositoryPullImageSyncReconciler(httpRootDir, httpHost string, now fu
 			if err != nil {
 				return err
 			}
-			reg, err := registry.NewBasicRegistry(
+			remoteOptions := []remote.Option{
 				remote.WithContext(ctx),
 				remote.WithAuthFromKeychain(keychain),
 				remote.WithTransport(RetrieveHttpRoundTripper(ctx)),
-			)
+			}
 
-			if err != nil {
-				return err
+			// reuse the artifact packaged from the image for another resource
+			limits := storage.Limits.Tighten(parent.Spec.Limits)
+			cacheKey := packagedImageCacheKey(artifactTgzFilename, digestAlgorithm, limits)
+			packaged, err := loadPackagedImage(storage.Cache, cacheKey, artifactTgz)
+			if err == nil {
+				// the other resource may have credentials for the image this
+				// resource does not have
+				if _, err := remote.Head(digest, remoteOptions...); err != nil {
+					log.Error(err, "unable to read image", "image", imageRef)
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "RemoteError", "unable to pull image %q: %s", parent.Spec.Image, err)
+					return nil
+				}
+				log.Info("reusing cached artifact", "image", imageRef)
+				parent.ManageConditions().MarkTrue(sourcev1alpha1.ImageRepositoryConditionImageResolved, "Resolved", "")
+				return storeArtifact(packaged)
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				log.Error(err, "unable to read cached artifact", "image", imageRef)
 			}
-			if err := plainimage.NewPlainImage(imageRef, reg).Pull(artifactDir, NewNoopLogger()); err != nil {
+
+			log.Info("pulling image", "image", imageRef, "directory", dir)
+
+			err = pullImage(imageRef, artifactDir, parent.Spec.LayerSelector, limits, storage.Cache, remoteOptions...)
+			if errors.Is(err, errArtifactTooLarge) {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "ArtifactTooLarge", "Image %q is too large: %s", imageRef, err)
+				return nil
+			}
+			if errors.Is(err, errLayerNotFound) {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "LayerNotFound", "Image %q has no layer with %s", imageRef, describeLayerSelector(parent.Spec.LayerSelector))
+				return nil
+			}
+			if errors.Is(err, errUnsafeSymlink) {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "UnsafeSymlink", "unable to package image %q: %s", parent.Spec.Image, err)
+				return nil
+			}
+			if errors.Is(err, errUnsupportedEntry) {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "UnsupportedEntry", "unable to package image %q: %s", parent.Spec.Image, err)
+				return nil
+			}
+			if err != nil {
 				// TODO distinguish forbidden and not found errors
-				log.Error(err, "unable to pull imgpkg image", "image", imageRef)
+				log.Error(err, "unable to pull image", "image", imageRef)
 				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionImageResolved, "RemoteError", "unable to pull image %q: %s", parent.Spec.Image, err)
 				return nil
 			}
 			parent.ManageConditions().MarkTrue(sourcev1alpha1.ImageRepositoryConditionImageResolved, "Resolved", "")
 
-			// package directory as tgz
-			if err := createTarGz(artifactDir, artifactTgz); err != nil {
-				log.Error(err, "error creating tarball", "dir", artifactDir, "file", artifactTgz)
-				return fmt.Errorf("error creating tarball: %w", err)
+			// select the files to package
+			if parent.Spec.Path != "" {
+				artifactDir, err = imageSubdirectory(artifactDir, parent.Spec.Path)
+				if errors.Is(err, errUnsafeSymlink) {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "UnsafeSymlink", "unable to package image %q: %s", parent.Spec.Image, err)
+					return nil
+				}
+				if errors.Is(err, fs.ErrNotExist) {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "PathNotFound", "Path %q is not a directory in image %q", parent.Spec.Path, imageRef)
+					return nil
+				}
+				if err != nil {
+					return err
+				}
 			}
-
-			// create sha1 checksum for artifact.tgz
-			checksum, err := sha1Checksum(artifactTgz)
-			if err != nil {
-				return err
+			if err := filter.Apply(artifactDir); err != nil {
+				log.Error(err, "error filtering image content", "dir", artifactDir)
+				return fmt.Errorf("error filtering image content: %w", err)
 			}
 
-			// copy artifact.tgz into httpRoot with a placeholder name
-			if err := copyFile(artifactTgz, path.Join(httpRootDir, fmt.Sprintf("%s.new", httpPath))); err != nil {
-				return err
+			// package directory in the requested format
+			checksum, artifactDigest, files, err := createArchive(artifactDir, artifactTgz, parent.Spec.Format, digestAlgorithm)
+			if errors.Is(err, errUnsafeSymlink) {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "UnsafeSymlink", "unable to package image %q: %s", parent.Spec.Image, err)
+				return nil
 			}
-			// rename placeholder file to match the final desired name
-			if err := os.Rename(path.Join(httpRootDir, fmt.Sprintf("%s.new", httpPath)), path.Join(httpRootDir, httpPath)); err != nil {
-				return err
+			if err != nil {
+				log.Error(err, "error creating tarball", "dir", artifactDir, "file", artifactTgz)
+				return fmt.Errorf("error creating tarball: %w", err)
 			}
 
-			parent.Status.Artifact = preserveArtifactLastUpdateTime(parent.Status.Artifact, &sourcev1alpha1.Artifact{
-				Checksum:       checksum,
-				Revision:       imageRef,
-				Path:           httpPath,
-				URL:            httpUrl,
-				LastUpdateTime: now().Rfc3339Copy(),
-			})
-			parent.Status.URL = httpUrl
-
-			parent.ManageConditions().MarkTrue(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable, "Available", "")
-
-			return nil
+			packaged = &packagedImage{Checksum: checksum, Digest: artifactDigest, Files: files}
+			if err := storePackagedImage(storage.Cache, cacheKey, artifactTgz, packaged); err != nil {
+				log.Error(err, "unable to cache artifact", "image", imageRef)
+			}
+			return storeArtifact(packaged)
 		},
 	}
 }
 
-func ImageRepositoryIntervalReconciler() reconcilers.SubReconciler[*sourcev1alpha1.ImageRepository] {
+func ImageRepositoryIntervalReconciler(storage *ArtifactStorage) reconcilers.SubReconciler[*sourcev1alpha1.ImageRepository] {
 	return &reconcilers.SyncReconciler[*sourcev1alpha1.ImageRepository]{
 		Name: "ImageRepositoryIntervalReconciler",
 		SyncWithResult: func(ctx context.Context, parent *sourcev1alpha1.ImageRepository) (controllerruntime.Result, error) {
-			return controllerruntime.Result{RequeueAfter: parent.Spec.Interval.Duration}, nil
+			return controllerruntime.Result{RequeueAfter: requeueInterval(parent.Spec.Interval.Duration, storage)}, nil
 		},
 	}
 }
 
+// artifactFilterSuffix distinguishes the artifacts of an image packaged with
+// different filters or layer selectors, the content and checksum of the
+// artifact depend on them. Unfiltered artifacts have no suffix.
+func artifactFilterSuffix(spec *sourcev1alpha1.ImageRepositorySpec) string {
+	if spec.Path == "" && len(spec.Include) == 0 && len(spec.Ignore) == 0 && spec.LayerSelector == nil {
+		return ""
+	}
+	filter, _ := json.Marshal(struct {
+		Path          string                             `json:"path"`
+		Include       []string                           `json:"include"`
+		Ignore        []string                           `json:"ignore"`
+		LayerSelector *sourcev1alpha1.ImageLayerSelector `json:"layerSelector,omitempty"`
+	}{spec.Path, spec.Include, spec.Ignore, spec.LayerSelector})
+	return fmt.Sprintf("-%x", sha256.Sum256(filter))[:13]
+}
+
+// imageSubdirectory resolves the directory at the slash separated path within
+// the image content at the root. The directory must not resolve outside of
+// the root through a symlink.
+func imageSubdirectory(root, name string) (string, error) {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		return "", err
+	}
+	if !withinDir(root, dir) {
+		return "", fmt.Errorf("%w: %q resolves outside of the image", errUnsafeSymlink, name)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%w: %q is not a directory", fs.ErrNotExist, name)
+	}
+	return dir, nil
+}
+
 func preserveArtifactLastUpdateTime(current, desired *sourcev1alpha1.Artifact) *sourcev1alpha1.Artifact {
 	if current == nil {
 		return desired
@@ -330,96 +719,24 @@ func preserveArtifactLastUpdateTime(current, desired *sourcev1alpha1.Artifact) *
 	return desired
 }
 
-func createTarGz(dir, name string) error {
-	file, err := os.Create(name)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	gzipWriter := gzip.NewWriter(file)
-	defer gzipWriter.Close()
-
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
-
-	return filepath.WalkDir(dir, func(fp string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
-
-		file, err := os.Open(fp)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-
-		info, err := file.Stat()
-		if err != nil {
-			return nil
-		}
-		name, err := filepath.Rel(dir, fp)
-		if err != nil {
-			return err
-		}
-		header, err := tar.FileInfoHeader(info, name)
-		header.Name = name
-		header.Uid = 0
-		header.Gid = 0
-		header.Uname = ""
-		header.Gname = ""
-
-		if err != nil {
-			return err
-		}
-		if err := tarWriter.WriteHeader(header); err != nil {
-			return err
-		}
-
-		if _, err := io.Copy(tarWriter, file); err != nil {
-			return err
-		}
-
-		return nil
-	})
+func sha1Checksum(name string) (string, error) {
+	return fileChecksum(name, SHA1)
 }
 
-func sha1Checksum(name string) (string, error) {
-	artifactTgzFile, err := os.Open(name)
+// fileChecksum returns the hex encoded checksum of the file's content
+func fileChecksum(name string, algorithm DigestAlgorithm) (string, error) {
+	file, err := os.Open(name)
 	if err != nil {
 		return "", err
 	}
-	defer artifactTgzFile.Close()
-	checksum := sha1.New()
-	if _, err := io.Copy(checksum, artifactTgzFile); err != nil {
+	defer file.Close()
+	checksum := algorithm.New()
+	if _, err := io.Copy(checksum, file); err != nil {
 		return "", err
 	}
 	return fmt.Sprintf("%x", checksum.Sum(nil)), nil
 }
 
-func copyFile(from, to string) error {
-	if err := os.MkdirAll(path.Dir(to), 0755); err != nil {
-		return err
-	}
-	fromFile, err := os.Open(from)
-	if err != nil {
-		return err
-	}
-	defer fromFile.Close()
-	toFile, err := os.Create(to)
-	if err != nil {
-		return nil
-	}
-	defer toFile.Close()
-	if _, err := io.Copy(toFile, fromFile); err != nil {
-		return err
-	}
-	return nil
-}
-
 const HttpRoundTripperStashKey reconcilers.StashKey = sourcev1alpha1.Group + "/http-round-tripper"
 
 func StashHttpRoundTripper(ctx context.Context, transport http.RoundTripper) {
@@ -461,3 +778,17 @@ func RetrieveImageRef(ctx context.Context) string {
 	}
 	return image
 }
+
+const ImageIndexDigestStashKey reconcilers.StashKey = sourcev1alpha1.Group + "/image-index-digest"
+
+func StashImageIndexDigest(ctx context.Context, digest string) {
+	reconcilers.StashValue(ctx, ImageIndexDigestStashKey, digest)
+}
+
+func RetrieveImageIndexDigest(ctx context.Context) string {
+	digest, ok := reconcilers.RetrieveValue(ctx, ImageIndexDigestStashKey).(string)
+	if !ok {
+		return ""
+	}
+	return digest
+}
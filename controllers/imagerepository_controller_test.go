@@ -18,13 +18,33 @@ package controllers_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"net/http/httptest"
 	"os"
 	"path"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -40,6 +60,7 @@ import (
 	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
 	"github.com/vmware-tanzu/tanzu-source-controller/controllers"
 	diesourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/dies/source/v1alpha1"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
 	btesting "github.com/vmware-tanzu/tanzu-source-controller/testing"
 )
 
@@ -58,7 +79,8 @@ func TestImageRepositoryReconciler(t *testing.T) {
 
 	helloImage := fmt.Sprintf("%s/%s", registryHost, "hello")
 	helloDigest := "66201d7a2285b74eef3221c5f548ebcaba03f9891eef305be94f4d51c661d933"
-	helloChecksum := "00a04fda65d6d2c7924a2729b8369efbe3f4e978"
+	helloChecksum := "17bc813c4262fc02a280220a8a78cb6401ca3a5b"
+	helloArtifactDigest := "sha256:fb5bce96c2e40dea2f1ea5024d2fc7e095bdef1fb66cd5680548d95f48364975"
 	utilruntime.Must(btesting.LoadImage(registry, "fixtures/hello.tar", helloImage))
 
 	artifactRootDir, err := os.MkdirTemp(os.TempDir(), "artifacts.*")
@@ -113,6 +135,7 @@ func TestImageRepositoryReconciler(t *testing.T) {
 							d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 							d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 							d.Checksum(helloChecksum)
+							d.Digest(helloArtifactDigest)
 							// use an old timestamp as an indication the resource wasn't updated
 							d.LastUpdateTime(metav1.Time{Time: time.Unix(100, 0)})
 						})
@@ -121,6 +144,7 @@ func TestImageRepositoryReconciler(t *testing.T) {
 							diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
 							diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 							diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+							diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
 						)
 					}),
 				defaultServiceAccount,
@@ -157,6 +181,7 @@ func TestImageRepositoryReconciler(t *testing.T) {
 							d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 							d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 							d.Checksum(helloChecksum)
+							d.Digest(helloArtifactDigest)
 							d.LastUpdateTime(now())
 						})
 						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
@@ -164,6 +189,7 @@ func TestImageRepositoryReconciler(t *testing.T) {
 							diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
 							diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 							diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+							diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
 						)
 					}),
 			},
@@ -260,6 +286,7 @@ func TestImageRepositoryReconciler(t *testing.T) {
 							d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 							d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 							d.Checksum(helloChecksum)
+							d.Digest(helloArtifactDigest)
 							// use an old timestamp as an indication the resource wasn't updated
 							d.LastUpdateTime(metav1.Time{Time: time.Unix(100, 0)})
 						})
@@ -268,6 +295,7 @@ func TestImageRepositoryReconciler(t *testing.T) {
 							diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
 							diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 							diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+							diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
 						)
 					}),
 				defaultServiceAccount,
@@ -307,6 +335,7 @@ func TestImageRepositoryReconciler(t *testing.T) {
 								Messagef(`Unable to resolve image with tag "%s/this/does/not/exist:latest" to a digest: HEAD https://%s/v2/this/does/not/exist/manifests/latest: unexpected status code 404 Not Found (HEAD responses have no body, use GET for details)`, registryHost, registryHost),
 							diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
 								Messagef(`Unable to resolve image with tag "%s/this/does/not/exist:latest" to a digest: HEAD https://%s/v2/this/does/not/exist/manifests/latest: unexpected status code 404 Not Found (HEAD responses have no body, use GET for details)`, registryHost, registryHost),
+							diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
 						)
 					}),
 			},
@@ -327,7 +356,7 @@ func TestImageRepositoryReconciler(t *testing.T) {
 		certs := []controllers.Cert{
 			{Certificate: registry.Certificate()},
 		}
-		return controllers.ImageRepositoryReconciler(c, artifactRootDir, "artifact.example", now, certs)
+		return controllers.ImageRepositoryReconciler(c, &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: artifactRootDir, Host: "artifact.example"}}, controllers.SHA256, now, certs)
 	})
 }
 
@@ -545,6 +574,28 @@ func TestImageRepositoryImageDigestSyncReconciler(t *testing.T) {
 
 	taggedImage := fmt.Sprintf("%s:latest", helloImage)
 	taggedImageDigest := fmt.Sprintf("%s@sha256:%s", taggedImage, helloDigest)
+	for _, tag := range []string{"1.3.0", "1.4.0", "1.4.2", "1.5.0-beta.1", "1.5.0-rc.1", "2.0.0"} {
+		utilruntime.Must(btesting.LoadImage(registry, "fixtures/hello.tar", fmt.Sprintf("%s:%s", helloImage, tag)))
+	}
+
+	// an index of the hello image for linux/arm64 and another image for
+	// linux/amd64
+	indexImage := fmt.Sprintf("%s/%s", registryHost, "multi-platform")
+	helloRef, err := name.ParseReference(taggedImage, name.WeakValidation)
+	utilruntime.Must(err)
+	helloRemoteImage, err := remote.Image(helloRef, remote.WithTransport(registry.Client().Transport))
+	utilruntime.Must(err)
+	otherImage, err := mutate.AppendLayers(empty.Image, static.NewLayer([]byte("other"), "application/vnd.oci.image.layer.v1.tar"))
+	utilruntime.Must(err)
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: otherImage, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: helloRemoteImage, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}}},
+	)
+	indexTag, err := name.NewTag(indexImage+":latest", name.WeakValidation)
+	utilruntime.Must(err)
+	utilruntime.Must(remote.WriteIndex(indexTag, index, remote.WithTransport(registry.Client().Transport)))
+	indexDigest, err := index.Digest()
+	utilruntime.Must(err)
 
 	scheme := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
@@ -594,6 +645,179 @@ func TestImageRepositoryImageDigestSyncReconciler(t *testing.T) {
 				controllers.ImageRefStashKey: taggedImageDigest,
 			},
 		},
+		"clear tag when tag policy is removed": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(taggedImage)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.Tag("1.4.2")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(taggedImage)
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: taggedImageDigest,
+			},
+		},
+		"resolve tag policy": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{SemVer: ">=1.4 <2"})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{SemVer: ">=1.4 <2"})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.Tag("1.4.2")
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: fmt.Sprintf("%s:1.4.2@sha256:%s", helloImage, helloDigest),
+			},
+		},
+		"resolve tag policy with prereleases": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{
+						SemVer:     ">=1.4 <2",
+						Prerelease: &sourcev1alpha1.ImageTagPrereleaseFilter{},
+					})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{
+						SemVer:     ">=1.4 <2",
+						Prerelease: &sourcev1alpha1.ImageTagPrereleaseFilter{},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.Tag("1.5.0-rc.1")
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: fmt.Sprintf("%s:1.5.0-rc.1@sha256:%s", helloImage, helloDigest),
+			},
+		},
+		"resolve tag policy with prerelease identifiers": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{
+						SemVer:     ">=1.4 <2",
+						Prerelease: &sourcev1alpha1.ImageTagPrereleaseFilter{Identifiers: []string{"beta"}},
+					})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{
+						SemVer:     ">=1.4 <2",
+						Prerelease: &sourcev1alpha1.ImageTagPrereleaseFilter{Identifiers: []string{"beta"}},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.Tag("1.5.0-beta.1")
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: fmt.Sprintf("%s:1.5.0-beta.1@sha256:%s", helloImage, helloDigest),
+			},
+		},
+		"tag policy without a matching tag": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{SemVer: ">=3"})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{SemVer: ">=3"})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionFalse).Reason("NoMatchingTag").Message(`No tag in repository "`+helloImage+`" matches semver ">=3"`),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("NoMatchingTag").Message(`No tag in repository "`+helloImage+`" matches semver ">=3"`),
+					)
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: nil,
+			},
+		},
+		"invalid tag policy": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{SemVer: "not a constraint"})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{SemVer: "not a constraint"})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionFalse).Reason("InvalidTagPolicy").Message(`Tag policy semver "not a constraint" is invalid: improper constraint: "not a constraint"`),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("InvalidTagPolicy").Message(`Tag policy semver "not a constraint" is invalid: improper constraint: "not a constraint"`),
+					)
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: nil,
+			},
+		},
+		"tag policy with a tagged image": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(taggedImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{SemVer: ">=1.4 <2"})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(taggedImage)
+					d.TagPolicy(&sourcev1alpha1.ImageTagPolicy{SemVer: ">=1.4 <2"})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionFalse).Reason("MalformedRepository").Message(`Image name "`+taggedImage+"\" failed validation: repository can only contain the characters `abcdefghijklmnopqrstuvwxyz0123456789_-./`: hello:latest"),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("MalformedRepository").Message(`Image name "`+taggedImage+"\" failed validation: repository can only contain the characters `abcdefghijklmnopqrstuvwxyz0123456789_-./`: hello:latest"),
+					)
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: nil,
+			},
+		},
 		"skip when pull secrets are missing": {
 			Resource: parent.
 				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
@@ -633,6 +857,116 @@ func TestImageRepositoryImageDigestSyncReconciler(t *testing.T) {
 				controllers.ImageRefStashKey: nil,
 			},
 		},
+		"select platform from an index": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexImage + ":latest")
+					d.Platform("linux/arm64")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexImage + ":latest")
+					d.Platform("linux/arm64")
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         fmt.Sprintf("%s:latest@sha256:%s", indexImage, helloDigest),
+				controllers.ImageIndexDigestStashKey: indexDigest.String(),
+			},
+		},
+		"select platform from a digested index": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexImage + "@" + indexDigest.String())
+					d.Platform("linux/arm64")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexImage + "@" + indexDigest.String())
+					d.Platform("linux/arm64")
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         fmt.Sprintf("%s@sha256:%s", indexImage, helloDigest),
+				controllers.ImageIndexDigestStashKey: indexDigest.String(),
+			},
+		},
+		"platform of an image that is not an index": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(taggedImage)
+					d.Platform("linux/arm64")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(taggedImage)
+					d.Platform("linux/arm64")
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         taggedImageDigest,
+				controllers.ImageIndexDigestStashKey: nil,
+			},
+		},
+		"platform not in the index": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexImage + ":latest")
+					d.Platform("linux/s390x")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexImage + ":latest")
+					d.Platform("linux/s390x")
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionFalse).Reason("PlatformNotFound").Message(`Image index "`+indexImage+`:latest" has no image for platform "linux/s390x"`),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("PlatformNotFound").Message(`Image index "`+indexImage+`:latest" has no image for platform "linux/s390x"`),
+					)
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: nil,
+			},
+		},
+		"invalid platform": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexImage + ":latest")
+					d.Platform("linux")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexImage + ":latest")
+					d.Platform("linux")
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionFalse).Reason("InvalidPlatform").Message(`Platform "linux" must be of the form os/arch[/variant]`),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("InvalidPlatform").Message(`Platform "linux" must be of the form os/arch[/variant]`),
+					)
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: nil,
+			},
+		},
 		"remote error": {
 			Resource: parent.
 				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
@@ -663,7 +997,7 @@ func TestImageRepositoryImageDigestSyncReconciler(t *testing.T) {
 	})
 }
 
-func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
+func TestImageRepositorySignatureSyncReconciler(t *testing.T) {
 	namespace := "test-namespace"
 	name := "my-image"
 
@@ -675,21 +1009,73 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 	utilruntime.Must(err)
 	defer registry.Close()
 
-	helloImage := fmt.Sprintf("%s/%s", registryHost, "hello")
-	utilruntime.Must(btesting.LoadImage(registry, "fixtures/hello.tar", helloImage))
 	helloDigest := "66201d7a2285b74eef3221c5f548ebcaba03f9891eef305be94f4d51c661d933"
-	helloChecksum := "00a04fda65d6d2c7924a2729b8369efbe3f4e978"
-	image := fmt.Sprintf("%s@sha256:%s", helloImage, helloDigest)
+	signedImage := fmt.Sprintf("%s/signed@sha256:%s", registryHost, helloDigest)
+	keylessImage := fmt.Sprintf("%s/keyless@sha256:%s", registryHost, helloDigest)
+	unsignedImage := fmt.Sprintf("%s/unsigned@sha256:%s", registryHost, helloDigest)
+	for _, repository := range []string{"signed", "keyless", "unsigned"} {
+		utilruntime.Must(btesting.LoadImage(registry, "fixtures/hello.tar", fmt.Sprintf("%s/%s", registryHost, repository)))
+	}
 
-	artifactRootDir, err := os.MkdirTemp(os.TempDir(), "artifacts.*")
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	utilruntime.Must(err)
-	defer os.RemoveAll(artifactRootDir)
+	untrustedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	utilruntime.Must(err)
+	utilruntime.Must(signImage(registry, signedImage, signingKey, nil))
 
-	now := func() metav1.Time {
-		return metav1.Time{
-			Time: time.Unix(1, 0),
-		}
-	}
+	// an unsigned index listing the signed image for linux/arm64
+	platformImage := fmt.Sprintf("%s/platform-signed@sha256:%s", registryHost, helloDigest)
+	utilruntime.Must(btesting.LoadImage(registry, "fixtures/hello.tar", fmt.Sprintf("%s/platform-signed", registryHost)))
+	utilruntime.Must(signImage(registry, platformImage, signingKey, nil))
+	platformRef, err := name.ParseReference(platformImage, name.WeakValidation)
+	utilruntime.Must(err)
+	platformRemoteImage, err := remote.Image(platformRef, remote.WithTransport(registry.Client().Transport))
+	utilruntime.Must(err)
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: platformRemoteImage, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}}},
+	)
+	indexTag, err := name.NewTag(fmt.Sprintf("%s/platform-signed:latest", registryHost), name.WeakValidation)
+	utilruntime.Must(err)
+	utilruntime.Must(remote.WriteIndex(indexTag, index, remote.WithTransport(registry.Client().Transport)))
+	indexDigest, err := index.Digest()
+	utilruntime.Must(err)
+
+	rootCertificate, keylessCertificate, keylessKey, err := newKeylessCertificate("https://issuer.example", "dev@example.com")
+	utilruntime.Must(err)
+	utilruntime.Must(signImage(registry, keylessImage, keylessKey, keylessCertificate))
+
+	trustedKeys := diecorev1.SecretBlank.
+		MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+			d.Namespace(namespace)
+			d.Name("trusted-keys")
+		}).
+		Data(map[string][]byte{
+			"cosign.pub": encodePublicKey(&signingKey.PublicKey),
+		})
+	untrustedKeys := diecorev1.SecretBlank.
+		MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+			d.Namespace(namespace)
+			d.Name("untrusted-keys")
+		}).
+		Data(map[string][]byte{
+			"cosign.pub": encodePublicKey(&untrustedKey.PublicKey),
+		})
+	keylessRoots := diecorev1.SecretBlank.
+		MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+			d.Namespace(namespace)
+			d.Name("keyless-roots")
+		}).
+		Data(map[string][]byte{
+			"fulcio.crt": rootCertificate,
+		})
+	invalidKeys := diecorev1.SecretBlank.
+		MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+			d.Namespace(namespace)
+			d.Name("invalid-keys")
+		}).
+		Data(map[string][]byte{
+			"cosign.pub": []byte("not a key"),
+		})
 
 	parent := diesourcev1alpha1.ImageRepositoryBlank.
 		MetadataDie(func(d *diemetav1.ObjectMetaDie) {
@@ -701,11 +1087,815 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 			d.ObservedGeneration(1)
 		})
 
-	rts := rtesting.SubReconcilerTests[*sourcev1alpha1.ImageRepository]{
-		"pull image": {
+	rts := rtesting.SubReconcilerTests[*sourcev1alpha1.ImageRepository]{
+		"verification not required": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(unsignedImage)
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey: unsignedImage,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(unsignedImage)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+		},
+		"verify signature with public key": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				trustedKeys,
+			},
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         signedImage,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("Verified"),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(trustedKeys, parent, scheme),
+			},
+		},
+		"verify signature of an image selected from an unsigned index": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexTag.String())
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				trustedKeys,
+			},
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         platformImage,
+				controllers.ImageIndexDigestStashKey: indexDigest.String(),
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(indexTag.String())
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("Verified"),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(trustedKeys, parent, scheme),
+			},
+		},
+		"verify keyless signature": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(keylessImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "keyless-roots"},
+						Keyless: []sourcev1alpha1.ImageKeylessIdentity{
+							{Issuer: "https://issuer.example", Subject: "dev@example.com"},
+						},
+					})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				keylessRoots,
+			},
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         keylessImage,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(keylessImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "keyless-roots"},
+						Keyless: []sourcev1alpha1.ImageKeylessIdentity{
+							{Issuer: "https://issuer.example", Subject: "dev@example.com"},
+						},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("Verified"),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(keylessRoots, parent, scheme),
+			},
+		},
+		"keyless signature from untrusted identity": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(keylessImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "keyless-roots"},
+						Keyless: []sourcev1alpha1.ImageKeylessIdentity{
+							{Issuer: "https://issuer.example", Subject: "other@example.com"},
+						},
+					})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				keylessRoots,
+			},
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         keylessImage,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(keylessImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "keyless-roots"},
+						Keyless: []sourcev1alpha1.ImageKeylessIdentity{
+							{Issuer: "https://issuer.example", Subject: "other@example.com"},
+						},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("VerificationFailed").
+							Messagef(`Unable to verify signatures for image %q: no signature is trusted: signing certificate identity "dev@example.com" issued by "https://issuer.example" is not trusted`, keylessImage),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionFalse).Reason("VerificationFailed").
+							Messagef(`Unable to verify signatures for image %q: no signature is trusted: signing certificate identity "dev@example.com" issued by "https://issuer.example" is not trusted`, keylessImage),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(keylessRoots, parent, scheme),
+			},
+		},
+		"signature from untrusted key": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "untrusted-keys"},
+					})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				untrustedKeys,
+			},
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         signedImage,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "untrusted-keys"},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("VerificationFailed").
+							Messagef(`Unable to verify signatures for image %q: no signature is trusted: signature was not made by a trusted public key`, signedImage),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionFalse).Reason("VerificationFailed").
+							Messagef(`Unable to verify signatures for image %q: no signature is trusted: signature was not made by a trusted public key`, signedImage),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(untrustedKeys, parent, scheme),
+			},
+		},
+		"unsigned image": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(unsignedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				trustedKeys,
+			},
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         unsignedImage,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(unsignedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("SignatureMissing").
+							Messagef(`No cosign signatures found for image %q`, unsignedImage),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionFalse).Reason("SignatureMissing").
+							Messagef(`No cosign signatures found for image %q`, unsignedImage),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(trustedKeys, parent, scheme),
+			},
+		},
+		"secret not found": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         signedImage,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("SecretMissing").Message(`Secret "trusted-keys" not found in namespace "test-namespace"`),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionFalse).Reason("SecretMissing").Message(`Secret "trusted-keys" not found in namespace "test-namespace"`),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(trustedKeys, parent, scheme),
+			},
+		},
+		"invalid secret": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "invalid-keys"},
+					})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				invalidKeys,
+			},
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         signedImage,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "invalid-keys"},
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("InvalidSecret").Message(`Secret "invalid-keys" cannot be used to verify signatures: public key "cosign.pub" is not PEM encoded`),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionFalse).Reason("InvalidSecret").Message(`Secret "invalid-keys" cannot be used to verify signatures: public key "cosign.pub" is not PEM encoded`),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(invalidKeys, parent, scheme),
+			},
+		},
+		"error fetching secret": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				trustedKeys,
+			},
+			WithReactors: []rtesting.ReactionFunc{
+				rtesting.InduceFailure("get", "Secret"),
+			},
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         signedImage,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ShouldErr: true,
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(trustedKeys, parent, scheme),
+			},
+		},
+		"missing image": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).DieReleasePtr(),
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(signedImage)
+					d.Verify(&sourcev1alpha1.ImageVerification{
+						SecretRef: corev1.LocalObjectReference{Name: "trusted-keys"},
+					})
+				}).DieReleasePtr(),
+		},
+	}
+
+	rts.Run(t, scheme, func(t *testing.T, rtc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository], c reconcilers.Config) reconcilers.SubReconciler[*sourcev1alpha1.ImageRepository] {
+		return controllers.ImageRepositorySignatureSyncReconciler()
+	})
+}
+
+// signImage attaches a cosign signature for the image to the registry. Key
+// signatures use cosign's ".sig" tag convention, keyless signatures are
+// attached as an OCI referrer.
+func signImage(registry *httptest.Server, image string, key *ecdsa.PrivateKey, certificate []byte) error {
+	transport := remote.WithTransport(registry.Client().Transport)
+	digest, err := name.NewDigest(image, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":%q},"optional":null}`, digest.Context().Name(), digest.DigestStr(), controllers.CosignSignatureType))
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		return err
+	}
+	annotations := map[string]string{
+		controllers.CosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature),
+	}
+	if certificate != nil {
+		annotations[controllers.CosignCertificateAnnotation] = string(certificate)
+	}
+	signatureImage, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, controllers.CosignSimpleSigningMediaType),
+		Annotations: annotations,
+	})
+	if err != nil {
+		return err
+	}
+
+	if certificate == nil {
+		return remote.Write(digest.Context().Tag(strings.Replace(digest.DigestStr(), ":", "-", 1)+".sig"), signatureImage, transport)
+	}
+
+	subject, err := remote.Get(digest, transport)
+	if err != nil {
+		return err
+	}
+	signatureImage = mutate.ConfigMediaType(signatureImage, controllers.CosignSignatureArtifactType)
+	signatureImage = mutate.Subject(signatureImage, subject.Descriptor).(v1.Image)
+	signatureDigest, err := signatureImage.Digest()
+	if err != nil {
+		return err
+	}
+	return remote.Write(digest.Context().Digest(signatureDigest.String()), signatureImage, transport)
+}
+
+// newKeylessCertificate creates a root certificate and a short lived code
+// signing certificate issued by the root to the subject, in the style of
+// Fulcio.
+func newKeylessCertificate(issuer, subject string) ([]byte, []byte, *ecdsa.PrivateKey, error) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio.example"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, rootKey.Public(), rootKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	root, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	issuerExtension, err := asn1.Marshal(issuer)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		// without a transparency log entry the certificate must still be
+		// valid when verified
+		NotBefore:      time.Now().Add(-5 * time.Minute),
+		NotAfter:       time.Now().Add(5 * time.Minute),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses: []string{subject},
+		ExtraExtensions: []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}, Value: issuerExtension},
+		},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, root, leafKey.Public(), rootKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		leafKey, nil
+}
+
+func encodePublicKey(key *ecdsa.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	utilruntime.Must(err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
+	namespace := "test-namespace"
+	name := "my-image"
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(sourcev1alpha1.AddToScheme(scheme))
+
+	registry, registryHost, err := btesting.NewRegistry()
+	utilruntime.Must(err)
+	defer registry.Close()
+
+	helloImage := fmt.Sprintf("%s/%s", registryHost, "hello")
+	utilruntime.Must(btesting.LoadImage(registry, "fixtures/hello.tar", helloImage))
+	helloDigest := "66201d7a2285b74eef3221c5f548ebcaba03f9891eef305be94f4d51c661d933"
+	helloChecksum := "17bc813c4262fc02a280220a8a78cb6401ca3a5b"
+	helloArtifactDigest := "sha256:fb5bce96c2e40dea2f1ea5024d2fc7e095bdef1fb66cd5680548d95f48364975"
+	helloZipChecksum := "0b574a23ade08d27590ea2d73c86e5ed39c5bc76"
+	helloZipArtifactDigest := "sha256:2373ec8c0d82d49f670a06598638ba9c8349b0641e1cfb18ab4b429408ff983b"
+	// every file of the image ignored
+	emptyChecksum := "f1f332484a6894376db561e20dd0fb6edc51cd05"
+	emptyArtifactDigest := "sha256:9addb4c7f3afa99b03f24f9e05cb87d274a63ae9ba30c94f02c75e85d133e9de"
+	ignoreFilterSuffix := "-4ad22f6a49b4"
+	image := fmt.Sprintf("%s@sha256:%s", helloImage, helloDigest)
+	// an index listing the image, the index itself is not pulled
+	indexDigest := "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+	artifactRootDir, err := os.MkdirTemp(os.TempDir(), "artifacts.*")
+	utilruntime.Must(err)
+	defer os.RemoveAll(artifactRootDir)
+
+	now := func() metav1.Time {
+		return metav1.Time{
+			Time: time.Unix(1, 0),
+		}
+	}
+
+	parent := diesourcev1alpha1.ImageRepositoryBlank.
+		MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+			d.Namespace(namespace)
+			d.Name(name)
+			d.Generation(1)
+		}).
+		StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+			d.ObservedGeneration(1)
+			d.ConditionsDie(
+				diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+			)
+		})
+
+	rts := rtesting.SubReconcilerTests[*sourcev1alpha1.ImageRepository]{
+		"pull image": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
+						d.Revision(image)
+						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.Checksum(helloChecksum)
+						d.Digest(helloArtifactDigest)
+						d.LastUpdateTime(now())
+					})
+					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) error {
+				// check that the file exists
+				artifact := path.Join(artifactRootDir, "imagerepository", namespace, name, helloDigest+".tar.gz")
+				if _, err := os.Stat(artifact); err != nil {
+					t.Errorf("artifact expected to exist %q", artifact)
+				}
+				// check that the manifest describes the artifact
+				content, err := os.ReadFile(path.Join(artifactRootDir, "imagerepository", namespace, name, helloDigest+".json"))
+				if err != nil {
+					t.Errorf("manifest expected to exist: %v", err)
+					return nil
+				}
+				manifest := &controllers.ArtifactManifest{}
+				if err := json.Unmarshal(content, manifest); err != nil {
+					t.Errorf("manifest expected to be valid JSON: %v", err)
+				}
+				if manifest.Revision != image {
+					t.Errorf("manifest expected revision %q, got %q", image, manifest.Revision)
+				}
+				if len(manifest.Files) == 0 {
+					t.Errorf("manifest expected to list the packaged files")
+				}
+				return nil
+			},
+		},
+		"pull image selected from an index": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.Platform("linux/arm64")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImageIndexDigestStashKey: indexDigest,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(helloImage)
+					d.Platform("linux/arm64")
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
+						d.Revision(fmt.Sprintf("%s@%s/sha256:%s", helloImage, indexDigest, helloDigest))
+						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.Checksum(helloChecksum)
+						d.Digest(helloArtifactDigest)
+						d.LastUpdateTime(now())
+					})
+					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+		},
+		"pull image as zip": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Format(sourcev1alpha1.ArtifactFormatZip)
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Format(sourcev1alpha1.ArtifactFormatZip)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
+						d.Revision(image)
+						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".zip")
+						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".zip")
+						d.Checksum(helloZipChecksum)
+						d.Digest(helloZipArtifactDigest)
+						d.LastUpdateTime(now())
+					})
+					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".zip")
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) error {
+				// check that the file exists
+				artifact := path.Join(artifactRootDir, "imagerepository", namespace, name, helloDigest+".zip")
+				if _, err := os.Stat(artifact); err != nil {
+					t.Errorf("artifact expected to exist %q", artifact)
+				}
+				return nil
+			},
+		},
+		"pull image with ignored files": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Ignore("*.txt")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Ignore("*.txt")
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
+						d.Revision(image)
+						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ignoreFilterSuffix + ".tar.gz")
+						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ignoreFilterSuffix + ".tar.gz")
+						d.Checksum(emptyChecksum)
+						d.Digest(emptyArtifactDigest)
+						d.LastUpdateTime(now())
+					})
+					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ignoreFilterSuffix + ".tar.gz")
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+		},
+		"invalid filter": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Include("[a-")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Include("[a-")
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionFalse).Reason("InvalidFilter").Message(`Include and ignore patterns are invalid: pattern "[a-" is malformed: syntax error in pattern`),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("InvalidFilter").Message(`Include and ignore patterns are invalid: pattern "[a-" is malformed: syntax error in pattern`),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+		},
+		"path not found in image": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Path("config")
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Path("config")
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionFalse).Reason("PathNotFound").Message(`Path "config" is not a directory in image "`+image+`"`),
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("PathNotFound").Message(`Path "config" is not a directory in image "`+image+`"`),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+		},
+		"image too large": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Limits(&sourcev1alpha1.ArtifactLimits{
+						MaxCompressedSize: resource.NewQuantity(10, resource.BinarySI),
+					})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.Limits(&sourcev1alpha1.ArtifactLimits{
+						MaxCompressedSize: resource.NewQuantity(10, resource.BinarySI),
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionFalse).Reason("ArtifactTooLarge").Message(`Image "`+image+`" is too large: artifact too large: 137 bytes to download exceed the limit of 10 bytes`),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("ArtifactTooLarge").Message(`Image "`+image+`" is too large: artifact too large: 137 bytes to download exceed the limit of 10 bytes`),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+		},
+		"layer not found in image": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.LayerSelector(&sourcev1alpha1.ImageLayerSelector{
+						MediaType: "application/vnd.cncf.flux.content.v1.tar+gzip",
+					})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.LayerSelector(&sourcev1alpha1.ImageLayerSelector{
+						MediaType: "application/vnd.cncf.flux.content.v1.tar+gzip",
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionFalse).Reason("LayerNotFound").Message(`Image "`+image+`" has no layer with media type "application/vnd.cncf.flux.content.v1.tar+gzip"`),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("LayerNotFound").Message(`Image "`+image+`" has no layer with media type "application/vnd.cncf.flux.content.v1.tar+gzip"`),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+		},
+		"skip existing image": {
+			Prepare: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) (context.Context, error) {
+				dir := path.Join(artifactRootDir, "imagerepository", namespace, name)
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return ctx, err
+				}
+				if _, err := os.Create(path.Join(dir, helloDigest+".tar.gz")); err != nil {
+					return ctx, err
+				}
+
+				return ctx, nil
+			},
 			Resource: parent.
 				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
 					d.Image(image)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
+						d.Revision(image)
+						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.Checksum(helloChecksum)
+						d.Digest(helloArtifactDigest)
+						// use an old timestamp as an indication the resource wasn't updated
+						d.LastUpdateTime(metav1.Time{Time: time.Unix(100, 0)})
+					})
+					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 				}).DieReleasePtr(),
 			GivenStashedValues: map[reconcilers.StashKey]interface{}{
 				controllers.ImageRefStashKey:         image,
@@ -722,25 +1912,20 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.Checksum(helloChecksum)
-						d.LastUpdateTime(now())
+						d.Digest(helloArtifactDigest)
+						// use an old timestamp as an indication the resource wasn't updated
+						d.LastUpdateTime(metav1.Time{Time: time.Unix(100, 0)})
 					})
 					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 					d.ConditionsDie(
 						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
 						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
 					)
 				}).DieReleasePtr(),
-			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) error {
-				// check that the file exists
-				artifact := path.Join(artifactRootDir, "imagerepository", namespace, name, helloDigest+".tar.gz")
-				if _, err := os.Stat(artifact); err != nil {
-					t.Errorf("artifact expected to exist %q", artifact)
-				}
-				return nil
-			},
 		},
-		"skip existing image": {
+		"update if host changes": {
 			Prepare: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) (context.Context, error) {
 				dir := path.Join(artifactRootDir, "imagerepository", namespace, name)
 				if err := os.MkdirAll(dir, 0755); err != nil {
@@ -760,8 +1945,9 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
 						d.Revision(image)
 						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
-						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.URL("http://localhost/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.Checksum(helloChecksum)
+						d.Digest(helloArtifactDigest)
 						// use an old timestamp as an indication the resource wasn't updated
 						d.LastUpdateTime(metav1.Time{Time: time.Unix(100, 0)})
 					})
@@ -782,18 +1968,19 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.Checksum(helloChecksum)
-						// use an old timestamp as an indication the resource wasn't updated
-						d.LastUpdateTime(metav1.Time{Time: time.Unix(100, 0)})
+						d.Digest(helloArtifactDigest)
+						d.LastUpdateTime(now())
 					})
 					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 					d.ConditionsDie(
 						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
 						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
 					)
 				}).DieReleasePtr(),
 		},
-		"update if host changes": {
+		"update if digest is missing": {
 			Prepare: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) (context.Context, error) {
 				dir := path.Join(artifactRootDir, "imagerepository", namespace, name)
 				if err := os.MkdirAll(dir, 0755); err != nil {
@@ -813,7 +2000,7 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
 						d.Revision(image)
 						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
-						d.URL("http://localhost/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.Checksum(helloChecksum)
 						// use an old timestamp as an indication the resource wasn't updated
 						d.LastUpdateTime(metav1.Time{Time: time.Unix(100, 0)})
@@ -835,6 +2022,7 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.Checksum(helloChecksum)
+						d.Digest(helloArtifactDigest)
 						d.LastUpdateTime(now())
 					})
 					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
@@ -842,6 +2030,7 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
 						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
 					)
 				}).DieReleasePtr(),
 		},
@@ -860,6 +2049,41 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 					d.Image(image)
 				}).DieReleasePtr(),
 		},
+		"untrusted signature": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("SignatureMissing"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionFalse).Reason("SignatureMissing"),
+					)
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("SignatureMissing"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionFalse).Reason("SignatureMissing"),
+					)
+				}).DieReleasePtr(),
+			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) error {
+				// check that the image was not packaged
+				artifact := path.Join(artifactRootDir, "imagerepository", namespace, name, helloDigest+".tar.gz")
+				if _, err := os.Stat(artifact); !os.IsNotExist(err) {
+					t.Errorf("artifact not expected to exist %q", artifact)
+				}
+				return nil
+			},
+		},
 		"missing pull secrets": {
 			Resource: parent.
 				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
@@ -893,6 +2117,7 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 					d.ConditionsDie(
 						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionFalse).Reason("MalformedDigest").Message(`Image reference "`+helloImage+`@sha512:`+helloDigest+`" is not a valid digest: unsupported digest algorithm: sha512:`+helloDigest),
 						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("MalformedDigest").Message(`Image reference "`+helloImage+`@sha512:`+helloDigest+`" is not a valid digest: unsupported digest algorithm: sha512:`+helloDigest),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
 					)
 				}).DieReleasePtr(),
 		}}
@@ -902,7 +2127,198 @@ func TestImageRepositoryPullImageSyncReconciler(t *testing.T) {
 		err := os.RemoveAll(artifactRootDir)
 		utilruntime.Must(err)
 
-		return controllers.ImageRepositoryPullImageSyncReconciler(artifactRootDir, "artifact.example", now)
+		return controllers.ImageRepositoryPullImageSyncReconciler(&controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: artifactRootDir, Host: "artifact.example"}}, controllers.SHA256, now)
+	})
+}
+
+func TestImageRepositoryPullImageSyncReconcilerCachedArtifact(t *testing.T) {
+	namespace := "test-namespace"
+	name := "my-image"
+	reg_user := "user"
+	reg_pwd := "pass"
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(sourcev1alpha1.AddToScheme(scheme))
+
+	registry, registryHost, err := btesting.NewAuthRegistryServer(reg_user, reg_pwd)
+	utilruntime.Must(err)
+	defer registry.Close()
+
+	helloImage := fmt.Sprintf("%s/%s", registryHost, "hello")
+	utilruntime.Must(btesting.LoadImageWithAuth(registry, "fixtures/hello.tar", helloImage, reg_user, reg_pwd))
+	helloDigest := "66201d7a2285b74eef3221c5f548ebcaba03f9891eef305be94f4d51c661d933"
+	// the artifact cached for another resource
+	cachedChecksum := "0123456789abcdef0123456789abcdef01234567"
+	cachedArtifactDigest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	image := fmt.Sprintf("%s@sha256:%s", helloImage, helloDigest)
+
+	dcj := fmt.Sprintf(`{"auths": {%q: {"username": %q, "password": %q}}}`, registryHost, reg_user, reg_pwd)
+	secret := corev1.Secret{
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(dcj),
+		},
+	}
+	secret.Namespace = namespace
+	secret.Name = "docker"
+	secretRef := []corev1.LocalObjectReference{{Name: "docker"}}
+
+	artifactRootDir, err := os.MkdirTemp(os.TempDir(), "artifacts.*")
+	utilruntime.Must(err)
+	defer os.RemoveAll(artifactRootDir)
+	cacheDir, err := os.MkdirTemp(os.TempDir(), "cache.*")
+	utilruntime.Must(err)
+	defer os.RemoveAll(cacheDir)
+
+	now := func() metav1.Time {
+		return metav1.Time{
+			Time: time.Unix(1, 0),
+		}
+	}
+
+	parent := diesourcev1alpha1.ImageRepositoryBlank.
+		MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+			d.Namespace(namespace)
+			d.Name(name)
+			d.Generation(1)
+		}).
+		StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+			d.ObservedGeneration(1)
+			d.ConditionsDie(
+				diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+			)
+		})
+
+	rts := rtesting.SubReconcilerTests[*sourcev1alpha1.ImageRepository]{
+		"reuse cached artifact": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.ImagePullSecrets(secretRef...)
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{secret},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.ImagePullSecrets(secretRef...)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
+						d.Revision(image)
+						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+						d.Checksum(cachedChecksum)
+						d.Digest(cachedArtifactDigest)
+						d.LastUpdateTime(now())
+					})
+					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) error {
+				content, err := os.ReadFile(path.Join(artifactRootDir, "imagerepository", namespace, name, helloDigest+".tar.gz"))
+				if err != nil || string(content) != "cached" {
+					t.Errorf("cached artifact expected to be stored, got %q: %v", content, err)
+				}
+				return nil
+			},
+		},
+		"cached artifact of another namespace without credentials": {
+			Resource: parent.
+				MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+					d.Namespace("other-namespace")
+				}).
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.ImagePullSecretsStashKey: []corev1.Secret{},
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+					d.Namespace("other-namespace")
+				}).
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
+							Messagef(`unable to pull image %q: HEAD https://%s/v2/hello/manifests/sha256:%s: unexpected status code 401 Unauthorized (HEAD responses have no body, use GET for details)`, image, registryHost, helloDigest),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
+							Messagef(`unable to pull image %q: HEAD https://%s/v2/hello/manifests/sha256:%s: unexpected status code 401 Unauthorized (HEAD responses have no body, use GET for details)`, image, registryHost, helloDigest),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) error {
+				if _, err := os.Stat(path.Join(artifactRootDir, "imagerepository", "other-namespace", name)); err == nil {
+					t.Errorf("cached artifact expected not to be stored")
+				}
+				return nil
+			},
+		},
+		"cached artifact with a missing secret": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.ImagePullSecrets(secretRef...)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionFalse).Reason("SecretMissing").Message(`Secret "docker" not found in namespace "test-namespace"`),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("SecretMissing").Message(`Secret "docker" not found in namespace "test-namespace"`),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.ImageRefStashKey:         image,
+				controllers.HttpRoundTripperStashKey: registry.Client().Transport,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.ImageRepositorySpecDie) {
+					d.Image(image)
+					d.ImagePullSecrets(secretRef...)
+				}).
+				StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionFalse).Reason("SecretMissing").Message(`Secret "docker" not found in namespace "test-namespace"`),
+						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionFalse).Reason("SecretMissing").Message(`Secret "docker" not found in namespace "test-namespace"`),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+					)
+				}).DieReleasePtr(),
+			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) error {
+				if _, err := os.Stat(path.Join(artifactRootDir, "imagerepository", namespace, name)); err == nil {
+					t.Errorf("cached artifact expected not to be stored")
+				}
+				return nil
+			},
+		}}
+
+	rts.Run(t, scheme, func(t *testing.T, rtc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository], c reconcilers.Config) reconcilers.SubReconciler[*sourcev1alpha1.ImageRepository] {
+		// drop the artifactRootDir and the cache between test cases
+		utilruntime.Must(os.RemoveAll(artifactRootDir))
+		utilruntime.Must(os.RemoveAll(cacheDir))
+
+		// the artifact packaged from the image for a resource of another
+		// namespace with credentials for the image
+		cache, err := blobcache.New(path.Join(cacheDir, "cache"), 1<<20)
+		utilruntime.Must(err)
+		cached := path.Join(cacheDir, helloDigest+".tar.gz")
+		utilruntime.Must(os.WriteFile(cached, []byte("cached"), 0644))
+		utilruntime.Must(controllers.CachePackagedImage(cache, helloDigest+".tar.gz", controllers.SHA256, cached, cachedChecksum, cachedArtifactDigest))
+
+		return controllers.ImageRepositoryPullImageSyncReconciler(&controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: artifactRootDir, Host: "artifact.example"}, Cache: cache}, controllers.SHA256, now)
 	})
 }
 
@@ -923,7 +2339,8 @@ func TestImageRepositoryPullImageSyncReconcilerWithAuth(t *testing.T) {
 	helloImage := fmt.Sprintf("%s/%s", registryHost, "hello")
 	utilruntime.Must(btesting.LoadImageWithAuth(registry, "fixtures/hello.tar", helloImage, reg_user, reg_pwd))
 	helloDigest := "66201d7a2285b74eef3221c5f548ebcaba03f9891eef305be94f4d51c661d933"
-	helloChecksum := "00a04fda65d6d2c7924a2729b8369efbe3f4e978"
+	helloChecksum := "17bc813c4262fc02a280220a8a78cb6401ca3a5b"
+	helloArtifactDigest := "sha256:fb5bce96c2e40dea2f1ea5024d2fc7e095bdef1fb66cd5680548d95f48364975"
 	image := fmt.Sprintf("%s@sha256:%s", helloImage, helloDigest)
 
 	var pullsecrets = []corev1.Secret{}
@@ -962,6 +2379,9 @@ func TestImageRepositoryPullImageSyncReconcilerWithAuth(t *testing.T) {
 		}).
 		StatusDie(func(d *diesourcev1alpha1.ImageRepositoryStatusDie) {
 			d.ObservedGeneration(1)
+			d.ConditionsDie(
+				diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
+			)
 		})
 
 	rts := rtesting.SubReconcilerTests[*sourcev1alpha1.ImageRepository]{
@@ -987,6 +2407,7 @@ func TestImageRepositoryPullImageSyncReconcilerWithAuth(t *testing.T) {
 						d.Path("imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
 						d.Checksum(helloChecksum)
+						d.Digest(helloArtifactDigest)
 						d.LastUpdateTime(now())
 					})
 					d.URL("http://artifact.example/imagerepository/test-namespace/my-image/" + helloDigest + ".tar.gz")
@@ -994,6 +2415,7 @@ func TestImageRepositoryPullImageSyncReconcilerWithAuth(t *testing.T) {
 						diesourcev1alpha1.ImageRepositoryConditionArtifactAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
 						diesourcev1alpha1.ImageRepositoryConditionImageResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.ImageRepositoryConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+						diesourcev1alpha1.ImageRepositoryConditionSignatureVerifiedBlank.Status(metav1.ConditionTrue).Reason("NotRequired"),
 					)
 				}).DieReleasePtr(),
 			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.ImageRepository]) error {
@@ -1011,6 +2433,6 @@ func TestImageRepositoryPullImageSyncReconcilerWithAuth(t *testing.T) {
 		err := os.RemoveAll(artifactRootDir)
 		utilruntime.Must(err)
 
-		return controllers.ImageRepositoryPullImageSyncReconciler(artifactRootDir, "artifact.example", now)
+		return controllers.ImageRepositoryPullImageSyncReconciler(&controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: artifactRootDir, Host: "artifact.example"}}, controllers.SHA256, now)
 	})
 }
@@ -7,10 +7,10 @@ import (
 	"net/http"
 	"path/filepath"
 	"strings"
-
-	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
+	"time"
 
 	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/mavenmetadata"
 )
 
 // sanitizeFilename returns an error if name is not a plain, single-segment
@@ -28,6 +28,8 @@ func sanitizeFilename(name string) (string, error) {
 type downloadError struct {
 	err            error
 	httpStatuscode int
+	// retryAfter is the delay asked for by the server with Retry-After, if any
+	retryAfter time.Duration
 }
 
 func (d *downloadError) Error() string {
@@ -58,8 +60,8 @@ type MavenResolver struct {
 }
 
 func (r *MavenResolver) Resolve(ctx context.Context, client *http.Client) error {
-	if strings.HasPrefix(r.Artifact.Version, "[") || strings.HasPrefix(r.Artifact.Version, "(") {
-		return fmt.Errorf("Invalid version %q; ranges are not supported", r.Artifact.Version)
+	if mavenmetadata.IsVersionRange(r.Artifact.Version) {
+		return r.processRangeVersion(ctx, client)
 	}
 
 	if r.Artifact.Version == "RELEASE" {
@@ -220,17 +222,47 @@ func (r *MavenResolver) processReleaseVersion(ctx context.Context, client *http.
 	return nil
 }
 
-// imgpkg logger
+func (r *MavenResolver) processRangeVersion(ctx context.Context, client *http.Client) error {
+	// set metadata URL
+	metadataURL := fmt.Sprintf("%s/%s/%s", r.RepositoryURL, r.RequestPath, "maven-metadata.xml")
 
-var _ plainimage.Logger = &NoopLogger{}
+	// get metadata
+	metadata, err := downloadMetadata(ctx, client, metadataURL)
+	if err != nil {
+		return err
+	}
 
-// NewNoopLogger creates a new noop logger
-func NewNoopLogger() *NoopLogger {
-	return &NoopLogger{}
-}
+	// retrieve highest version within the range
+	rv, err := metadata.RangeVersion(r.Artifact.Version)
+	if err != nil {
+		metaxml, _ := xml.MarshalIndent(metadata, "  ", "    ")
+		r.MetaXML = string(metaxml)
+		return err
+	}
+
+	// if the matched version is a SNAPSHOT, process as SNAPSHOT
+	if strings.HasSuffix(rv, "-SNAPSHOT") {
+		r.Artifact.Version = rv
+		return r.processSnapshotVersion(ctx, client)
+	}
+
+	// update artifact details
+	r.Artifact.Version = rv
+	r.ResolvedVersion = rv
 
-// NoopLogger this logger will not print
-type NoopLogger struct{}
+	// set artifact filename
+	if len(r.Artifact.Classifier) > 0 {
+		r.ResolvedFilename = fmt.Sprintf("%s-%s-%s.%s", r.Artifact.ArtifactId, r.ResolvedVersion, r.Artifact.Classifier, r.Artifact.Type)
+	} else {
+		r.ResolvedFilename = fmt.Sprintf("%s-%s.%s", r.Artifact.ArtifactId, r.ResolvedVersion, r.Artifact.Type)
+	}
 
-// Logf does nothing
-func (n NoopLogger) Logf(string, ...interface{}) {}
+	// set artifact download URL
+	r.DownloadURL = fmt.Sprintf("%s/%s/%s/%s", r.RepositoryURL, r.RequestPath, r.ResolvedVersion, r.ResolvedFilename)
+
+	if _, err := sanitizeFilename(r.ResolvedFilename); err != nil {
+		return fmt.Errorf("resolved artifact filename is invalid: %w", err)
+	}
+
+	return nil
+}
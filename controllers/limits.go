@@ -0,0 +1,155 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+)
+
+// errArtifactTooLarge is returned when the content fetched or extracted to
+// produce an artifact exceeds a limit
+var errArtifactTooLarge = errors.New("artifact too large")
+
+// ArtifactLimits bounds the content fetched and extracted to produce an
+// artifact. A zero limit is unlimited.
+type ArtifactLimits struct {
+	// MaxCompressedSize is the largest number of bytes downloaded
+	MaxCompressedSize int64
+	// MaxSize is the largest number of bytes extracted from the downloaded
+	// content
+	MaxSize int64
+	// MaxFiles is the largest number of entries extracted from the
+	// downloaded content
+	MaxFiles int64
+}
+
+// Tighten returns the limits lowered to the limits set by a resource. A
+// resource cannot raise the limits.
+func (l ArtifactLimits) Tighten(limits *sourcev1alpha1.ArtifactLimits) ArtifactLimits {
+	if limits == nil {
+		return l
+	}
+	if limits.MaxCompressedSize != nil {
+		l.MaxCompressedSize = lowerLimit(l.MaxCompressedSize, limits.MaxCompressedSize.Value())
+	}
+	if limits.MaxSize != nil {
+		l.MaxSize = lowerLimit(l.MaxSize, limits.MaxSize.Value())
+	}
+	if limits.MaxFiles != nil {
+		l.MaxFiles = lowerLimit(l.MaxFiles, *limits.MaxFiles)
+	}
+	return l
+}
+
+func lowerLimit(limit, lower int64) int64 {
+	if lower > 0 && (limit <= 0 || lower < limit) {
+		return lower
+	}
+	return limit
+}
+
+// checkCompressedSize fails when the known size of content to download
+// exceeds the limit, before it is downloaded
+func (l ArtifactLimits) checkCompressedSize(size int64) error {
+	if l.MaxCompressedSize > 0 && size > l.MaxCompressedSize {
+		return fmt.Errorf("%w: %d bytes to download exceed the limit of %d bytes", errArtifactTooLarge, size, l.MaxCompressedSize)
+	}
+	return nil
+}
+
+// limitCompressed returns a reader of the downloaded content that fails once
+// more bytes than the limit are read, counting the bytes already downloaded
+// when a download is resumed
+func (l ArtifactLimits) limitCompressed(r io.Reader, downloaded int64) io.Reader {
+	if l.MaxCompressedSize <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, limit: l.MaxCompressedSize, read: downloaded}
+}
+
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	// read one byte past the limit to tell content of exactly the limit
+	// apart from larger content
+	if remaining := r.limit - r.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n, fmt.Errorf("%w: download exceeds the limit of %d bytes", errArtifactTooLarge, r.limit)
+	}
+	return n, err
+}
+
+// extraction tracks the entries and bytes extracted from downloaded content
+// against the limits
+type extraction struct {
+	limits ArtifactLimits
+	files  int64
+	size   int64
+}
+
+func newExtraction(limits ArtifactLimits) *extraction {
+	return &extraction{limits: limits}
+}
+
+// addEntry counts an extracted file, directory or symlink
+func (e *extraction) addEntry() error {
+	e.files++
+	if e.limits.MaxFiles > 0 && e.files > e.limits.MaxFiles {
+		return fmt.Errorf("%w: extracted entries exceed the limit of %d", errArtifactTooLarge, e.limits.MaxFiles)
+	}
+	return nil
+}
+
+// addSize counts the bytes of a file extracted without being copied, such as
+// a hard link
+func (e *extraction) addSize(size int64) error {
+	e.size += size
+	if e.limits.MaxSize > 0 && e.size > e.limits.MaxSize {
+		return fmt.Errorf("%w: extracted content exceeds the limit of %d bytes", errArtifactTooLarge, e.limits.MaxSize)
+	}
+	return nil
+}
+
+// copy extracts the content of a file, failing as soon as the bytes
+// extracted exceed the limit
+func (e *extraction) copy(dst io.Writer, src io.Reader) error {
+	if e.limits.MaxSize <= 0 {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	n, err := io.Copy(dst, io.LimitReader(src, e.limits.MaxSize-e.size+1))
+	e.size += n
+	if err != nil {
+		return err
+	}
+	if e.size > e.limits.MaxSize {
+		return fmt.Errorf("%w: extracted content exceeds the limit of %d bytes", errArtifactTooLarge, e.limits.MaxSize)
+	}
+	return nil
+}
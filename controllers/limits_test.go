@@ -0,0 +1,251 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+)
+
+func TestArtifactLimitsTighten(t *testing.T) {
+	quantity := func(s string) *resource.Quantity {
+		q := resource.MustParse(s)
+		return &q
+	}
+	files := func(n int64) *int64 {
+		return &n
+	}
+	tests := []struct {
+		name     string
+		limits   ArtifactLimits
+		resource *sourcev1alpha1.ArtifactLimits
+		expected ArtifactLimits
+	}{
+		{
+			name:     "no resource limits",
+			limits:   ArtifactLimits{MaxCompressedSize: 100, MaxSize: 200, MaxFiles: 10},
+			expected: ArtifactLimits{MaxCompressedSize: 100, MaxSize: 200, MaxFiles: 10},
+		},
+		{
+			name:     "unlimited",
+			resource: &sourcev1alpha1.ArtifactLimits{MaxCompressedSize: quantity("1Ki"), MaxSize: quantity("1Mi"), MaxFiles: files(10)},
+			expected: ArtifactLimits{MaxCompressedSize: 1024, MaxSize: 1048576, MaxFiles: 10},
+		},
+		{
+			name:     "lowered",
+			limits:   ArtifactLimits{MaxCompressedSize: 2048, MaxSize: 2097152, MaxFiles: 20},
+			resource: &sourcev1alpha1.ArtifactLimits{MaxCompressedSize: quantity("1Ki"), MaxSize: quantity("1Mi"), MaxFiles: files(10)},
+			expected: ArtifactLimits{MaxCompressedSize: 1024, MaxSize: 1048576, MaxFiles: 10},
+		},
+		{
+			name:     "not raised",
+			limits:   ArtifactLimits{MaxCompressedSize: 512, MaxSize: 1024, MaxFiles: 5},
+			resource: &sourcev1alpha1.ArtifactLimits{MaxCompressedSize: quantity("1Ki"), MaxSize: quantity("1Mi"), MaxFiles: files(10)},
+			expected: ArtifactLimits{MaxCompressedSize: 512, MaxSize: 1024, MaxFiles: 5},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := tc.limits.Tighten(tc.resource)
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("Tighten() (-expected, +actual): %s", diff)
+			}
+		})
+	}
+}
+
+func TestArtifactLimitsLimitCompressed(t *testing.T) {
+	limits := ArtifactLimits{MaxCompressedSize: 10}
+
+	content, err := io.ReadAll(limits.limitCompressed(strings.NewReader("0123456789"), 0))
+	if err != nil {
+		t.Fatalf("unexpected error reading content of the limit: %v", err)
+	}
+	if string(content) != "0123456789" {
+		t.Errorf("expected the content to be read, got %q", content)
+	}
+
+	if _, err := io.ReadAll(limits.limitCompressed(strings.NewReader("0123456789a"), 0)); !errors.Is(err, errArtifactTooLarge) {
+		t.Errorf("expected an artifact too large error, got %v", err)
+	}
+	// the bytes downloaded before a download is resumed count towards the
+	// limit
+	if _, err := io.ReadAll(limits.limitCompressed(strings.NewReader("56789a"), 5)); !errors.Is(err, errArtifactTooLarge) {
+		t.Errorf("expected an artifact too large error for a resumed download, got %v", err)
+	}
+}
+
+type tarEntry struct {
+	name     string
+	contents string
+	linkname string
+	typeflag byte
+}
+
+func writeTestTar(t *testing.T, entries []tarEntry) io.Reader {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Linkname: entry.linkname,
+			Typeflag: entry.typeflag,
+			Mode:     0o644,
+			Size:     int64(len(entry.contents)),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write tar entry %q: %v", entry.name, err)
+		}
+		if _, err := tw.Write([]byte(entry.contents)); err != nil {
+			t.Fatalf("failed to write tar entry %q: %v", entry.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestExtractTar(t *testing.T) {
+	tests := []struct {
+		name        string
+		limits      ArtifactLimits
+		entries     []tarEntry
+		expectedErr error
+	}{
+		{
+			name: "files, directories and symlinks",
+			entries: []tarEntry{
+				{name: "./", typeflag: tar.TypeDir},
+				{name: "lib/", typeflag: tar.TypeDir},
+				{name: "lib/app.jar", contents: "jar", typeflag: tar.TypeReg},
+				{name: "app.jar", linkname: "lib/app.jar", typeflag: tar.TypeSymlink},
+			},
+		},
+		{
+			name: "later entries replace earlier entries",
+			entries: []tarEntry{
+				{name: "app.jar", contents: "old", typeflag: tar.TypeReg},
+				{name: "app.jar", linkname: "lib/app.jar", typeflag: tar.TypeSymlink},
+				{name: "lib/app.jar", contents: "jar", typeflag: tar.TypeReg},
+			},
+		},
+		{
+			name: "hard link",
+			entries: []tarEntry{
+				{name: "lib/app.jar", contents: "jar", typeflag: tar.TypeReg},
+				{name: "app.jar", linkname: "lib/app.jar", typeflag: tar.TypeLink},
+			},
+		},
+		{
+			name: "hard link to a missing file",
+			entries: []tarEntry{
+				{name: "app.jar", linkname: "lib/app.jar", typeflag: tar.TypeLink},
+			},
+			expectedErr: fs.ErrNotExist,
+		},
+		{
+			name:   "hard link exceeding the size limit",
+			limits: ArtifactLimits{MaxSize: 4},
+			entries: []tarEntry{
+				{name: "lib/app.jar", contents: "jar", typeflag: tar.TypeReg},
+				{name: "app.jar", linkname: "lib/app.jar", typeflag: tar.TypeLink},
+			},
+			expectedErr: errArtifactTooLarge,
+		},
+		{
+			name: "unsupported entry",
+			entries: []tarEntry{
+				{name: "app.jar", contents: "jar", typeflag: tar.TypeReg},
+				{name: "pipe", typeflag: tar.TypeFifo},
+			},
+			expectedErr: errUnsupportedEntry,
+		},
+		{
+			name: "whiteouts do not remove entries of the same layer",
+			entries: []tarEntry{
+				{name: "app.jar", contents: "jar", typeflag: tar.TypeReg},
+				{name: ".wh.app.jar", typeflag: tar.TypeReg},
+				{name: ".wh..wh..opq", typeflag: tar.TypeReg},
+			},
+		},
+		{
+			name: "symlink outside of the directory",
+			entries: []tarEntry{
+				{name: "evil", linkname: "../..", typeflag: tar.TypeSymlink},
+			},
+			expectedErr: errUnsafeSymlink,
+		},
+		{
+			name: "entry written through a symlink within the directory",
+			entries: []tarEntry{
+				{name: "lib/", typeflag: tar.TypeDir},
+				{name: "lib/parent", linkname: "..", typeflag: tar.TypeSymlink},
+				{name: "lib/parent/app.jar", contents: "jar", typeflag: tar.TypeReg},
+			},
+		},
+		{
+			name:   "too many files",
+			limits: ArtifactLimits{MaxFiles: 2},
+			entries: []tarEntry{
+				{name: "lib/", typeflag: tar.TypeDir},
+				{name: "lib/app.jar", contents: "jar", typeflag: tar.TypeReg},
+				{name: "app.jar", linkname: "lib/app.jar", typeflag: tar.TypeSymlink},
+			},
+			expectedErr: errArtifactTooLarge,
+		},
+		{
+			name:   "too large",
+			limits: ArtifactLimits{MaxSize: 1 << 20},
+			entries: []tarEntry{
+				{name: "bomb.txt", contents: strings.Repeat("0", 1<<21), typeflag: tar.TypeReg},
+			},
+			expectedErr: errArtifactTooLarge,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := extractTar(writeTestTar(t, tc.entries), dir, newExtraction(tc.limits))
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error extracting tarball: %v", err)
+			}
+			if content, err := os.ReadFile(filepath.Join(dir, "app.jar")); err != nil || string(content) != "jar" {
+				t.Fatalf("expected app.jar to contain %q, got %q: %v", "jar", content, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"reconciler.io/runtime/reconcilers"
+)
+
+// a multi-GB fixture is packaged with, for example,
+// 'go test ./controllers -run XXX -bench MavenArtifact -maven-benchmark-size 4294967296'
+var mavenBenchmarkSize = flag.Int64("maven-benchmark-size", 256<<20, "The size in bytes of the Maven artifact packaged by benchmarks.")
+
+// writeBenchmarkJar writes a jar of incompressible entries. Entries of 16MiB
+// are a choice of the benchmark, keeping the number of entries of large jars
+// small.
+func writeBenchmarkJar(b *testing.B, name string, size int64) string {
+	b.Helper()
+	file, err := os.Create(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+	checksum := SHA1.New()
+	zipWriter := zip.NewWriter(io.MultiWriter(file, checksum))
+	if _, err := zipWriter.Create("lib/"); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; size > 0; i++ {
+		entrySize := min(size, 16<<20)
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("lib/%d.jar", i), Method: zip.Store})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.CopyN(w, rand.Reader, entrySize); err != nil {
+			b.Fatal(err)
+		}
+		size -= entrySize
+	}
+	if err := zipWriter.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return fmt.Sprintf("%x", checksum.Sum(nil))
+}
+
+// BenchmarkMavenArtifactPackaging downloads, verifies, extracts, packages and
+// stores a Maven artifact as the download reconciler does. The "temp dir"
+// sub-benchmark is the baseline the reconciler was measured against: the file
+// is read back to be verified and named, and the artifact is packaged in a
+// temporary directory before it is stored.
+func BenchmarkMavenArtifactPackaging(b *testing.B) {
+	repository := b.TempDir()
+	checksum := remoteChecksum{algorithm: SHA1, checksum: writeBenchmarkJar(b, path.Join(repository, "app.jar"), *mavenBenchmarkSize)}
+	server := httptest.NewServer(http.FileServer(http.Dir(repository)))
+	defer server.Close()
+
+	ctx := reconcilers.WithStash(context.Background())
+	storage := &ArtifactStorage{Backend: &FilesystemBackend{RootDir: b.TempDir()}}
+
+	b.Run("staged", func(b *testing.B) {
+		b.SetBytes(*mavenBenchmarkSize)
+		for i := 0; i < b.N; i++ {
+			dir, err := os.MkdirTemp(os.TempDir(), "maven-artifact.*")
+			if err != nil {
+				b.Fatal(err)
+			}
+			artifactDir, artifactSHA1, err := downloadArtifact(ctx, server.URL+"/app.jar", dir, "app.jar", checksum, server.Client(), ArtifactLimits{}, nil, "")
+			if err != nil {
+				b.Fatal(err)
+			}
+			extractedDir, err := extractArchive(dir, path.Join(artifactDir, "app.jar"), ArtifactLimits{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			artifactTgz, err := storage.Stage(ctx, path.Join("mavenartifact", "default", "app", artifactSHA1+".tar.gz"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			_, digest, _, err := createArchive(extractedDir, artifactTgz.Name, "", SHA256)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := artifactTgz.Commit(ctx, digest); err != nil {
+				b.Fatal(err)
+			}
+			artifactTgz.Close()
+			os.RemoveAll(dir)
+		}
+	})
+
+	b.Run("temp dir", func(b *testing.B) {
+		b.SetBytes(*mavenBenchmarkSize)
+		for i := 0; i < b.N; i++ {
+			dir, err := os.MkdirTemp(os.TempDir(), "maven-artifact.*")
+			if err != nil {
+				b.Fatal(err)
+			}
+			artifactFile := path.Join(dir, "app.jar")
+			downloadBenchmarkJar(b, server.URL+"/app.jar", artifactFile)
+			if actual, err := fileChecksum(artifactFile, checksum.algorithm); err != nil || actual != checksum.checksum {
+				b.Fatalf("unable to verify the checksum %q of the artifact: %v", actual, err)
+			}
+			artifactSHA1, err := sha1Checksum(artifactFile)
+			if err != nil {
+				b.Fatal(err)
+			}
+			extractedDir, err := extractArchive(dir, artifactFile, ArtifactLimits{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			artifactTgzDir := path.Join(dir, "artifactTgz")
+			if err := os.Mkdir(artifactTgzDir, os.ModePerm); err != nil {
+				b.Fatal(err)
+			}
+			artifactTgz := path.Join(artifactTgzDir, artifactSHA1+".tar.gz")
+			_, digest, _, err := createArchive(extractedDir, artifactTgz, "", SHA256)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := storage.Put(ctx, path.Join("mavenartifact", "default", "app", artifactSHA1+".tar.gz"), artifactTgz, digest); err != nil {
+				b.Fatal(err)
+			}
+			os.RemoveAll(dir)
+		}
+	})
+}
+
+// downloadBenchmarkJar downloads the jar at url to the file at name
+func downloadBenchmarkJar(b *testing.B, url, name string) {
+	b.Helper()
+	response, err := http.Get(url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer response.Body.Close()
+	out, err := os.Create(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, response.Body); err != nil {
+		b.Fatal(err)
+	}
+}
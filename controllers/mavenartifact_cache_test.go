@@ -0,0 +1,103 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"reconciler.io/runtime/reconcilers"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+)
+
+func TestMavenArtifactCacheKey(t *testing.T) {
+	artifact := sourcev1alpha1.MavenArtifactType{GroupId: "org.springframework", ArtifactId: "spring-core", Version: "RELEASE", Classifier: "sources"}
+	checksum := remoteChecksum{algorithm: SHA1, checksum: "0123"}
+
+	actual := mavenArtifactCacheKey(artifact, "5.3.23", "spring-core-5.3.23-sources.jar", checksum)
+	expected := "maven/org/springframework/spring-core/5.3.23/spring-core-5.3.23-sources.jar@sha1:0123"
+	if actual != expected {
+		t.Errorf("expected key %q, got %q", expected, actual)
+	}
+}
+
+func TestDownloadArtifactCache(t *testing.T) {
+	content := "jar content"
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	cache, err := blobcache.New(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := remoteChecksum{algorithm: SHA1, checksum: fmt.Sprintf("%x", sha1.Sum([]byte(content)))}
+	key := mavenArtifactCacheKey(sourcev1alpha1.MavenArtifactType{GroupId: "com.example", ArtifactId: "app"}, "1.0.0", "app-1.0.0.jar", checksum)
+	ctx := reconcilers.WithStash(context.Background())
+
+	download := func(limits ArtifactLimits) (string, error) {
+		dir, sha1, err := downloadArtifact(ctx, server.URL+"/app-1.0.0.jar", t.TempDir(), "app-1.0.0.jar", checksum, server.Client(), limits, cache, key)
+		if err != nil {
+			return "", err
+		}
+		if sha1 != checksum.checksum {
+			t.Errorf("expected the sha1 %q, got %q", checksum.checksum, sha1)
+		}
+		actual, err := os.ReadFile(path.Join(dir, "app-1.0.0.jar"))
+		return string(actual), err
+	}
+
+	for i := 0; i < 2; i++ {
+		actual, err := download(ArtifactLimits{})
+		if err != nil {
+			t.Fatalf("unexpected error downloading artifact: %v", err)
+		}
+		if actual != content {
+			t.Errorf("expected the artifact to contain %q, got %q", content, actual)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the artifact to be downloaded once, got %d requests", requests)
+	}
+
+	// the cached file is held to the limits of each resource
+	if _, err := download(ArtifactLimits{MaxCompressedSize: 4}); !errors.Is(err, errArtifactTooLarge) {
+		t.Errorf("expected an artifact too large error, got %v", err)
+	}
+
+	// files not matching the checksum are never cached
+	content = "tampered"
+	otherKey := mavenArtifactCacheKey(sourcev1alpha1.MavenArtifactType{GroupId: "com.example", ArtifactId: "app"}, "1.0.1", "app-1.0.1.jar", checksum)
+	if _, _, err := downloadArtifact(ctx, server.URL+"/app-1.0.1.jar", t.TempDir(), "app-1.0.1.jar", checksum, server.Client(), ArtifactLimits{}, cache, otherKey); err == nil {
+		t.Fatalf("expected a checksum error")
+	}
+	if _, err := cache.Open(otherKey); err == nil {
+		t.Errorf("expected the tampered artifact not to be cached")
+	}
+}
@@ -18,18 +18,23 @@ package controllers
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
-	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
@@ -40,6 +45,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 
 	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
 	"github.com/vmware-tanzu/tanzu-source-controller/pkg/mavenmetadata"
 )
 
@@ -47,6 +53,7 @@ const (
 	MavenArtifactVersionStashKey    reconcilers.StashKey = sourcev1alpha1.Group + "/artifact-version"
 	MavenArtifactAuthSecretStashKey reconcilers.StashKey = sourcev1alpha1.Group + "/auth-secret"
 	MavenArtifactHttpClientKey      reconcilers.StashKey = sourcev1alpha1.Group + "/http-client"
+	MavenArtifactTrustedKeysKey     reconcilers.StashKey = sourcev1alpha1.Group + "/trusted-keys"
 )
 
 type MavenArtifactAuthOptionsFromSecret struct {
@@ -66,16 +73,19 @@ type ArtifactDetails struct {
 	ArtifactDownloadURL string
 }
 
-// artifactCache contains artifact source and checksum iformation saved on disc
+// artifactCache contains artifact source and checksum iformation saved alongside the artifact
 type artifactCache struct {
 	// source where the artifact came from
 	source string
-	// checksum of the artifact
+	// checksum of the artifact in the form of '<algorithm>:<checksum>'
 	checksum string
+	// signer is the fingerprint of the trusted key that signed the artifact,
+	// empty when the signature was not verified
+	signer string
 }
 
 func (ac *artifactCache) toString() string {
-	return fmt.Sprintf("%s|%s", ac.source, ac.checksum)
+	return fmt.Sprintf("%s|%s|%s", ac.source, ac.checksum, ac.signer)
 }
 
 //+kubebuilder:rbac:groups=source.apps.tanzu.vmware.com,resources=mavenartifacts,verbs=get;list;watch;create;update;patch;delete
@@ -83,15 +93,15 @@ func (ac *artifactCache) toString() string {
 //+kubebuilder:rbac:groups=source.apps.tanzu.vmware.com,resources=mavenartifacts/finalizers,verbs=update
 //+kubebuilder:rbac:groups=core;events.k8s.io,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func MavenArtifactReconciler(c reconcilers.Config, httpRootDir, httpHost string, now func() metav1.Time, certs []Cert) *reconcilers.ResourceReconciler[*sourcev1alpha1.MavenArtifact] {
+func MavenArtifactReconciler(c reconcilers.Config, storage *ArtifactStorage, digestAlgorithm, checksumFloor DigestAlgorithm, now func() metav1.Time, certs []Cert) *reconcilers.ResourceReconciler[*sourcev1alpha1.MavenArtifact] {
 	return &reconcilers.ResourceReconciler[*sourcev1alpha1.MavenArtifact]{
 		Reconciler: &reconcilers.WithFinalizer[*sourcev1alpha1.MavenArtifact]{
 			Finalizer: sourcev1alpha1.Group + "/finalizer",
 			Reconciler: reconcilers.Sequence[*sourcev1alpha1.MavenArtifact]{
 				MavenArtifactSecretsSyncReconciler(certs),
 				MavenArtifactVersionSyncReconciler(),
-				MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost, now),
-				MavenArtifactIntervalReconciler(),
+				MavenArtifactDownloadSyncReconciler(storage, digestAlgorithm, checksumFloor, now),
+				MavenArtifactIntervalReconciler(storage),
 			},
 		},
 
@@ -144,6 +154,24 @@ func MavenArtifactSecretsSyncReconciler(certs []Cert) reconcilers.SubReconciler[
 				stashAuthSecret(ctx, authSecret)
 			}
 
+			if ref := parent.Spec.TrustedKeysSecretRef; ref != nil {
+				trustedKeysSecret := corev1.Secret{}
+				err := c.TrackAndGet(ctx, types.NamespacedName{Namespace: parent.Namespace, Name: ref.Name}, &trustedKeysSecret)
+				if err != nil {
+					if apierrs.IsNotFound(err) {
+						parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactResolved, "SecretMissing", "Secret %q not found in namespace %q", ref.Name, parent.Namespace)
+						return nil
+					}
+					return err
+				}
+				trustedKeys, err := readTrustedKeys(trustedKeysSecret)
+				if err != nil {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactResolved, "InvalidSecret", "Secret %q does not contain trusted OpenPGP keys: %s", ref.Name, err)
+					return nil
+				}
+				stashTrustedKeys(ctx, trustedKeys)
+			}
+
 			reconcileCerts := certs
 			if authSecret.Data != nil {
 				certBytes := authSecret.Data["caFile"]
@@ -175,7 +203,7 @@ func MavenArtifactSecretsSyncReconciler(certs []Cert) reconcilers.SubReconciler[
 func MavenArtifactVersionSyncReconciler() reconcilers.SubReconciler[*sourcev1alpha1.MavenArtifact] {
 	return &reconcilers.SyncReconciler[*sourcev1alpha1.MavenArtifact]{
 		Name: "MavenArtifactVersionSyncReconciler",
-		Sync: func(ctx context.Context, parent *sourcev1alpha1.MavenArtifact) error {
+		SyncWithResult: requeueAfterRetry(func(ctx context.Context, parent *sourcev1alpha1.MavenArtifact) error {
 			log := logr.FromContextOrDiscard(ctx)
 			client := retrieveHttpClient(ctx)
 			if client == nil {
@@ -225,11 +253,11 @@ func MavenArtifactVersionSyncReconciler() reconcilers.SubReconciler[*sourcev1alp
 					log.Error(err, "error downloading artifact metadata", "statuscode", dlerr.httpStatuscode)
 					// retry for statuscode 429
 					if dlerr.httpStatuscode == http.StatusTooManyRequests {
-						return dlerr.err
+						return dlerr
 					}
 					// don't update condition for statuscodes in 500 range
 					if dlerr.httpStatuscode >= 500 {
-						return dlerr.err
+						return dlerr
 					}
 
 					if dlerr.httpStatuscode == 401 {
@@ -263,20 +291,20 @@ func MavenArtifactVersionSyncReconciler() reconcilers.SubReconciler[*sourcev1alp
 				stashArtifactVersion(ctx, artifactDetails)
 				return nil
 			}
-		},
+		}),
 	}
 }
 
-func MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost string, now func() metav1.Time) reconcilers.SubReconciler[*sourcev1alpha1.MavenArtifact] {
+func MavenArtifactDownloadSyncReconciler(storage *ArtifactStorage, digestAlgorithm, checksumFloor DigestAlgorithm, now func() metav1.Time) reconcilers.SubReconciler[*sourcev1alpha1.MavenArtifact] {
 	return &reconcilers.SyncReconciler[*sourcev1alpha1.MavenArtifact]{
 		Name: "MavenArtifactDownloadSyncReconciler",
 		Finalize: func(ctx context.Context, parent *sourcev1alpha1.MavenArtifact) error {
 			log := logr.FromContextOrDiscard(ctx)
-			dir := path.Join(httpRootDir, "mavenartifact", parent.Namespace, parent.Name)
+			dir := path.Join("mavenartifact", parent.Namespace, parent.Name)
 			log.Info("removing artifacts", "dir", dir)
-			return os.RemoveAll(dir)
+			return storage.Remove(ctx, dir)
 		},
-		Sync: func(ctx context.Context, parent *sourcev1alpha1.MavenArtifact) error {
+		SyncWithResult: requeueAfterRetry(func(ctx context.Context, parent *sourcev1alpha1.MavenArtifact) error {
 			log := logr.FromContextOrDiscard(ctx)
 
 			artifactInfo := retrieveArtifactVersion(ctx)
@@ -295,13 +323,13 @@ func MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost string, now func(
 			ctx, cancel := context.WithTimeout(ctx, timeout)
 			defer cancel()
 
-			// GET artifact checksum (SHA1)
-			remoteChecksum, err := downloadChecksum(ctx, client, artifactInfo.ArtifactDownloadURL)
+			// GET the strongest artifact checksum available
+			remoteChecksum, err := downloadChecksum(ctx, client, artifactInfo.ArtifactDownloadURL, checksumFloor)
 			if err != nil {
 				// handle timeout error
 				if errors.Is(err, context.DeadlineExceeded) {
 					parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "Timeout",
-						"Request timeout error downloading Maven artifact checksum file %q: %s", fmt.Sprintf("%s.%s", artifactInfo.ArtifactDownloadURL, "sha1"), err.Error())
+						"Request timeout error downloading Maven artifact checksum file %q: %s", remoteChecksum.url, err.Error())
 					return nil
 				}
 
@@ -311,22 +339,22 @@ func MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost string, now func(
 					log.Error(err, "error downloading artifact checksum", "statuscode", dlerr.httpStatuscode)
 					// retry for statuscode 429
 					if dlerr.httpStatuscode == http.StatusTooManyRequests {
-						return dlerr.err
+						return dlerr
 					}
 					// don't update condition for statuscodes in 500 range
 					if dlerr.httpStatuscode >= 500 {
-						return dlerr.err
+						return dlerr
 					}
 					if dlerr.httpStatuscode == 401 {
 						parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "RemoteError",
-							`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v.sha1". Check the credentials provided in the Secret.`, artifactInfo.ArtifactDownloadURL)
+							`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL %q. Check the credentials provided in the Secret.`, remoteChecksum.url)
 					} else if dlerr.httpStatuscode == 404 {
 						parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "RemoteError",
-							`Maven artifact checksum file not found (HTTP 404) at URL "%v.sha1".`, artifactInfo.ArtifactDownloadURL)
+							`Maven artifact checksum file not found (HTTP 404) at URL "%v.{%s}".`, artifactInfo.ArtifactDownloadURL, joinDigestAlgorithms(checksumAlgorithmsDownTo(checksumFloor)))
 					} else {
 						// for all other download errors, including 404 will update the status condition
 						parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "RemoteError",
-							`Error downloading Maven artifact checksum from URL "%v.sha1": %v`, artifactInfo.ArtifactDownloadURL, err)
+							`Error downloading Maven artifact checksum from URL %q: %v`, remoteChecksum.url, err)
 					}
 					return nil
 				}
@@ -337,28 +365,46 @@ func MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost string, now func(
 			}
 
 			// Retrieve cache data if exist
-			cacheFile := fmt.Sprintf("%s/%s.%s", path.Join(httpRootDir, "mavenartifact", parent.Namespace, parent.Name), artifactInfo.ResolvedFileName, "sha1")
-			cache, err := retrieveChecksumFromFile(cacheFile)
+			cacheFile := fmt.Sprintf("%s/%s.%s", path.Join("mavenartifact", parent.Namespace, parent.Name), artifactInfo.ResolvedFileName, "sha1")
+			cache, err := retrieveChecksumFromFile(ctx, storage.Backend, cacheFile)
 			if err != nil {
-				log.Error(err, "Error reading Maven artifact checksum file", "filename", path.Join(httpRootDir, "mavenartifact", parent.Namespace, parent.Name))
+				log.Error(err, "Error reading Maven artifact checksum file", "filename", cacheFile)
 				return err
 			}
 
-			// Compare checksum with cache if the resource status.artifact is set
-			if cache != nil && parent.Status.Artifact != nil {
-				if cache.checksum == remoteChecksum && cache.source == artifactInfo.ArtifactDownloadURL {
-					log.Info("download skipped", "checksum matched on disc", cache.checksum, "checksum from remote repository", remoteChecksum)
+			trustedKeys := retrieveTrustedKeys(ctx)
+
+			// Compare checksum with cache if the resource status.artifact is set in the requested format
+			if cache != nil && parent.Status.Artifact != nil && digestAlgorithm.Matches(parent.Status.Artifact.Digest) && strings.HasSuffix(parent.Status.Artifact.Path, parent.Spec.Format.Extension()) {
+				if cache.checksum == remoteChecksum.String() && cache.source == artifactInfo.ArtifactDownloadURL && isTrustedSigner(trustedKeys, cache.signer) {
+					log.Info("download skipped", "checksum matched on disc", cache.checksum, "checksum from remote repository", remoteChecksum.String())
+					if storage.URLSigner != nil {
+						// signed URLs are refreshed before they expire
+						httpUrl, err := storage.URL(ctx, parent.Status.Artifact.Path, parent.Status.Artifact.URL)
+						if err != nil {
+							return err
+						}
+						parent.Status.URL = httpUrl
+						parent.Status.Artifact.URL = httpUrl
+					}
+					if err := storage.Index(ctx, parent.Status.Artifact); err != nil {
+						return err
+					}
+					latestUrl, err := storage.LatestURL(ctx, parent.Status.Artifact, parent.Status.LatestURL)
+					if err != nil {
+						return err
+					}
+					parent.Status.LatestURL = latestUrl
+					if err := storage.Prune(ctx, parent.Status.Artifact.Path, parent.Spec.RevisionHistoryLimit); err != nil {
+						log.Error(err, "unable to prune artifact revisions", "artifact", artifactInfo.ResolvedFileName)
+					}
 					return nil
 				} else {
-					log.Info("download continue", "checksum matched on disc", cache.checksum, "checksum from remote repository", remoteChecksum)
+					log.Info("download continue", "checksum matched on disc", cache.checksum, "checksum from remote repository", remoteChecksum.String())
 				}
 			}
 
 			// Set temp dir
-			if !path.IsAbs(httpRootDir) {
-				httpRootDir = path.Join(httpRootDir)
-			}
-
 			dir, err := os.MkdirTemp(os.TempDir(), "maven-artifact.*")
 			if err != nil {
 				return err
@@ -366,8 +412,15 @@ func MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost string, now func(
 			defer os.RemoveAll(dir)
 
 			// Download the artifact
-			artifactDir, err := downloadArtifact(ctx, artifactInfo.ArtifactDownloadURL, dir, artifactInfo.ResolvedFileName, remoteChecksum, client)
+			limits := storage.Limits.Tighten(parent.Spec.Limits)
+			cacheKey := mavenArtifactCacheKey(parent.Spec.Artifact, artifactInfo.ArtifactVersion, artifactInfo.ResolvedFileName, remoteChecksum)
+			artifactDir, artifactSHA1, err := downloadArtifact(ctx, artifactInfo.ArtifactDownloadURL, dir, artifactInfo.ResolvedFileName, remoteChecksum, client, limits, storage.Cache, cacheKey)
 			if err != nil {
+				if errors.Is(err, errArtifactTooLarge) {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "ArtifactTooLarge",
+						"Maven artifact file %q is too large: %s", artifactInfo.ResolvedFileName, err)
+					return nil
+				}
 				if errors.Is(err, context.DeadlineExceeded) {
 					parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "Timeout",
 						"Request timeout error downloading Maven artifact file %q: %s", parent.Spec.Artifact.ArtifactId, err.Error())
@@ -379,11 +432,11 @@ func MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost string, now func(
 					log.Error(err, "error downloading Maven artifact file", "statuscode", dlerr.httpStatuscode)
 					// Retry for statuscode 429
 					if dlerr.httpStatuscode == http.StatusTooManyRequests {
-						return dlerr.err
+						return dlerr
 					}
 					// No need to update condition for statuscodes in 500 range
 					if dlerr.httpStatuscode >= 500 {
-						return dlerr.err
+						return dlerr
 					}
 					if dlerr.httpStatuscode == 401 {
 						parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "DownloadError",
@@ -405,17 +458,48 @@ func MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost string, now func(
 				return nil
 			}
 
-			// Establish unique file name by creating a sha1 of downloaded file
-			artifactTgzFilename, err := sha1Checksum(path.Join(artifactDir, artifactInfo.ResolvedFileName))
-			if err != nil {
-				return err
+			// Verify the detached signature of the artifact
+			signer := ""
+			if trustedKeys != nil {
+				signatureURL := artifactInfo.ArtifactDownloadURL + ".asc"
+				signer, err = verifyArtifactSignature(ctx, client, signatureURL, path.Join(artifactDir, artifactInfo.ResolvedFileName), trustedKeys)
+				if err != nil {
+					if errors.Is(err, context.DeadlineExceeded) {
+						parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "Timeout",
+							"Request timeout error downloading Maven artifact signature file %q: %s", signatureURL, err.Error())
+						return nil
+					}
+					dlerr, isDownloadError := err.(*downloadError)
+					if isDownloadError {
+						log.Error(err, "error downloading Maven artifact signature file", "statuscode", dlerr.httpStatuscode)
+						// Retry for statuscode 429 and statuscodes in 500 range
+						if dlerr.httpStatuscode == http.StatusTooManyRequests || dlerr.httpStatuscode >= 500 {
+							return dlerr
+						}
+						if dlerr.httpStatuscode == 404 {
+							parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "SignatureError",
+								`Maven artifact signature file not found (HTTP 404) at URL %q.`, signatureURL)
+							return nil
+						}
+					}
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "SignatureError",
+						"Unable to verify signature of Maven artifact file %q: %s", artifactInfo.ResolvedFileName, err.Error())
+					return nil
+				}
 			}
-			artifactTgzFilename = fmt.Sprintf("%s.tar.gz", artifactTgzFilename)
+
+			// Establish unique file name from the sha1 of downloaded file
+			artifactTgzFilename := artifactSHA1 + parent.Spec.Format.Extension()
 
 			// Unpack if artifact is an archive
 			artifactFilePath := path.Join(artifactDir, artifactInfo.ResolvedFileName)
 			if isArchive(artifactFilePath) {
-				artifactDir, err = extractArchive(dir, artifactFilePath)
+				artifactDir, err = extractArchive(dir, artifactFilePath, limits)
+				if errors.Is(err, errArtifactTooLarge) {
+					parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "ArtifactTooLarge",
+						"Maven artifact file %q is too large to extract: %s", artifactInfo.ResolvedFileName, err)
+					return nil
+				}
 				if err != nil {
 					parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "FileError",
 						"Failed to extract Maven artifact file %q", artifactInfo.ResolvedFileName)
@@ -424,45 +508,56 @@ func MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost string, now func(
 				}
 			}
 
-			artifactTgzDir := path.Join(dir, "artifactTgz")
-			err = os.Mkdir(artifactTgzDir, os.ModePerm)
+			httpPath := path.Join("mavenartifact", parent.Namespace, parent.Name, artifactTgzFilename)
+			httpUrl, err := storage.URL(ctx, httpPath, parent.Status.URL)
 			if err != nil {
 				return err
 			}
 
-			artifactTgz := path.Join(artifactTgzDir, artifactTgzFilename)
-
-			// package directory as tgz
-			if err := createTarGz(artifactDir, artifactTgz); err != nil {
-				log.Error(err, "error creating tar", "dir", artifactDir, "file", artifactTgz)
+			// package directory in the requested format, written where it is
+			// stored when able rather than copied there
+			artifactTgz, err := storage.Stage(ctx, httpPath)
+			if err != nil {
+				return err
+			}
+			defer artifactTgz.Close()
+			checksum, digest, files, err := createArchive(artifactDir, artifactTgz.Name, parent.Spec.Format, digestAlgorithm)
+			if errors.Is(err, errUnsafeSymlink) {
+				parent.ManageConditions().MarkFalse(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "UnsafeSymlink",
+					"Unable to package Maven artifact file %q: %s", artifactInfo.ResolvedFileName, err)
+				return nil
+			}
+			if err != nil {
+				log.Error(err, "error creating tar", "dir", artifactDir, "file", artifactTgz.Name)
 				return fmt.Errorf("Error creating tar file for Maven artifact file %q: %w", artifactTgzFilename, err)
 			}
 
-			// create sha1 checksum for artifact.tgz
-			checksum, err := sha1Checksum(artifactTgz)
-			if err != nil {
+			// the manifest is stored first, an artifact is never served
+			// without it
+			stored := now().Rfc3339Copy()
+			if err := storage.PutManifest(ctx, httpPath, &ArtifactManifest{Revision: artifactInfo.ResolvedFileName, Stored: &stored, Files: files}); err != nil {
 				return err
 			}
 
-			httpPath := path.Join("mavenartifact", parent.Namespace, parent.Name, artifactTgzFilename)
-			httpUrl := fmt.Sprintf("http://%s", path.Join(httpHost, httpPath))
-
-			// copy artifact.tgz into httpRoot with a placeholder name
-			if err := copyCompressedFile(artifactTgz, path.Join(httpRootDir, httpPath)); err != nil {
+			// store artifact.tgz to be served
+			if err := artifactTgz.Commit(ctx, digest); err != nil {
 				return err
 			}
 
 			// add artifact cached data
 			cacheData := artifactCache{
 				source:   artifactInfo.ArtifactDownloadURL,
-				checksum: remoteChecksum,
+				checksum: remoteChecksum.String(),
+				signer:   signer,
 			}
-			if err := os.WriteFile(cacheFile, []byte(cacheData.toString()), os.ModePerm); err != nil {
+			cacheContent := cacheData.toString()
+			if err := storage.Backend.Put(ctx, cacheFile, strings.NewReader(cacheContent), int64(len(cacheContent))); err != nil {
 				return err
 			}
 
 			parent.Status.Artifact = preserveArtifactLastUpdateTime(parent.Status.Artifact, &sourcev1alpha1.Artifact{
 				Checksum:       checksum,
+				Digest:         digest,
 				Revision:       artifactInfo.ResolvedFileName,
 				Path:           httpPath,
 				URL:            httpUrl,
@@ -470,17 +565,37 @@ func MavenArtifactDownloadSyncReconciler(httpRootDir, httpHost string, now func(
 			})
 			parent.Status.URL = httpUrl
 
-			parent.ManageConditions().MarkTrue(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "Available", "")
+			if signer != "" {
+				parent.ManageConditions().MarkTrue(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "Available", "Maven artifact verified with %s checksum and OpenPGP signature by key %s", remoteChecksum.algorithm, signer)
+			} else {
+				parent.ManageConditions().MarkTrue(sourcev1alpha1.MavenArtifactConditionArtifactAvailable, "Available", "Maven artifact verified with %s checksum", remoteChecksum.algorithm)
+			}
+
+			// point the latest alias at the new artifact
+			if err := storage.Index(ctx, parent.Status.Artifact); err != nil {
+				return err
+			}
+			latestUrl, err := storage.LatestURL(ctx, parent.Status.Artifact, parent.Status.LatestURL)
+			if err != nil {
+				return err
+			}
+			parent.Status.LatestURL = latestUrl
+
+			// older revisions are retained for consumers that have not yet
+			// observed the new artifact, pruning is retried on the next reconcile
+			if err := storage.Prune(ctx, httpPath, parent.Spec.RevisionHistoryLimit); err != nil {
+				log.Error(err, "unable to prune artifact revisions", "artifact", artifactInfo.ResolvedFileName)
+			}
 			return nil
-		},
+		}),
 	}
 }
 
-func MavenArtifactIntervalReconciler() reconcilers.SubReconciler[*sourcev1alpha1.MavenArtifact] {
+func MavenArtifactIntervalReconciler(storage *ArtifactStorage) reconcilers.SubReconciler[*sourcev1alpha1.MavenArtifact] {
 	return &reconcilers.SyncReconciler[*sourcev1alpha1.MavenArtifact]{
 		Name: "MavenArtifactIntervalReconciler",
 		SyncWithResult: func(ctx context.Context, parent *sourcev1alpha1.MavenArtifact) (controllerruntime.Result, error) {
-			return controllerruntime.Result{RequeueAfter: parent.Spec.Interval.Duration}, nil
+			return controllerruntime.Result{RequeueAfter: requeueInterval(parent.Spec.Interval.Duration, storage)}, nil
 		},
 	}
 }
@@ -525,63 +640,271 @@ func downloadMetadata(ctx context.Context, client *http.Client, url string) (*ma
 	return parsedData, nil
 }
 
-func downloadChecksum(ctx context.Context, client *http.Client, url string) (string, error) {
-	// download checksum
-	checksum, err := download(ctx, fmt.Sprintf("%s.%s", url, "sha1"), client)
-	if err != nil {
-		return "", err
+// remoteChecksum is the content of a checksum file published by a Maven
+// repository alongside an artifact, such as "<artifact>.sha256"
+type remoteChecksum struct {
+	// algorithm used to compute the checksum
+	algorithm DigestAlgorithm
+	// checksum is the lowercase hex encoded checksum value
+	checksum string
+	// url of the checksum file
+	url string
+}
+
+func (c remoteChecksum) String() string {
+	return fmt.Sprintf("%s:%s", c.algorithm, c.checksum)
+}
+
+// downloadChecksum negotiates the strongest checksum file published for the
+// artifact at url. Algorithms are tried from strongest to weakest, stopping at
+// the floor; a missing file (HTTP 404) moves on to the next algorithm while any
+// other error is returned. The url of the last checksum file requested is
+// always returned to aid error reporting.
+func downloadChecksum(ctx context.Context, client *http.Client, url string, floor DigestAlgorithm) (remoteChecksum, error) {
+	checksum := remoteChecksum{}
+	for _, algorithm := range checksumAlgorithmsDownTo(floor) {
+		checksum.algorithm = algorithm
+		checksum.url = fmt.Sprintf("%s.%s", url, algorithm)
+		content, err := download(ctx, checksum.url, client)
+		if err != nil {
+			if dlerr, ok := err.(*downloadError); ok && dlerr.httpStatuscode == http.StatusNotFound {
+				continue
+			}
+			return checksum, err
+		}
+		// checksum files may contain the name of the file after the checksum
+		fields := strings.Fields(string(content))
+		if len(fields) == 0 {
+			return checksum, fmt.Errorf("Maven artifact checksum file %q is empty", checksum.url)
+		}
+		checksum.checksum = strings.ToLower(fields[0])
+		return checksum, nil
+	}
+	return checksum, &downloadError{
+		err:            fmt.Errorf("Error no checksum file found for %q with extensions %s", url, joinDigestAlgorithms(checksumAlgorithmsDownTo(floor))),
+		httpStatuscode: http.StatusNotFound,
 	}
-	return string(checksum), nil
 }
 
-func downloadArtifact(ctx context.Context, url string, dir string, fileName string, checksum string, client *http.Client) (string, error) {
+func joinDigestAlgorithms(algorithms []DigestAlgorithm) string {
+	names := make([]string, len(algorithms))
+	for i, a := range algorithms {
+		names[i] = string(a)
+	}
+	return strings.Join(names, ",")
+}
+
+// mavenArtifactCacheKey identifies a downloaded Maven artifact file in the
+// cache by its coordinates, in the layout of a local Maven repository, and
+// its checksum. The file name holds the classifier and type of the artifact.
+func mavenArtifactCacheKey(artifact sourcev1alpha1.MavenArtifactType, version, fileName string, checksum remoteChecksum) string {
+	return path.Join("maven", strings.ReplaceAll(artifact.GroupId, ".", "/"), artifact.ArtifactId, version, fileName) + "@" + checksum.String()
+}
+
+// downloadArtifact downloads the artifact file into the directory and
+// verifies it against the checksum, returning the directory and the SHA-1
+// checksum of the file. The file is hashed as it is downloaded. Files
+// verified are added to the cache, when not nil, and reused rather than
+// downloaded again.
+func downloadArtifact(ctx context.Context, url string, dir string, fileName string, checksum remoteChecksum, client *http.Client, limits ArtifactLimits, cache *blobcache.Cache, cacheKey string) (string, string, error) {
 	artifactDir := path.Join(dir, "artifact")
 	err := os.Mkdir(artifactDir, os.ModePerm)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	if cache != nil {
+		// the file was downloaded and verified for another resource
+		cached, err := linkCachedArtifact(cache, cacheKey, path.Join(artifactDir, fileName), limits)
+		if err != nil {
+			return "", "", err
+		}
+		if cached {
+			if checksum.algorithm == SHA1 {
+				return artifactDir, checksum.checksum, nil
+			}
+			fileSHA1, err := sha1Checksum(path.Join(artifactDir, fileName))
+			if err != nil {
+				return "", "", err
+			}
+			return artifactDir, fileSHA1, nil
+		}
 	}
 	out, err := os.Create(path.Join(artifactDir, fileName))
 	if err != nil {
-		return "", fmt.Errorf("Error creating local Maven artifact file %s %s", fileName, err)
+		return "", "", fmt.Errorf("Error creating local Maven artifact file %s %s", fileName, err)
 	}
 	defer out.Close()
 
-	// build httpRequest object
-	request, err := buildRequestObject(ctx, "GET", url, basicAuthCredentialsFromSecret(ctx))
-	if err != nil {
-		return "", fmt.Errorf("Error %q while request parsing URL %q", err, url)
+	// hash the file as it is downloaded rather than reading it back
+	verifier := checksum.algorithm.New()
+	fileSHA1 := verifier
+	hashes := io.Writer(verifier)
+	if checksum.algorithm != SHA1 {
+		fileSHA1 = SHA1.New()
+		hashes = io.MultiWriter(verifier, fileSHA1)
 	}
+	// an interrupted download resumes from the bytes received, when the
+	// repository serves ranges of the same file
+	received := int64(0)
+	validator := ""
+	err = downloadRetries.do(ctx, func() error {
+		// build httpRequest object
+		request, err := buildRequestObject(ctx, "GET", url, basicAuthCredentialsFromSecret(ctx))
+		if err != nil {
+			return fmt.Errorf("Error %q while request parsing URL %q", err, url)
+		}
+		if received > 0 {
+			request.Header.Set("Range", fmt.Sprintf("bytes=%d-", received))
+			if validator != "" {
+				request.Header.Set("If-Range", validator)
+			}
+		}
+
+		// process request
+		response, err := client.Do(request)
+		if err != nil {
+			return transientError(fmt.Errorf("%s download error %s", url, err), err)
+		}
+		defer response.Body.Close()
 
-	// process request
-	response, err := client.Do(request)
+		if received > 0 && response.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			// every byte was received before the connection dropped
+			if contentRangeSize(response) == received {
+				return nil
+			}
+			// otherwise the file changed, it is downloaded again without a
+			// range
+			if err := restartDownload(out, verifier, fileSHA1); err != nil {
+				return err
+			}
+			received = 0
+			return &retryableError{err: responseError(url, response)}
+		}
+		// if no error from the client, inspect statuscode and return download error from non 200s
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			return responseError(url, response)
+		}
+		if received > 0 && (response.StatusCode != http.StatusPartialContent || contentRangeStart(response) != received) {
+			// the whole file is sent again
+			if err := restartDownload(out, verifier, fileSHA1); err != nil {
+				return err
+			}
+			received = 0
+		}
+		if etag := response.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			validator = etag
+		} else {
+			validator = response.Header.Get("Last-Modified")
+		}
+
+		// reject artifacts known to be too large before downloading them
+		if response.ContentLength >= 0 {
+			if err := limits.checkCompressedSize(received + response.ContentLength); err != nil {
+				return err
+			}
+		}
+
+		// copy response body to file
+		n, err := io.Copy(out, io.TeeReader(limits.limitCompressed(response.Body, received), hashes))
+		received += n
+		if errors.Is(err, errArtifactTooLarge) {
+			return err
+		}
+		if err != nil {
+			return transientError(fmt.Errorf("Error downloading Maven artifact file data %q: %q", out.Name(), err), err)
+		}
+		return nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("%s download error %s", url, err)
+		return "", "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", "", fmt.Errorf("Error writing Maven artifact file %q: %q", out.Name(), err)
 	}
-	defer response.Body.Close()
 
-	// if no error from the client, inspect statuscode and return download error from non 200s
-	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return "", &downloadError{err: fmt.Errorf("Error received HTTP status %v getting %q", response.StatusCode, url), httpStatuscode: response.StatusCode}
+	// verify checksum
+	actualChecksum := fmt.Sprintf("%x", verifier.Sum(nil))
+	if actualChecksum != checksum.checksum {
+		return "", "", fmt.Errorf("Checksum (%v) of downloaded Maven artifact file %q does not match expected remote %s checksum (%v). This file may have been tampered with in transit!", actualChecksum, out.Name(), checksum.algorithm, checksum.checksum)
 	}
+	if cache != nil {
+		if err := cache.Put(cacheKey, out.Name()); err != nil {
+			// the artifact is still packaged
+			logr.FromContextOrDiscard(ctx).Error(err, "unable to cache Maven artifact file", "file", fileName)
+		}
+	}
+	return artifactDir, fmt.Sprintf("%x", fileSHA1.Sum(nil)), nil
+}
 
-	// copy response body to file
-	_, err = io.Copy(out, response.Body)
+// linkCachedArtifact links the cached artifact file for the key at name,
+// reporting whether it was cached. The size of the file is checked against
+// the limits as it would be when downloaded.
+func linkCachedArtifact(cache *blobcache.Cache, key, name string, limits ArtifactLimits) (bool, error) {
+	err := cache.Link(key, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		cacheRequests.WithLabelValues(cacheContentMavenArtifact, cacheMiss).Inc()
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	cacheRequests.WithLabelValues(cacheContentMavenArtifact, cacheHit).Inc()
+	info, err := os.Stat(name)
 	if err != nil {
-		return "", fmt.Errorf("Error downloading Maven artifact file data %q: %q", out.Name(), err)
+		return false, err
+	}
+	if err := limits.checkCompressedSize(info.Size()); err != nil {
+		return false, err
 	}
+	return true, nil
+}
 
-	os.Chtimes(out.Name(), time.UnixMilli(0), time.UnixMilli(0))
+// contentRangeStart returns the offset of the first byte of a partial
+// response, or -1 when the Content-Range header is missing or invalid
+func contentRangeStart(response *http.Response) int64 {
+	// bytes <first>-<last>/<size>
+	value, ok := strings.CutPrefix(response.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	first, _, ok := strings.Cut(value, "-")
+	if !ok {
+		return -1
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
 
-	// verify checksum
-	fileChecksum, err := sha1Checksum(out.Name())
+// contentRangeSize returns the size of the file given by the Content-Range of
+// a response refusing a range, or -1 when unknown
+func contentRangeSize(response *http.Response) int64 {
+	// bytes */<size>
+	value, ok := strings.CutPrefix(response.Header.Get("Content-Range"), "bytes */")
+	if !ok {
+		return -1
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
-		return "", fmt.Errorf("Error creating checksum value for Maven artifact file %q: %q", out.Name(), err)
+		return -1
 	}
+	return size
+}
 
-	if fileChecksum != checksum {
-		return "", fmt.Errorf("Checksum (%v) of downloaded Maven artifact file %q does not match expected remote checksum (%v). This file may have been tampered with in transit!", fileChecksum, checksum, out.Name())
+// restartDownload discards the content downloaded to the file and hashed
+func restartDownload(out *os.File, hashes ...hash.Hash) error {
+	if err := out.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		h.Reset()
 	}
-	return artifactDir, nil
+	return nil
 }
 
 func download(ctx context.Context, url string, client *http.Client) ([]byte, error) {
@@ -591,26 +914,91 @@ func download(ctx context.Context, url string, client *http.Client) ([]byte, err
 		return nil, fmt.Errorf("Error %q while parsing request URL %q", err, url)
 	}
 
-	// process request
-	response, err := client.Do(request)
-	// we can bail here, and return the error with some deatils when available
+	var responseBody []byte
+	err = downloadRetries.do(ctx, func() error {
+		// process request
+		response, err := client.Do(request)
+		// we can bail here, and return the error with some deatils when available
+		if err != nil {
+			return transientError(fmt.Errorf("%s download error %s", url, err), err)
+		}
+		defer response.Body.Close()
+
+		// if no error from the client, inspect statuscode and return download error from non 200s
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			return responseError(url, response)
+		}
+
+		// read response body
+		responseBody, err = io.ReadAll(response.Body)
+		if err != nil {
+			return transientError(&downloadError{err: fmt.Errorf("Error downloading file data from URL %q: %q", url, err), httpStatuscode: response.StatusCode}, err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("%s download error %s", url, err)
+		return nil, err
 	}
-	defer response.Body.Close()
 
-	// if no error from the client, inspect statuscode and return download error from non 200s
-	if response != nil && (response.StatusCode < 200 || response.StatusCode >= 300) {
-		return nil, &downloadError{err: fmt.Errorf("Error received HTTP status %v getting %q", response.StatusCode, url), httpStatuscode: response.StatusCode}
+	return responseBody, nil
+}
+
+// readTrustedKeys parses the ASCII armored OpenPGP public keys held by each
+// data value of the secret.
+func readTrustedKeys(secret corev1.Secret) (openpgp.EntityList, error) {
+	names := make([]string, 0, len(secret.Data))
+	for name := range secret.Data {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	// read response body
-	responseBody, err := io.ReadAll(response.Body)
+	keys := openpgp.EntityList{}
+	for _, name := range names {
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(secret.Data[name]))
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", name, err)
+		}
+		keys = append(keys, entities...)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys found")
+	}
+	return keys, nil
+}
+
+// isTrustedSigner returns true when no trusted keys are required, or the
+// fingerprint belongs to one of the trusted keys.
+func isTrustedSigner(trustedKeys openpgp.EntityList, fingerprint string) bool {
+	if trustedKeys == nil {
+		return true
+	}
+	for _, entity := range trustedKeys {
+		if entity.PrimaryKey != nil && fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint) == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyArtifactSignature downloads the ASCII armored detached signature and
+// verifies the file against it, returning the fingerprint of the signing key.
+func verifyArtifactSignature(ctx context.Context, client *http.Client, url string, fileName string, trustedKeys openpgp.EntityList) (string, error) {
+	signature, err := download(ctx, url, client)
 	if err != nil {
-		return nil, &downloadError{err: fmt.Errorf("Error downloading file data from URL %q: %q", url, err), httpStatuscode: response.StatusCode}
+		return "", err
 	}
 
-	return responseBody, nil
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	signer, err := openpgp.CheckArmoredDetachedSignature(trustedKeys, file, bytes.NewReader(signature), nil)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint), nil
 }
 
 func stashHttpClient(ctx context.Context, httpclient *http.Client) {
@@ -626,6 +1014,18 @@ func retrieveHttpClient(ctx context.Context) *http.Client {
 	return client
 }
 
+func stashTrustedKeys(ctx context.Context, trustedKeys openpgp.EntityList) {
+	reconcilers.StashValue(ctx, MavenArtifactTrustedKeysKey, trustedKeys)
+}
+
+func retrieveTrustedKeys(ctx context.Context) openpgp.EntityList {
+	trustedKeys, ok := reconcilers.RetrieveValue(ctx, MavenArtifactTrustedKeysKey).(openpgp.EntityList)
+	if !ok {
+		return nil
+	}
+	return trustedKeys
+}
+
 func stashAuthSecret(ctx context.Context, authSecret corev1.Secret) {
 	reconcilers.StashValue(ctx, MavenArtifactAuthSecretStashKey, authSecret)
 }
@@ -667,32 +1067,7 @@ func isArchive(artifactFilePath string) bool {
 	return filetype == "application/zip"
 }
 
-func copyCompressedFile(from, to string) error {
-	intermediateDestination := fmt.Sprintf("%s.new", to)
-	if err := os.MkdirAll(path.Dir(intermediateDestination), 0755); err != nil {
-		return err
-	}
-	fromFile, err := os.Open(from)
-	if err != nil {
-		return err
-	}
-	defer fromFile.Close()
-	toFile, err := os.Create(intermediateDestination)
-	if err != nil {
-		return nil
-	}
-	defer toFile.Close()
-	if _, err := io.Copy(toFile, fromFile); err != nil {
-		return err
-	}
-
-	if err := os.Rename(intermediateDestination, to); err != nil {
-		return err
-	}
-	return nil
-}
-
-func extractArchive(parentDir string, pathToJarFile string) (string, error) {
+func extractArchive(parentDir string, pathToJarFile string, limits ArtifactLimits) (string, error) {
 	openedFile, err := zip.OpenReader(pathToJarFile)
 	if err != nil {
 		return "", err
@@ -705,12 +1080,19 @@ func extractArchive(parentDir string, pathToJarFile string) (string, error) {
 
 	defer openedFile.Close()
 
+	extraction := newExtraction(limits)
 	for _, file := range openedFile.File {
 		filePath, err := safeArchiveEntryPath(fileDestinationFolder, file.Name)
 		if err != nil {
 			return "", err
 		}
-		if err = extractFile(file, filePath); err != nil {
+		if filePath == fileDestinationFolder {
+			continue
+		}
+		if err := extraction.addEntry(); err != nil {
+			return "", err
+		}
+		if err = extractFile(file, fileDestinationFolder, filePath, extraction); err != nil {
 			return "", err
 		}
 	}
@@ -733,11 +1115,38 @@ func safeArchiveEntryPath(destDir, name string) (string, error) {
 	return joined, nil
 }
 
-func extractFile(file *zip.File, filePath string) error {
+// extractFile writes the archive entry to filePath within destDir. Symlinks
+// are recreated when their target is within destDir, and no entry is written
+// through a symlink that leads outside of destDir. The content written is
+// counted towards the limits of the extraction.
+func extractFile(file *zip.File, destDir, filePath string, extraction *extraction) error {
+	if err := checkEntryParent(destDir, filePath, file.Name); err != nil {
+		return err
+	}
 	if file.FileInfo().IsDir() {
 		if err := os.MkdirAll(filePath, os.ModePerm); err != nil {
 			return err
 		}
+	} else if file.Mode()&fs.ModeSymlink != 0 {
+		fileInArchive, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer fileInArchive.Close()
+		target, err := io.ReadAll(io.LimitReader(fileInArchive, 4096))
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(destDir, filePath)
+		if err != nil {
+			return err
+		}
+		if err := checkSymlink(destDir, filepath.ToSlash(name), string(target)); err != nil {
+			return err
+		}
+		if err := os.Symlink(string(target), filePath); err != nil {
+			return err
+		}
 	} else {
 		fileInArchive, err := file.Open()
 		if err != nil {
@@ -750,16 +1159,15 @@ func extractFile(file *zip.File, filePath string) error {
 			return err
 		}
 		defer destinationFile.Close()
-		if _, err := io.Copy(destinationFile, fileInArchive); err != nil {
+		if err := extraction.copy(destinationFile, fileInArchive); err != nil {
 			return err
 		}
 	}
-	os.Chtimes(filePath, time.UnixMilli(0), time.UnixMilli(0))
 	return nil
 }
 
-func retrieveChecksumFromFile(filename string) (*artifactCache, error) {
-	cache, err := os.ReadFile(filename)
+func retrieveChecksumFromFile(ctx context.Context, backend ArtifactBackend, key string) (*artifactCache, error) {
+	file, err := backend.Get(ctx, key)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, nil
@@ -767,9 +1175,19 @@ func retrieveChecksumFromFile(filename string) (*artifactCache, error) {
 			return nil, err
 		}
 	}
+	defer file.Close()
+	cache, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
 
-	return &artifactCache{
-		source:   strings.Split(string(cache), "|")[0],
-		checksum: strings.Split(string(cache), "|")[1],
-	}, nil
+	fields := strings.Split(string(cache), "|")
+	ac := &artifactCache{
+		source:   fields[0],
+		checksum: fields[1],
+	}
+	if len(fields) > 2 {
+		ac.signer = fields[2]
+	}
+	return ac, nil
 }
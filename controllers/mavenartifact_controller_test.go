@@ -17,8 +17,11 @@ limitations under the License.
 package controllers_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/pem"
 	"fmt"
@@ -33,6 +36,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -66,6 +71,21 @@ func TestMavenArtifactSecretsSyncReconciler(t *testing.T) {
 			d.Name("missing-secret-ref")
 		})
 
+	signer, err := openpgp.NewEntity("Maven Signer", "", "signer@example.com", nil)
+	utilruntime.Must(err)
+	trustedKeysSecret := diecorev1.SecretBlank.
+		MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+			d.Namespace(namespace)
+			d.Name("trusted-keys")
+		}).
+		AddData("signer.asc", armoredPublicKey(signer))
+	invalidTrustedKeysSecret := diecorev1.SecretBlank.
+		MetadataDie(func(d *diemetav1.ObjectMetaDie) {
+			d.Namespace(namespace)
+			d.Name("invalid-trusted-keys")
+		}).
+		AddData("signer.asc", []byte("not a key"))
+
 	repositoryURL := "https://artifact.example.com/repository/project"
 
 	scheme := runtime.NewScheme()
@@ -154,7 +174,65 @@ func TestMavenArtifactSecretsSyncReconciler(t *testing.T) {
 			ExpectTracks: []rtesting.TrackRequest{
 				rtesting.NewTrackRequest(missingSecretRef, parent, scheme),
 			},
-		}}
+		},
+		"trusted keys secret provided and secret found": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.TrustedKeysSecretRef(&corev1.LocalObjectReference{Name: "trusted-keys"})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				trustedKeysSecret,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.TrustedKeysSecretRef(&corev1.LocalObjectReference{Name: "trusted-keys"})
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(trustedKeysSecret, parent, scheme),
+			},
+		},
+		"trusted keys secret provided but not found": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.TrustedKeysSecretRef(&corev1.LocalObjectReference{Name: "trusted-keys"})
+				}).DieReleasePtr(),
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.TrustedKeysSecretRef(&corev1.LocalObjectReference{Name: "trusted-keys"})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionFalse).Reason("SecretMissing").Messagef("Secret %q not found in namespace %q", "trusted-keys", "test-namespace"),
+						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("SecretMissing").Messagef("Secret %q not found in namespace %q", "trusted-keys", "test-namespace"),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(trustedKeysSecret, parent, scheme),
+			},
+		},
+		"trusted keys secret does not contain keys": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.TrustedKeysSecretRef(&corev1.LocalObjectReference{Name: "invalid-trusted-keys"})
+				}).DieReleasePtr(),
+			GivenObjects: []client.Object{
+				invalidTrustedKeysSecret,
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.TrustedKeysSecretRef(&corev1.LocalObjectReference{Name: "invalid-trusted-keys"})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionFalse).Reason("InvalidSecret").Messagef("Secret %q does not contain trusted OpenPGP keys: key %q: openpgp: invalid argument: no armored data found", "invalid-trusted-keys", "signer.asc"),
+						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("InvalidSecret").Messagef("Secret %q does not contain trusted OpenPGP keys: key %q: openpgp: invalid argument: no armored data found", "invalid-trusted-keys", "signer.asc"),
+					)
+				}).DieReleasePtr(),
+			ExpectTracks: []rtesting.TrackRequest{
+				rtesting.NewTrackRequest(invalidTrustedKeysSecret, parent, scheme),
+			},
+		},
+	}
 
 	rts.Run(t, scheme, func(t *testing.T, rtc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.MavenArtifact], c reconcilers.Config) reconcilers.SubReconciler[*sourcev1alpha1.MavenArtifact] {
 		return controllers.MavenArtifactSecretsSyncReconciler([]controllers.Cert{})
@@ -394,6 +472,7 @@ func TestMavenArtifactVersionSyncReconciler(t *testing.T) {
 		artifactType                      = "jar"
 		missingArtifactId                 = "missing-artifact"
 		pinnedVersion                     = "2.6.0"
+		rangeVersion                      = "[2.6,2.6.5)"
 		latestVersion                     = "2.6.7"
 		releaseVersion                    = "2.6.7"
 		snapshotVersion                   = "2.7.0-SNAPSHOT"
@@ -701,6 +780,34 @@ func TestMavenArtifactVersionSyncReconciler(t *testing.T) {
 			})
 		})
 
+	parentWithRangeVersion := parent.
+		SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+			d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+				d.GroupId(groupId)
+				d.ArtifactId(artifactId)
+				d.Type(artifactType)
+				d.Version(rangeVersion)
+			})
+			d.RepositoryDie(func(d *diesourcev1alpha1.RepositoryDie) {
+				d.URL(tlsServer.URL + "/ca-releases")
+				d.SecretRef(corev1.LocalObjectReference{Name: "cert-secret-ref"})
+			})
+		})
+
+	parentWithUnmatchedRangeVersion := parent.
+		SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+			d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+				d.GroupId(groupId)
+				d.ArtifactId(artifactId)
+				d.Type(artifactType)
+				d.Version("[3.0,)")
+			})
+			d.RepositoryDie(func(d *diesourcev1alpha1.RepositoryDie) {
+				d.URL(tlsServer.URL + "/ca-releases")
+				d.SecretRef(corev1.LocalObjectReference{Name: "cert-secret-ref"})
+			})
+		})
+
 	parentWithSnapshotVersion := parent.
 		SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
 			d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
@@ -921,6 +1028,50 @@ func TestMavenArtifactVersionSyncReconciler(t *testing.T) {
 				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
 			},
 		},
+		"range version": {
+			Resource: parentWithRangeVersion.DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
+				controllers.MavenArtifactHttpClientKey:      tlsServer.Client(),
+			},
+			ExpectResource: parentWithRangeVersion.
+				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
+					d.ObservedGeneration(1)
+					d.ConditionsDie(
+						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.
+							Status(metav1.ConditionTrue).
+							Reason("Resolved").
+							Messagef(`Resolved version %q for artifact "%s/ca-releases/org/my-group/%s/%s/%s-%s.%s"`, pinnedVersion, tlsServer.URL, artifactId, pinnedVersion, artifactId, pinnedVersion, artifactType),
+					)
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.MavenArtifactVersionStashKey: controllers.ArtifactDetails{
+					ArtifactVersion:     pinnedVersion,
+					ResolvedFileName:    fmt.Sprintf("%s-%s.%s", artifactId, pinnedVersion, artifactType),
+					ArtifactDownloadURL: fmt.Sprintf("%s/ca-releases/org/my-group/%s/%s/%s-%s.%s", tlsServer.URL, artifactId, pinnedVersion, artifactId, pinnedVersion, artifactType),
+				},
+				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
+			},
+		},
+		"range version without a match": {
+			Resource: parentWithUnmatchedRangeVersion.DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
+				controllers.MavenArtifactHttpClientKey:      tlsServer.Client(),
+			},
+			ExpectResource: parentWithUnmatchedRangeVersion.
+				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionFalse).Reason("VersionError").
+							Message(`artifact metadata does not have a version matching range "[3.0,)"`),
+						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("VersionError").
+							Message(`artifact metadata does not have a version matching range "[3.0,)"`),
+					)
+				}).DieReleasePtr(),
+			ExpectStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
+			},
+		},
 		"snapshot version": {
 			Resource: parentWithSnapshotVersion.DieReleasePtr(),
 			GivenStashedValues: map[reconcilers.StashKey]interface{}{
@@ -1212,18 +1363,28 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 	badArtifactId := "goodbyeworld"
 	failDownloadArtifact := "fail-download"
 	checksumMismatchArtifactId := "checksum-mismatch"
+	sha256ArtifactId := "helloworld-sha256"
+	md5ArtifactId := "helloworld-md5"
+	signedArtifactId := "helloworld-signed"
+	untrustedArtifactId := "helloworld-untrusted"
 	artifactVersion := "1.1"
 	classifier := "sources"
 	failDownloadZip := fmt.Sprintf("%s-%s.zip", failDownloadArtifact, artifactVersion)
 	checksumMismatchFilename := fmt.Sprintf("%s-%s.jar", checksumMismatchArtifactId, artifactVersion)
+	sha256Filename := fmt.Sprintf("%s-%s.jar", sha256ArtifactId, artifactVersion)
+	md5Filename := fmt.Sprintf("%s-%s.jar", md5ArtifactId, artifactVersion)
+	signedFilename := fmt.Sprintf("%s-%s.jar", signedArtifactId, artifactVersion)
+	untrustedFilename := fmt.Sprintf("%s-%s.jar", untrustedArtifactId, artifactVersion)
 	fileName := fmt.Sprintf("%s-%s.jar", artifactId, artifactVersion)
 	badFilename := fmt.Sprintf("%s-%s.jar", badArtifactId, artifactVersion)
 	fileNameWithZip := fmt.Sprintf("%s-%s.zip", artifactId, artifactVersion)
 	fileNameAndClassifier := fmt.Sprintf("%s-%s-%s.jar", artifactId, artifactVersion, classifier)
 	artifactJarToTgzFilename := "8fdea0bf0e6441c8717853230a270e4ed51cd77a"
 	artifactZipToTgzFilename := "a3794eec54f0ab3a2d62c31cf5a3b947c1ecc2b1"
-	checksum := "6271d8d39c1936f8e0b25c8b2d43fe671f7de1f8"
-	zipChecksum := "d1f7d7c82fdb54a360e7f3c29024d3af2f10600c"
+	checksum := "a86d1721c309672a05bc9d818984d8f8821894bd"
+	digest := "sha256:4d6fd65366fa3214062f46d65551e1f5776de706a8219e7e827bf8ecee202b59"
+	zipChecksum := "2497e228a87bb9f3626cbd57ef7b508781367d56"
+	zipDigest := "sha256:fec5ca2b8042e36bb8b1149bea1913ec7ed266f410293443fbdf57c93e6c471f"
 
 	now := func() metav1.Time {
 		return metav1.Time{
@@ -1237,6 +1398,11 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 		}
 	}
 
+	signer, err := openpgp.NewEntity("Maven Signer", "", "signer@example.com", nil)
+	utilruntime.Must(err)
+	untrustedSigner, err := openpgp.NewEntity("Untrusted Signer", "", "untrusted@example.com", nil)
+	utilruntime.Must(err)
+
 	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		func(w http.ResponseWriter, r *http.Request) {
 			user, pass, ok := r.BasicAuth()
@@ -1321,6 +1487,55 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 				// checksum-mismatch error path (a plain error, not a *downloadError)
 				w.WriteHeader(http.StatusOK)
 				w.Write([]byte("deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"))
+			} else if r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v", groupId, sha256ArtifactId, artifactVersion, sha256Filename) ||
+				r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v", groupId, md5ArtifactId, artifactVersion, md5Filename) {
+				fileBytes, err := os.ReadFile("fixtures/maven-artifact/helloworld-1.1.jar")
+				if err != nil {
+					panic(err)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write(fileBytes)
+			} else if r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v.sha256", groupId, sha256ArtifactId, artifactVersion, sha256Filename) {
+				fileBytes, err := os.ReadFile("fixtures/maven-artifact/helloworld-1.1.jar")
+				if err != nil {
+					panic(err)
+				}
+				// checksum files may name the file after the checksum
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(fmt.Sprintf("%X  %s\n", sha256.Sum256(fileBytes), sha256Filename)))
+			} else if r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v.sha1", groupId, sha256ArtifactId, artifactVersion, sha256Filename) {
+				// a weaker checksum that must not be used when a stronger one is available
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"))
+			} else if r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v", groupId, signedArtifactId, artifactVersion, signedFilename) ||
+				r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v", groupId, untrustedArtifactId, artifactVersion, untrustedFilename) {
+				fileBytes, err := os.ReadFile("fixtures/maven-artifact/helloworld-1.1.jar")
+				if err != nil {
+					panic(err)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write(fileBytes)
+			} else if r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v.sha1", groupId, signedArtifactId, artifactVersion, signedFilename) ||
+				r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v.sha1", groupId, untrustedArtifactId, artifactVersion, untrustedFilename) {
+				checksum, err := sha1Checksum("fixtures/maven-artifact/helloworld-1.1.jar")
+				if err != nil {
+					panic(err)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(checksum))
+			} else if r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v.asc", groupId, signedArtifactId, artifactVersion, signedFilename) {
+				w.WriteHeader(http.StatusOK)
+				w.Write(signFile(signer, "fixtures/maven-artifact/helloworld-1.1.jar"))
+			} else if r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v.asc", groupId, untrustedArtifactId, artifactVersion, untrustedFilename) {
+				w.WriteHeader(http.StatusOK)
+				w.Write(signFile(untrustedSigner, "fixtures/maven-artifact/helloworld-1.1.jar"))
+			} else if r.URL.Path == fmt.Sprintf("/ca-releases/%v/%v/%v/%v.md5", groupId, md5ArtifactId, artifactVersion, md5Filename) {
+				fileBytes, err := os.ReadFile("fixtures/maven-artifact/helloworld-1.1.jar")
+				if err != nil {
+					panic(err)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(fmt.Sprintf("%x", md5.Sum(fileBytes))))
 			} else {
 				w.WriteHeader(http.StatusNotFound)
 			}
@@ -1491,10 +1706,11 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 						d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactZipToTgzFilename + ".tar.gz")
 						d.LastUpdateTime(now())
 						d.Checksum(zipChecksum)
+						d.Digest(zipDigest)
 					})
 					d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactZipToTgzFilename + ".tar.gz")
 					d.ConditionsDie(
-						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available").Message("Maven artifact verified with sha1 checksum"),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
 					)
@@ -1564,15 +1780,226 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 						d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 						d.LastUpdateTime(now())
 						d.Checksum(checksum)
+						d.Digest(digest)
 					})
 					d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 					d.ConditionsDie(
-						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available").Message("Maven artifact verified with sha1 checksum"),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
 					)
 				}).DieReleasePtr(),
 		},
+		"download artifact verified with the strongest checksum": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+						d.Type("jar")
+						d.ArtifactId(sha256ArtifactId)
+						d.GroupId(groupId)
+					})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.MavenArtifactVersionStashKey: controllers.ArtifactDetails{
+					ArtifactVersion:     artifactVersion,
+					ResolvedFileName:    sha256Filename,
+					ArtifactDownloadURL: fmt.Sprintf("%s/ca-releases/my-group/%s/%s/%s", tlsServer.URL, sha256ArtifactId, artifactVersion, sha256Filename),
+				},
+				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
+				controllers.MavenArtifactHttpClientKey:      tlsServer.Client(),
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.RepositoryDie(func(d *diesourcev1alpha1.RepositoryDie) {
+						d.URL(tlsServer.URL + "/ca-releases")
+						d.SecretRef(corev1.LocalObjectReference{Name: "cert-secret-ref"})
+					})
+					d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+						d.Type("jar")
+						d.ArtifactId(sha256ArtifactId)
+						d.GroupId(groupId)
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
+					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
+						d.Revision(sha256Filename)
+						d.Path("mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
+						d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
+						d.LastUpdateTime(now())
+						d.Checksum(checksum)
+						d.Digest(digest)
+					})
+					d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
+					d.ConditionsDie(
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available").Message("Maven artifact verified with sha256 checksum"),
+						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+					)
+				}).DieReleasePtr(),
+			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.MavenArtifact]) error {
+				artifact := path.Join(artifactRootDir, "mavenartifact")
+				os.RemoveAll(artifact)
+				return nil
+			},
+		},
+		"checksum weaker than the floor is not used": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+						d.Type("jar")
+						d.ArtifactId(md5ArtifactId)
+						d.GroupId(groupId)
+					})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.MavenArtifactVersionStashKey: controllers.ArtifactDetails{
+					ArtifactVersion:     artifactVersion,
+					ResolvedFileName:    md5Filename,
+					ArtifactDownloadURL: fmt.Sprintf("%s/ca-releases/my-group/%s/%s/%s", tlsServer.URL, md5ArtifactId, artifactVersion, md5Filename),
+				},
+				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
+				controllers.MavenArtifactHttpClientKey:      tlsServer.Client(),
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.RepositoryDie(func(d *diesourcev1alpha1.RepositoryDie) {
+						d.URL(tlsServer.URL + "/ca-releases")
+						d.SecretRef(corev1.LocalObjectReference{Name: "cert-secret-ref"})
+					})
+					d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+						d.Type("jar")
+						d.ArtifactId(md5ArtifactId)
+						d.GroupId(groupId)
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
+							Messagef(`Maven artifact checksum file not found (HTTP 404) at URL "%s/ca-releases/my-group/%s/1.1/%s".`, tlsServer.URL, md5ArtifactId, md5Filename+".{sha512,sha256,sha1}"),
+						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
+							Messagef(`Maven artifact checksum file not found (HTTP 404) at URL "%s/ca-releases/my-group/%s/1.1/%s".`, tlsServer.URL, md5ArtifactId, md5Filename+".{sha512,sha256,sha1}"),
+					)
+				}).DieReleasePtr(),
+		},
+		"download artifact verified with a trusted signature": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+						d.Type("jar")
+						d.ArtifactId(signedArtifactId)
+						d.GroupId(groupId)
+					})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.MavenArtifactVersionStashKey: controllers.ArtifactDetails{
+					ArtifactVersion:     artifactVersion,
+					ResolvedFileName:    signedFilename,
+					ArtifactDownloadURL: fmt.Sprintf("%s/ca-releases/my-group/%s/%s/%s", tlsServer.URL, signedArtifactId, artifactVersion, signedFilename),
+				},
+				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
+				controllers.MavenArtifactHttpClientKey:      tlsServer.Client(),
+				controllers.MavenArtifactTrustedKeysKey:     openpgp.EntityList{signer},
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.RepositoryDie(func(d *diesourcev1alpha1.RepositoryDie) {
+						d.URL(tlsServer.URL + "/ca-releases")
+						d.SecretRef(corev1.LocalObjectReference{Name: "cert-secret-ref"})
+					})
+					d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+						d.Type("jar")
+						d.ArtifactId(signedArtifactId)
+						d.GroupId(groupId)
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
+					d.ArtifactDie(func(d *diesourcev1alpha1.ArtifactDie) {
+						d.Revision(signedFilename)
+						d.Path("mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
+						d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
+						d.LastUpdateTime(now())
+						d.Checksum(checksum)
+						d.Digest(digest)
+					})
+					d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
+					d.ConditionsDie(
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available").
+							Messagef("Maven artifact verified with sha1 checksum and OpenPGP signature by key %X", signer.PrimaryKey.Fingerprint),
+						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
+					)
+				}).DieReleasePtr(),
+			CleanUp: func(t *testing.T, ctx context.Context, tc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.MavenArtifact]) error {
+				artifact := path.Join(artifactRootDir, "mavenartifact")
+				os.RemoveAll(artifact)
+				return nil
+			},
+		},
+		"artifact signed by an untrusted key": {
+			Resource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+						d.Type("jar")
+						d.ArtifactId(untrustedArtifactId)
+						d.GroupId(groupId)
+					})
+				}).DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.MavenArtifactVersionStashKey: controllers.ArtifactDetails{
+					ArtifactVersion:     artifactVersion,
+					ResolvedFileName:    untrustedFilename,
+					ArtifactDownloadURL: fmt.Sprintf("%s/ca-releases/my-group/%s/%s/%s", tlsServer.URL, untrustedArtifactId, artifactVersion, untrustedFilename),
+				},
+				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
+				controllers.MavenArtifactHttpClientKey:      tlsServer.Client(),
+				controllers.MavenArtifactTrustedKeysKey:     openpgp.EntityList{signer},
+			},
+			ExpectResource: parent.
+				SpecDie(func(d *diesourcev1alpha1.MavenArtifactSpecDie) {
+					d.RepositoryDie(func(d *diesourcev1alpha1.RepositoryDie) {
+						d.URL(tlsServer.URL + "/ca-releases")
+						d.SecretRef(corev1.LocalObjectReference{Name: "cert-secret-ref"})
+					})
+					d.MavenArtifactDie(func(d *diesourcev1alpha1.MavenArtifactTypeDie) {
+						d.Type("jar")
+						d.ArtifactId(untrustedArtifactId)
+						d.GroupId(groupId)
+					})
+				}).
+				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("SignatureError").
+							Messagef("Unable to verify signature of Maven artifact file %q: openpgp: signature made by unknown entity", untrustedFilename),
+						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("SignatureError").
+							Messagef("Unable to verify signature of Maven artifact file %q: openpgp: signature made by unknown entity", untrustedFilename),
+					)
+				}).DieReleasePtr(),
+		},
+		"artifact signature not found": {
+			Resource: parentWithoutClassifier.DieReleasePtr(),
+			GivenStashedValues: map[reconcilers.StashKey]interface{}{
+				controllers.MavenArtifactVersionStashKey: controllers.ArtifactDetails{
+					ArtifactVersion:     artifactVersion,
+					ResolvedFileName:    fileName,
+					ArtifactDownloadURL: fmt.Sprintf("%s/ca-releases/my-group/%s/%s/%s", tlsServer.URL, artifactId, artifactVersion, fileName),
+				},
+				controllers.MavenArtifactAuthSecretStashKey: validAuthorisedSecret,
+				controllers.MavenArtifactHttpClientKey:      tlsServer.Client(),
+				controllers.MavenArtifactTrustedKeysKey:     openpgp.EntityList{signer},
+			},
+			ExpectResource: parentWithoutClassifier.
+				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
+					d.ConditionsDie(
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("SignatureError").
+							Messagef("Maven artifact signature file not found (HTTP 404) at URL \"%s/ca-releases/my-group/%s/1.1/%s.asc\".", tlsServer.URL, artifactId, fileName),
+						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
+						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("SignatureError").
+							Messagef("Maven artifact signature file not found (HTTP 404) at URL \"%s/ca-releases/my-group/%s/1.1/%s.asc\".", tlsServer.URL, artifactId, fileName),
+					)
+				}).DieReleasePtr(),
+		},
 		"download artifact with classifier": {
 			Resource: parentWithClassifier.DieReleasePtr(),
 			GivenStashedValues: map[reconcilers.StashKey]interface{}{
@@ -1604,10 +2031,11 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 						d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 						d.LastUpdateTime(now())
 						d.Checksum(checksum)
+						d.Digest(digest)
 					})
 					d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 					d.ConditionsDie(
-						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available").Message("Maven artifact verified with sha1 checksum"),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
 					)
@@ -1623,7 +2051,7 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 					return ctx, err
 				}
 
-				return ctx, setCache(fmt.Sprintf("%s/helloworld-1.1.jar.sha1", dir), fmt.Sprintf("%s/my-group/helloworld/1.1/helloworld-1.1.jar|%s", tlsServer.URL+"/ca-releases", "8fdea0bf0e6441c8717853230a270e4ed51cd77a"))
+				return ctx, setCache(fmt.Sprintf("%s/helloworld-1.1.jar.sha1", dir), fmt.Sprintf("%s/my-group/helloworld/1.1/helloworld-1.1.jar|%s", tlsServer.URL+"/ca-releases", "sha1:8fdea0bf0e6441c8717853230a270e4ed51cd77a"))
 			},
 			Resource: parent.
 				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
@@ -1633,6 +2061,7 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 						d.URL("http://localhost.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 						d.LastUpdateTime(olderTime())
 						d.Checksum(checksum)
+						d.Digest(digest)
 					})
 					d.URL("http://localhost.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 				}).DieReleasePtr(),
@@ -1664,6 +2093,7 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 						d.URL("http://localhost.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 						d.LastUpdateTime(olderTime())
 						d.Checksum(checksum)
+						d.Digest(digest)
 					})
 					d.URL("http://localhost.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 					d.ConditionsDie(
@@ -1709,10 +2139,10 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
 					d.ConditionsDie(
 						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
-							Messagef(`Maven artifact checksum file not found (HTTP 404) at URL "%s/ca-releases/my-group/%s/1.1/%s-1.1.jar.sha1".`, tlsServer.URL, badArtifactId, badArtifactId),
+							Messagef(`Maven artifact checksum file not found (HTTP 404) at URL "%s/ca-releases/my-group/%s/1.1/%s-1.1.jar.{sha512,sha256,sha1}".`, tlsServer.URL, badArtifactId, badArtifactId),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
-							Messagef(`Maven artifact checksum file not found (HTTP 404) at URL "%s/ca-releases/my-group/%s/1.1/%s-1.1.jar.sha1".`, tlsServer.URL, badArtifactId, badArtifactId),
+							Messagef(`Maven artifact checksum file not found (HTTP 404) at URL "%s/ca-releases/my-group/%s/1.1/%s-1.1.jar.{sha512,sha256,sha1}".`, tlsServer.URL, badArtifactId, badArtifactId),
 					)
 				}).DieReleasePtr(),
 		},
@@ -1750,12 +2180,12 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
 					d.ConditionsDie(
 						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("DownloadError").
-							Messagef(`Error downloading Maven artifact file %q: Checksum (%v) of downloaded Maven artifact file %q does not match expected remote checksum (%v). This file may have been tampered with in transit!`,
-								checksumMismatchArtifactId, artifactJarToTgzFilename, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", "<tmp>/artifact/"+checksumMismatchFilename),
+							Messagef(`Error downloading Maven artifact file %q: Checksum (%v) of downloaded Maven artifact file %q does not match expected remote sha1 checksum (%v). This file may have been tampered with in transit!`,
+								checksumMismatchArtifactId, artifactJarToTgzFilename, "<tmp>/artifact/"+checksumMismatchFilename, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("DownloadError").
-							Messagef(`Error downloading Maven artifact file %q: Checksum (%v) of downloaded Maven artifact file %q does not match expected remote checksum (%v). This file may have been tampered with in transit!`,
-								checksumMismatchArtifactId, artifactJarToTgzFilename, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", "<tmp>/artifact/"+checksumMismatchFilename),
+							Messagef(`Error downloading Maven artifact file %q: Checksum (%v) of downloaded Maven artifact file %q does not match expected remote sha1 checksum (%v). This file may have been tampered with in transit!`,
+								checksumMismatchArtifactId, artifactJarToTgzFilename, "<tmp>/artifact/"+checksumMismatchFilename, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"),
 					)
 				}).DieReleasePtr(),
 		},
@@ -1784,10 +2214,10 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
 					d.ConditionsDie(
 						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
-							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha1". Check the credentials provided in the Secret.`, tlsServer.URL),
+							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha512". Check the credentials provided in the Secret.`, tlsServer.URL),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
-							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha1". Check the credentials provided in the Secret.`, tlsServer.URL),
+							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha512". Check the credentials provided in the Secret.`, tlsServer.URL),
 					)
 				}).DieReleasePtr(),
 		},
@@ -1821,10 +2251,11 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 						d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 						d.LastUpdateTime(now())
 						d.Checksum(checksum)
+						d.Digest(digest)
 					})
 					d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 					d.ConditionsDie(
-						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available").Message("Maven artifact verified with sha1 checksum"),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
 					)
@@ -1855,16 +2286,16 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
 					d.ConditionsDie(
 						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
-							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha1". Check the credentials provided in the Secret.`, tlsServer.URL),
+							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha512". Check the credentials provided in the Secret.`, tlsServer.URL),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
-							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha1". Check the credentials provided in the Secret.`, tlsServer.URL),
+							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha512". Check the credentials provided in the Secret.`, tlsServer.URL),
 					)
 				}).DieReleasePtr(),
 		}}
 
 	successRTS.Run(t, scheme, func(t *testing.T, rtc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.MavenArtifact], c reconcilers.Config) reconcilers.SubReconciler[*sourcev1alpha1.MavenArtifact] {
-		return controllers.MavenArtifactDownloadSyncReconciler(artifactRootDir, "artifact.example", now)
+		return controllers.MavenArtifactDownloadSyncReconciler(&controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: artifactRootDir, Host: "artifact.example"}}, controllers.SHA256, controllers.SHA1, now)
 	})
 
 	failRTS := rtesting.SubReconcilerTests[*sourcev1alpha1.MavenArtifact]{
@@ -1894,10 +2325,10 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
 					d.ConditionsDie(
 						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
-							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha1". Check the credentials provided in the Secret.`, tlsServer.URL),
+							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha512". Check the credentials provided in the Secret.`, tlsServer.URL),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("RemoteError").
-							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha1". Check the credentials provided in the Secret.`, tlsServer.URL),
+							Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%v/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha512". Check the credentials provided in the Secret.`, tlsServer.URL),
 					)
 				}).DieReleasePtr(),
 		},
@@ -1926,14 +2357,14 @@ func TestMavenArtifactDownloadSyncReconciler(t *testing.T) {
 				}).
 				StatusDie(func(d *diesourcev1alpha1.MavenArtifactStatusDie) {
 					d.ConditionsDie(
-						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("RemoteError").Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%s/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha1". Check the credentials provided in the Secret.`, tlsServer.URL),
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionFalse).Reason("RemoteError").Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%s/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha512". Check the credentials provided in the Secret.`, tlsServer.URL),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
-						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("RemoteError").Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%s/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha1". Check the credentials provided in the Secret.`, tlsServer.URL),
+						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionFalse).Reason("RemoteError").Messagef(`Unauthorized credentials (HTTP 401) error downloading Maven artifact checksum from URL "%s/ca-releases/my-group/helloworld/1.1/helloworld-1.1.jar.sha512". Check the credentials provided in the Secret.`, tlsServer.URL),
 					)
 				}).DieReleasePtr(),
 		}}
 	failRTS.Run(t, scheme, func(t *testing.T, rtc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.MavenArtifact], c reconcilers.Config) reconcilers.SubReconciler[*sourcev1alpha1.MavenArtifact] {
-		return controllers.MavenArtifactDownloadSyncReconciler(artifactRootDir, "artifact.example", now)
+		return controllers.MavenArtifactDownloadSyncReconciler(&controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: artifactRootDir, Host: "artifact.example"}}, controllers.SHA256, controllers.SHA1, now)
 	})
 }
 
@@ -1947,7 +2378,8 @@ func TestMavenArtifactReconciler(t *testing.T) {
 	latestVersion := "1.1"
 	fileName := fmt.Sprintf("%s-%s.jar", artifactId, latestVersion)
 	fileNameWithoutType := "8fdea0bf0e6441c8717853230a270e4ed51cd77a"
-	checksum := "6271d8d39c1936f8e0b25c8b2d43fe671f7de1f8"
+	checksum := "a86d1721c309672a05bc9d818984d8f8821894bd"
+	digest := "sha256:4d6fd65366fa3214062f46d65551e1f5776de706a8219e7e827bf8ecee202b59"
 
 	// TNZGOV-13098: artifact IDs used to prove the repository host cannot use an
 	// HTTP redirect to send the client's follow-up request to a different host.
@@ -2080,11 +2512,12 @@ func TestMavenArtifactReconciler(t *testing.T) {
 							d.URL(fmt.Sprintf("http://artifact.example/mavenartifact/%s/%s/%s.tar.gz", namespace, name, fileNameWithoutType))
 							d.LastUpdateTime(now())
 							d.Checksum(checksum)
+							d.Digest(digest)
 						})
 						d.URL(fmt.Sprintf("http://artifact.example/mavenartifact/%s/%s/%s.tar.gz", namespace, name, fileNameWithoutType))
 						d.ObservedGeneration(1)
 						d.ConditionsDie(
-							diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+							diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available").Message("Maven artifact verified with sha1 checksum"),
 							diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved").Messagef(`Resolved version %q for artifact "%s/%s/%s/%s/%s-%s.jar"`, latestVersion, tlsServer.URL+"/ca-releases", groupId, artifactId, latestVersion, artifactId, latestVersion),
 							diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
 						)
@@ -2203,11 +2636,12 @@ func TestMavenArtifactReconciler(t *testing.T) {
 							d.URL(fmt.Sprintf("http://artifact.example/mavenartifact/%s/%s/%s.tar.gz", namespace, name, fileNameWithoutType))
 							d.LastUpdateTime(now())
 							d.Checksum(checksum)
+							d.Digest(digest)
 						})
 						d.URL(fmt.Sprintf("http://artifact.example/mavenartifact/%s/%s/%s.tar.gz", namespace, name, fileNameWithoutType))
 						d.ObservedGeneration(1)
 						d.ConditionsDie(
-							diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+							diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available").Message("Maven artifact verified with sha1 checksum"),
 							diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved").
 								Messagef(`Resolved version %q for artifact "%s/%s/%s/%s/%s"`, latestVersion, tlsServer.URL+"/ca-releases", groupId, redirectSameHostArtifactId, latestVersion, redirectSameHostFileName),
 							diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
@@ -2242,7 +2676,7 @@ func TestMavenArtifactReconciler(t *testing.T) {
 					return ctx, err
 				}
 
-				return ctx, setCache(fmt.Sprintf("%s/helloworld-1.1.jar.sha1", dir), fmt.Sprintf("%s/my-group/helloworld/1.1/helloworld-1.1.jar|%s", tlsServer.URL+"/ca-releases", "8fdea0bf0e6441c8717853230a270e4ed51cd77a"))
+				return ctx, setCache(fmt.Sprintf("%s/helloworld-1.1.jar.sha1", dir), fmt.Sprintf("%s/my-group/helloworld/1.1/helloworld-1.1.jar|%s", tlsServer.URL+"/ca-releases", "sha1:8fdea0bf0e6441c8717853230a270e4ed51cd77a"))
 			},
 			GivenObjects: []client.Object{
 				parent.
@@ -2272,6 +2706,7 @@ func TestMavenArtifactReconciler(t *testing.T) {
 							d.URL(fmt.Sprintf("http://artifact.example/mavenartifact/%s/%s/%s.tar.gz", namespace, name, fileNameWithoutType))
 							d.LastUpdateTime(now())
 							d.Checksum(checksum)
+							d.Digest(digest)
 						})
 						d.URL(fmt.Sprintf("http://artifact.example/mavenartifact/%s/%s/%s.tar.gz", namespace, name, fileNameWithoutType))
 						d.ObservedGeneration(1)
@@ -2352,7 +2787,7 @@ func TestMavenArtifactReconciler(t *testing.T) {
 		err := os.RemoveAll(artifactRootDir)
 		utilruntime.Must(err)
 
-		return controllers.MavenArtifactReconciler(c, artifactRootDir, "artifact.example", now, []controllers.Cert{})
+		return controllers.MavenArtifactReconciler(c, &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: artifactRootDir, Host: "artifact.example"}}, controllers.SHA256, controllers.SHA1, now, []controllers.Cert{})
 	})
 }
 
@@ -2369,6 +2804,37 @@ func sha1Checksum(name string) (string, error) {
 	return fmt.Sprintf("%x", checksum.Sum(nil)), nil
 }
 
+// signFile returns an ASCII armored detached signature of the file
+func signFile(signer *openpgp.Entity, name string) []byte {
+	file, err := os.Open(name)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	signature := &bytes.Buffer{}
+	if err := openpgp.ArmoredDetachSign(signature, signer, file, nil); err != nil {
+		panic(err)
+	}
+	return signature.Bytes()
+}
+
+// armoredPublicKey returns the ASCII armored public key of the entity
+func armoredPublicKey(entity *openpgp.Entity) []byte {
+	key := &bytes.Buffer{}
+	w, err := armor.Encode(key, openpgp.PublicKeyType, nil)
+	if err != nil {
+		panic(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		panic(err)
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return key.Bytes()
+}
+
 func setCache(file string, content string) error {
 	return os.WriteFile(file, []byte(content), os.ModePerm)
 }
@@ -2389,7 +2855,8 @@ func TestMavenArtifactJarDownloadAndValidation(t *testing.T) {
 	artifactVersion := "1.1"
 	fileName := fmt.Sprintf("%s-%s.jar", artifactId, artifactVersion)
 	artifactJarToTgzFilename := "8fdea0bf0e6441c8717853230a270e4ed51cd77a"
-	checksum := "6271d8d39c1936f8e0b25c8b2d43fe671f7de1f8"
+	checksum := "a86d1721c309672a05bc9d818984d8f8821894bd"
+	digest := "sha256:4d6fd65366fa3214062f46d65551e1f5776de706a8219e7e827bf8ecee202b59"
 
 	now := func() metav1.Time {
 		return metav1.Time{
@@ -2494,10 +2961,11 @@ func TestMavenArtifactJarDownloadAndValidation(t *testing.T) {
 						d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 						d.LastUpdateTime(now())
 						d.Checksum(checksum)
+						d.Digest(digest)
 					})
 					d.URL("http://artifact.example/mavenartifact/test-namespace/my-maven-artifact/" + artifactJarToTgzFilename + ".tar.gz")
 					d.ConditionsDie(
-						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available"),
+						diesourcev1alpha1.MavenArtifactConditionAvailableBlank.Status(metav1.ConditionTrue).Reason("Available").Message("Maven artifact verified with sha1 checksum"),
 						diesourcev1alpha1.MavenArtifactConditionVersionResolvedBlank.Status(metav1.ConditionTrue).Reason("Resolved"),
 						diesourcev1alpha1.MavenArtifactConditionReadyBlank.Status(metav1.ConditionTrue).Reason("Ready"),
 					)
@@ -2657,6 +3125,6 @@ func TestMavenArtifactJarDownloadAndValidation(t *testing.T) {
 	}
 
 	rts.Run(t, scheme, func(t *testing.T, rtc *rtesting.SubReconcilerTestCase[*sourcev1alpha1.MavenArtifact], c reconcilers.Config) reconcilers.SubReconciler[*sourcev1alpha1.MavenArtifact] {
-		return controllers.MavenArtifactDownloadSyncReconciler(artifactRootDir, "artifact.example", now)
+		return controllers.MavenArtifactDownloadSyncReconciler(&controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: artifactRootDir, Host: "artifact.example"}}, controllers.SHA256, controllers.SHA1, now)
 	})
 }
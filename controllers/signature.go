@@ -0,0 +1,478 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	corev1 "k8s.io/api/core/v1"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+)
+
+const (
+	CosignSignatureAnnotation    = "dev.cosignproject.cosign/signature"
+	CosignCertificateAnnotation  = "dev.sigstore.cosign/certificate"
+	CosignChainAnnotation        = "dev.sigstore.cosign/chain"
+	CosignBundleAnnotation       = "dev.sigstore.cosign/bundle"
+	CosignSimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	CosignSignatureArtifactType  = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	CosignSignatureType          = "cosign container image signature"
+)
+
+var (
+	// oidcIssuerExtension holds the OIDC issuer in Fulcio certificates as a
+	// DER encoded UTF8String
+	oidcIssuerExtension = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+	// oidcIssuerLegacyExtension holds the OIDC issuer in Fulcio certificates as
+	// raw bytes
+	oidcIssuerLegacyExtension = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+)
+
+// errNoSignatures is returned when an image has no cosign signatures attached
+var errNoSignatures = errors.New("no cosign signatures found")
+
+// cosignPayload is the simple signing payload signed by cosign
+type cosignPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// cosignSignature is a signature layer attached to an image by cosign
+type cosignSignature struct {
+	payload     []byte
+	signature   []byte
+	certificate string
+	chain       string
+	// bundle is the Rekor transparency log entry of a keyless signature
+	bundle string
+}
+
+// cosignVerifier verifies cosign signatures against the public keys and keyless
+// identities trusted by an ImageRepository
+type cosignVerifier struct {
+	// publicKeys by the Secret data key they were read from
+	publicKeys map[string]crypto.PublicKey
+	// roots that keyless signing certificates must chain to
+	roots *x509.CertPool
+	// rekorKeys by the hex encoded SHA-256 of their DER encoding, the log id
+	// of a Rekor transparency log
+	rekorKeys map[string]*ecdsa.PublicKey
+	// identities allowed to create keyless signatures
+	identities []sourcev1alpha1.ImageKeylessIdentity
+}
+
+// newCosignVerifier reads the trusted public keys (data keys ending in ".pub"),
+// Rekor public keys (data keys named "rekor.pub" or ending in ".rekor.pub")
+// and root certificates (data keys ending in ".crt") from the secret.
+func newCosignVerifier(secret corev1.Secret, identities []sourcev1alpha1.ImageKeylessIdentity) (*cosignVerifier, error) {
+	v := &cosignVerifier{
+		publicKeys: map[string]crypto.PublicKey{},
+		roots:      x509.NewCertPool(),
+		rekorKeys:  map[string]*ecdsa.PublicKey{},
+		identities: identities,
+	}
+	hasRoots := false
+	for key, value := range secret.Data {
+		switch {
+		case key == "rekor.pub" || strings.HasSuffix(key, ".rekor.pub"):
+			block, _ := pem.Decode(value)
+			if block == nil {
+				return nil, fmt.Errorf("Rekor public key %q is not PEM encoded", key)
+			}
+			publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("Rekor public key %q is invalid: %w", key, err)
+			}
+			rekorKey, ok := publicKey.(*ecdsa.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("Rekor public key %q is not an ECDSA key", key)
+			}
+			logID := sha256.Sum256(block.Bytes)
+			v.rekorKeys[hex.EncodeToString(logID[:])] = rekorKey
+		case strings.HasSuffix(key, ".pub"):
+			block, _ := pem.Decode(value)
+			if block == nil {
+				return nil, fmt.Errorf("public key %q is not PEM encoded", key)
+			}
+			publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("public key %q is invalid: %w", key, err)
+			}
+			v.publicKeys[key] = publicKey
+		case strings.HasSuffix(key, ".crt"):
+			if !v.roots.AppendCertsFromPEM(value) {
+				return nil, fmt.Errorf("root certificate %q is invalid", key)
+			}
+			hasRoots = true
+		}
+	}
+	if len(v.identities) != 0 && !hasRoots {
+		return nil, fmt.Errorf("keyless identities require at least one root certificate")
+	}
+	if len(v.publicKeys) == 0 && len(v.identities) == 0 {
+		return nil, fmt.Errorf("no public keys or keyless identities are trusted")
+	}
+	return v, nil
+}
+
+// Verify returns nil when at least one of the signatures is a valid signature
+// for the image digest made by a trusted key or identity.
+func (v *cosignVerifier) Verify(digest name.Digest, signatures []cosignSignature) error {
+	messages := []string{}
+	for _, signature := range signatures {
+		err := v.verifySignature(digest, signature)
+		if err == nil {
+			return nil
+		}
+		messages = append(messages, err.Error())
+	}
+	return fmt.Errorf("no signature is trusted: %s", strings.Join(messages, "; "))
+}
+
+func (v *cosignVerifier) verifySignature(digest name.Digest, signature cosignSignature) error {
+	if signature.certificate != "" && len(v.identities) != 0 {
+		publicKey, err := v.verifyCertificate(signature)
+		if err != nil {
+			return err
+		}
+		if err := verifyCosignSignature(publicKey, signature.payload, signature.signature); err != nil {
+			return err
+		}
+	} else {
+		verified := false
+		// try keys in a stable order
+		keys := make([]string, 0, len(v.publicKeys))
+		for key := range v.publicKeys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := verifyCosignSignature(v.publicKeys[key], signature.payload, signature.signature); err == nil {
+				verified = true
+				break
+			}
+		}
+		if !verified {
+			return fmt.Errorf("signature was not made by a trusted public key")
+		}
+	}
+
+	// the signature is only meaningful for the image it names
+	payload := cosignPayload{}
+	if err := json.Unmarshal(signature.payload, &payload); err != nil {
+		return fmt.Errorf("signature payload is invalid: %w", err)
+	}
+	if payload.Critical.Type != CosignSignatureType {
+		return fmt.Errorf("signature payload type %q is not supported", payload.Critical.Type)
+	}
+	if payload.Critical.Image.DockerManifestDigest != digest.DigestStr() {
+		return fmt.Errorf("signature is for digest %q, not %q", payload.Critical.Image.DockerManifestDigest, digest.DigestStr())
+	}
+	return nil
+}
+
+// verifyCertificate checks the keyless signing certificate chains to a trusted
+// root and was issued to a trusted identity, returning the certificate's
+// public key.
+func (v *cosignVerifier) verifyCertificate(signature cosignSignature) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(signature.certificate))
+	if block == nil {
+		return nil, fmt.Errorf("signing certificate is not PEM encoded")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("signing certificate is invalid: %w", err)
+	}
+	intermediates := x509.NewCertPool()
+	if signature.chain != "" {
+		intermediates.AppendCertsFromPEM([]byte(signature.chain))
+	}
+	// keyless certificates are short lived, they only need to be valid at the
+	// time of signing when the transparency log proves when that was
+	signedAt := time.Now()
+	if signature.bundle != "" {
+		signedAt, err = v.verifyBundle(signature, block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = certificate.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   signedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("signing certificate is not trusted: %w", err)
+	}
+
+	issuer := certificateIssuer(certificate)
+	subjects := append([]string{}, certificate.EmailAddresses...)
+	for _, uri := range certificate.URIs {
+		subjects = append(subjects, uri.String())
+	}
+	for _, identity := range v.identities {
+		if identity.Issuer != issuer {
+			continue
+		}
+		for _, subject := range subjects {
+			if identity.Subject == subject {
+				return certificate.PublicKey, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("signing certificate identity %q issued by %q is not trusted", strings.Join(subjects, ","), issuer)
+}
+
+// rekorBundle is the Rekor transparency log entry attached to a signature by
+// cosign
+type rekorBundle struct {
+	SignedEntryTimestamp []byte             `json:"SignedEntryTimestamp"`
+	Payload              rekorBundlePayload `json:"Payload"`
+}
+
+// rekorBundlePayload is the log entry signed by Rekor, the fields are ordered
+// as in its canonical JSON encoding
+type rekorBundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// rekorEntry is the body of a "hashedrekord" Rekor log entry
+type rekorEntry struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// verifyBundle checks the Rekor bundle was signed by a trusted log and records
+// the signature made with the certificate, returning the time the entry was
+// integrated into the log.
+func (v *cosignVerifier) verifyBundle(signature cosignSignature, certificateDER []byte) (time.Time, error) {
+	bundle := rekorBundle{}
+	if err := json.Unmarshal([]byte(signature.bundle), &bundle); err != nil {
+		return time.Time{}, fmt.Errorf("Rekor bundle is invalid: %w", err)
+	}
+	rekorKey, ok := v.rekorKeys[bundle.Payload.LogID]
+	if !ok {
+		return time.Time{}, fmt.Errorf("Rekor bundle is from untrusted log %q", bundle.Payload.LogID)
+	}
+	canonical, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	hash := sha256.Sum256(canonical)
+	if !ecdsa.VerifyASN1(rekorKey, hash[:], bundle.SignedEntryTimestamp) {
+		return time.Time{}, fmt.Errorf("Rekor bundle signed entry timestamp is invalid")
+	}
+
+	body, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Rekor bundle body is not base64 encoded: %w", err)
+	}
+	entry := rekorEntry{}
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, fmt.Errorf("Rekor bundle body is invalid: %w", err)
+	}
+	if entry.Kind != "hashedrekord" {
+		return time.Time{}, fmt.Errorf("Rekor entry kind %q is not supported", entry.Kind)
+	}
+	payloadHash := sha256.Sum256(signature.payload)
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(payloadHash[:]) {
+		return time.Time{}, fmt.Errorf("Rekor entry does not record the signed payload")
+	}
+	if !bytes.Equal(entry.Spec.Signature.Content, signature.signature) {
+		return time.Time{}, fmt.Errorf("Rekor entry does not record the signature")
+	}
+	block, _ := pem.Decode(entry.Spec.Signature.PublicKey.Content)
+	if block == nil || !bytes.Equal(block.Bytes, certificateDER) {
+		return time.Time{}, fmt.Errorf("Rekor entry does not record the signing certificate")
+	}
+	return time.Unix(bundle.Payload.IntegratedTime, 0), nil
+}
+
+// certificateIssuer returns the OIDC issuer recorded in a Fulcio certificate
+func certificateIssuer(certificate *x509.Certificate) string {
+	for _, extension := range certificate.Extensions {
+		if extension.Id.Equal(oidcIssuerExtension) {
+			var issuer string
+			if _, err := asn1.Unmarshal(extension.Value, &issuer); err == nil {
+				return issuer
+			}
+		}
+	}
+	for _, extension := range certificate.Extensions {
+		if extension.Id.Equal(oidcIssuerLegacyExtension) {
+			return string(extension.Value)
+		}
+	}
+	return ""
+}
+
+// verifyCosignSignature verifies the signature of the payload in the way it
+// is signed by cosign for each type of key
+func verifyCosignSignature(publicKey crypto.PublicKey, payload, signature []byte) error {
+	switch publicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		hash := sha256.Sum256(payload)
+		if !ecdsa.VerifyASN1(publicKey, hash[:], signature) {
+			return fmt.Errorf("invalid ECDSA signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		hash := sha256.Sum256(payload)
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash[:], signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(publicKey, payload, signature) {
+			return fmt.Errorf("invalid Ed25519 signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key type %T", publicKey)
+}
+
+// fetchCosignSignatures returns the cosign signatures attached to the image
+// either as OCI referrers or with cosign's "sha256-<hex>.sig" tag convention.
+func fetchCosignSignatures(digest name.Digest, options ...remote.Option) ([]cosignSignature, error) {
+	signatures := []cosignSignature{}
+
+	referrers, err := remote.Referrers(digest, append(options, remote.WithFilter("artifactType", CosignSignatureArtifactType))...)
+	if err != nil {
+		return nil, err
+	}
+	index, err := referrers.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, descriptor := range index.Manifests {
+		image, err := remote.Image(digest.Context().Digest(descriptor.Digest.String()), options...)
+		if err != nil {
+			return nil, err
+		}
+		s, err := cosignSignaturesFromImage(image)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, s...)
+	}
+
+	tag := digest.Context().Tag(fmt.Sprintf("%s.sig", strings.Replace(digest.DigestStr(), ":", "-", 1)))
+	image, err := remote.Image(tag, options...)
+	if err != nil {
+		var terr *transport.Error
+		if !errors.As(err, &terr) || terr.StatusCode != http.StatusNotFound {
+			return nil, err
+		}
+	} else {
+		s, err := cosignSignaturesFromImage(image)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, s...)
+	}
+
+	if len(signatures) == 0 {
+		return nil, errNoSignatures
+	}
+	return signatures, nil
+}
+
+func cosignSignaturesFromImage(image v1.Image) ([]cosignSignature, error) {
+	manifest, err := image.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	signatures := []cosignSignature{}
+	for _, descriptor := range manifest.Layers {
+		if descriptor.MediaType != CosignSimpleSigningMediaType {
+			continue
+		}
+		encoded, ok := descriptor.Annotations[CosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("signature annotation is not base64 encoded: %w", err)
+		}
+		layer, err := image.LayerByDigest(descriptor.Digest)
+		if err != nil {
+			return nil, err
+		}
+		reader, err := layer.Compressed()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, cosignSignature{
+			payload:     payload,
+			signature:   signature,
+			certificate: descriptor.Annotations[CosignCertificateAnnotation],
+			chain:       descriptor.Annotations[CosignChainAnnotation],
+			bundle:      descriptor.Annotations[CosignBundleAnnotation],
+		})
+	}
+	return signatures, nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+)
+
+func TestCosignVerifierKeylessSigningTime(t *testing.T) {
+	digest, err := name.NewDigest("registry.example/app@sha256:" + strings.Repeat("a", 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"registry.example/app"},"image":{"docker-manifest-digest":%q},"type":%q},"optional":null}`, digest.DigestStr(), CosignSignatureType))
+
+	rekorKey := generateTestKey(t)
+	untrustedRekorKey := generateTestKey(t)
+	rootKey := generateTestKey(t)
+	root := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio.example"},
+		NotBefore:             time.Now().Add(-2 * time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, root, root, rootKey.Public(), rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorDER, err := x509.MarshalPKIXPublicKey(rekorKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := newCosignVerifier(corev1.Secret{Data: map[string][]byte{
+		"fulcio.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER}),
+		"rekor.pub":  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rekorDER}),
+	}}, []sourcev1alpha1.ImageKeylessIdentity{{Issuer: "https://issuer.example", Subject: "dev@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sign issues a certificate valid for ten minutes from notBefore and signs
+	// the payload with it
+	sign := func(notBefore time.Time) (cosignSignature, []byte) {
+		issuer, err := asn1.Marshal("https://issuer.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := generateTestKey(t)
+		leaf := &x509.Certificate{
+			SerialNumber:   big.NewInt(2),
+			NotBefore:      notBefore,
+			NotAfter:       notBefore.Add(10 * time.Minute),
+			KeyUsage:       x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			EmailAddresses: []string{"dev@example.com"},
+			ExtraExtensions: []pkix.Extension{
+				{Id: oidcIssuerExtension, Value: issuer},
+			},
+		}
+		parent, _ := x509.ParseCertificate(rootDER)
+		leafDER, err := x509.CreateCertificate(rand.Reader, leaf, parent, key.Public(), rootKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := sha256.Sum256(payload)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+		return cosignSignature{payload: payload, signature: signature, certificate: string(certificate)}, certificate
+	}
+	// bundle records the signature in a Rekor log signed by the key
+	bundle := func(signature cosignSignature, certificate []byte, integratedAt time.Time, key *ecdsa.PrivateKey) string {
+		payloadHash := sha256.Sum256(signature.payload)
+		body, err := json.Marshal(map[string]interface{}{
+			"apiVersion": "0.0.1",
+			"kind":       "hashedrekord",
+			"spec": map[string]interface{}{
+				"data": map[string]interface{}{
+					"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(payloadHash[:])},
+				},
+				"signature": map[string]interface{}{
+					"content":   signature.signature,
+					"publicKey": map[string]interface{}{"content": certificate},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		logID := sha256.Sum256(der)
+		entry := rekorBundlePayload{
+			Body:           base64.StdEncoding.EncodeToString(body),
+			IntegratedTime: integratedAt.Unix(),
+			LogID:          hex.EncodeToString(logID[:]),
+			LogIndex:       1,
+		}
+		canonical, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := sha256.Sum256(canonical)
+		set, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := json.Marshal(rekorBundle{SignedEntryTimestamp: set, Payload: entry})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(encoded)
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name      string
+		signature func() cosignSignature
+		err       string
+	}{
+		{
+			name: "valid certificate",
+			signature: func() cosignSignature {
+				signature, _ := sign(time.Now().Add(-time.Minute))
+				return signature
+			},
+		},
+		{
+			name: "expired certificate signed while valid",
+			signature: func() cosignSignature {
+				signature, certificate := sign(expired)
+				signature.bundle = bundle(signature, certificate, expired.Add(time.Minute), rekorKey)
+				return signature
+			},
+		},
+		{
+			name: "expired certificate without a transparency log entry",
+			signature: func() cosignSignature {
+				signature, _ := sign(expired)
+				return signature
+			},
+			err: "signing certificate is not trusted: x509: certificate has expired or is not yet valid",
+		},
+		{
+			name: "expired certificate logged after it expired",
+			signature: func() cosignSignature {
+				signature, certificate := sign(expired)
+				signature.bundle = bundle(signature, certificate, time.Now(), rekorKey)
+				return signature
+			},
+			err: "signing certificate is not trusted: x509: certificate has expired or is not yet valid",
+		},
+		{
+			name: "untrusted log",
+			signature: func() cosignSignature {
+				signature, certificate := sign(expired)
+				signature.bundle = bundle(signature, certificate, expired.Add(time.Minute), untrustedRekorKey)
+				return signature
+			},
+			err: "Rekor bundle is from untrusted log",
+		},
+		{
+			name: "log entry for another signature",
+			signature: func() cosignSignature {
+				signature, certificate := sign(expired)
+				other, _ := sign(expired)
+				signature.bundle = bundle(other, certificate, expired.Add(time.Minute), rekorKey)
+				return signature
+			},
+			err: "Rekor entry does not record the signature",
+		},
+		{
+			name: "tampered signing time",
+			signature: func() cosignSignature {
+				signature, certificate := sign(expired)
+				signature.bundle = strings.Replace(bundle(signature, certificate, expired.Add(time.Minute), rekorKey),
+					fmt.Sprintf(`"integratedTime":%d`, expired.Add(time.Minute).Unix()),
+					fmt.Sprintf(`"integratedTime":%d`, expired.Add(2*time.Minute).Unix()), 1)
+				return signature
+			},
+			err: "Rekor bundle signed entry timestamp is invalid",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifier.verifySignature(digest, tc.signature())
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func generateTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
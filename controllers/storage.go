@@ -0,0 +1,540 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/signedurl"
+	"github.com/vmware-tanzu/tanzu-source-controller/server"
+)
+
+// artifactKinds maps the top level directories of the artifact root to the
+// resource that owns the artifacts stored beneath them.
+var artifactKinds = map[string]func() client.Object{
+	"imagerepository": func() client.Object { return &sourcev1alpha1.ImageRepository{} },
+	"mavenartifact":   func() client.Object { return &sourcev1alpha1.MavenArtifact{} },
+}
+
+// ArtifactResources maps the top level directories of the artifact root to
+// the resource that owns the artifacts stored beneath them, for authorizing
+// downloads.
+var ArtifactResources = map[string]schema.GroupResource{
+	"imagerepository": {Group: sourcev1alpha1.GroupVersion.Group, Resource: "imagerepositories"},
+	"mavenartifact":   {Group: sourcev1alpha1.GroupVersion.Group, Resource: "mavenartifacts"},
+}
+
+var (
+	artifactReclaimedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "source_controller_artifact_reclaimed_bytes_total",
+		Help: "Bytes reclaimed by removing stored artifacts.",
+	}, []string{"kind", "reason"})
+	artifactRemovedRevisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "source_controller_artifact_removed_revisions_total",
+		Help: "Artifact revisions removed from storage.",
+	}, []string{"kind", "reason"})
+)
+
+const (
+	// reclaimRetention labels artifacts removed because they exceeded the
+	// revision history limit of their resource
+	reclaimRetention = "retention"
+	// reclaimOrphaned labels artifacts removed because their resource no
+	// longer exists
+	reclaimOrphaned = "orphaned"
+)
+
+func init() {
+	metrics.Registry.MustRegister(artifactReclaimedBytes, artifactRemovedRevisions)
+}
+
+// ArtifactObject describes an object stored by an ArtifactBackend
+type ArtifactObject struct {
+	// Key identifies the object, in the form of '<kind>/<namespace>/<name>/<file>'
+	Key string
+	// Size of the object in bytes
+	Size int64
+	// ModTime is when the object was last stored
+	ModTime time.Time
+}
+
+// ArtifactBackend stores the artifacts of each resource and generates the
+// URLs they are served from. Keys are slash separated paths.
+type ArtifactBackend interface {
+	// Put stores size bytes read from r at the key, replacing any existing
+	// object. Consumers never observe a partially stored object.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get returns the content of the object at the key. The error wraps
+	// fs.ErrNotExist when the object does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat describes the object at the key. The error wraps fs.ErrNotExist
+	// when the object does not exist.
+	Stat(ctx context.Context, key string) (ArtifactObject, error)
+	// List describes every object with a key beneath the prefix.
+	List(ctx context.Context, prefix string) ([]ArtifactObject, error)
+	// Delete removes the object at the key, if it exists.
+	Delete(ctx context.Context, key string) error
+	// URL returns the URL the object at the key is served from.
+	URL(key string) string
+}
+
+// fileBackend is implemented by backends able to store a local file without
+// copying it
+type fileBackend interface {
+	// PutFile stores the file at name at the key. The file must not be
+	// modified once stored.
+	PutFile(ctx context.Context, key string, name string) error
+	// StagingName returns the name of a local file the object at the key may
+	// be written to, then stored with PutFile without being copied.
+	StagingName(key string) (string, error)
+}
+
+// ArtifactStorage manages the artifacts stored for each resource by the
+// backend, in the form of '<kind>/<namespace>/<name>/<file>'.
+type ArtifactStorage struct {
+	// Backend stores the artifacts
+	Backend ArtifactBackend
+	// RevisionHistoryLimit is the number of revisions retained for each
+	// resource that does not set its own limit. Zero retains every revision.
+	RevisionHistoryLimit int
+	// Client looks up the resources that own the stored artifacts
+	Client client.Reader
+	// SweepInterval is how often artifacts of resources that no longer exist
+	// are removed
+	SweepInterval time.Duration
+	// URLSigner signs the URLs of artifacts with an expiring token. URLs are
+	// not signed when nil.
+	URLSigner *signedurl.Signer
+	// Limits bounds the content fetched and extracted to produce each
+	// artifact. Resources may tighten the limits.
+	Limits ArtifactLimits
+	// Cache holds the content downloaded and the artifacts packaged from it,
+	// shared by resources fetching the same content. Nothing is cached when
+	// nil.
+	Cache *blobcache.Cache
+}
+
+// URL returns the URL the artifact at the key is served from. Signed URLs are
+// reused while current is a URL for the same key that is not yet due to be
+// refreshed.
+func (s *ArtifactStorage) URL(ctx context.Context, key string, current string) (string, error) {
+	url := s.Backend.URL(key)
+	if s.URLSigner == nil {
+		return url, nil
+	}
+	if signedurl.Unsigned(current) == url && !s.URLSigner.NeedsRefresh(current) {
+		return current, nil
+	}
+	return s.URLSigner.Sign(ctx, url)
+}
+
+// LatestURL signs the URL of the latest alias of the artifact, reusing the
+// current URL until it needs refreshing. Unsigned alias URLs are derived from
+// the artifact URL, and the alias is only served for artifacts stored by the
+// filesystem backend, otherwise the URL is empty.
+func (s *ArtifactStorage) LatestURL(ctx context.Context, artifact *sourcev1alpha1.Artifact, current string) (string, error) {
+	if s.URLSigner == nil {
+		return "", nil
+	}
+	if _, ok := s.Backend.(*FilesystemBackend); !ok {
+		return "", nil
+	}
+	ext := server.ArtifactExtension(path.Base(artifact.Path))
+	return s.URL(ctx, path.Join(path.Dir(artifact.Path), server.LatestName+ext), current)
+}
+
+// URLRefreshInterval is how often resources are reconciled to refresh signed
+// URLs before they expire, zero when URLs are not signed
+func (s *ArtifactStorage) URLRefreshInterval() time.Duration {
+	if s.URLSigner == nil {
+		return 0
+	}
+	return s.URLSigner.TTL / 2
+}
+
+// requeueInterval is the interval a resource is reconciled at, shortened to
+// refresh the signed URL of its artifact before it expires
+func requeueInterval(interval time.Duration, storage *ArtifactStorage) time.Duration {
+	if refresh := storage.URLRefreshInterval(); refresh > 0 && refresh < interval {
+		return refresh
+	}
+	return interval
+}
+
+// Put stores the local file at the key, along with its algorithm-prefixed
+// digest. The artifact server derives the ETag of the artifact from the
+// digest.
+func (s *ArtifactStorage) Put(ctx context.Context, key string, name string, digest string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return s.store(ctx, key, digest, func() error {
+		if backend, ok := s.Backend.(fileBackend); ok {
+			// the file is copied when it cannot be linked
+			if err := backend.PutFile(ctx, key, name); err == nil {
+				return nil
+			}
+		}
+		return s.Backend.Put(ctx, key, file, info.Size())
+	})
+}
+
+// store stores the artifact at the key with put, along with its digest
+func (s *ArtifactStorage) store(ctx context.Context, key string, digest string, put func() error) error {
+	// an artifact may be replaced, never serve it with the digest of the
+	// previous content
+	digestKey := key + server.DigestSuffix
+	if err := s.Backend.Delete(ctx, digestKey); err != nil {
+		return err
+	}
+	if err := put(); err != nil {
+		return err
+	}
+	return s.Backend.Put(ctx, digestKey, strings.NewReader(digest), int64(len(digest)))
+}
+
+// StagedArtifact is a local file an artifact is written to before it is
+// stored. Artifacts stored on the local filesystem are staged beside the
+// artifact they replace, and stored without being copied.
+type StagedArtifact struct {
+	// Name of the file to write the artifact to
+	Name string
+
+	storage *ArtifactStorage
+	key     string
+	// tempDir holds the file when it is not staged by the backend
+	tempDir string
+	stored  bool
+}
+
+// Stage returns the file to write the artifact at the key to, stored once
+// committed. The file is removed when closed, unless stored.
+func (s *ArtifactStorage) Stage(ctx context.Context, key string) (*StagedArtifact, error) {
+	if backend, ok := s.Backend.(fileBackend); ok {
+		name, err := backend.StagingName(key)
+		if err != nil {
+			return nil, err
+		}
+		return &StagedArtifact{Name: name, storage: s, key: key}, nil
+	}
+	dir, err := os.MkdirTemp(os.TempDir(), "artifact.*")
+	if err != nil {
+		return nil, err
+	}
+	return &StagedArtifact{Name: path.Join(dir, path.Base(key)), storage: s, key: key, tempDir: dir}, nil
+}
+
+// Commit stores the artifact written to the file, along with its
+// algorithm-prefixed digest
+func (a *StagedArtifact) Commit(ctx context.Context, digest string) error {
+	if a.tempDir != "" {
+		return a.storage.Put(ctx, a.key, a.Name, digest)
+	}
+	err := a.storage.store(ctx, a.key, digest, func() error {
+		return a.storage.Backend.(fileBackend).PutFile(ctx, a.key, a.Name)
+	})
+	if err != nil {
+		return err
+	}
+	a.stored = true
+	return nil
+}
+
+// Close removes the file, unless stored
+func (a *StagedArtifact) Close() error {
+	if a.tempDir != "" {
+		return os.RemoveAll(a.tempDir)
+	}
+	if a.stored {
+		return nil
+	}
+	if err := os.Remove(a.Name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ArtifactManifest describes the content of an artifact, allowing consumers to
+// inspect and compare revisions without downloading them
+type ArtifactManifest struct {
+	// Revision is the source revision the artifact was packaged from
+	Revision string `json:"revision"`
+	// Stored is when the artifact was stored. Revisions are pruned oldest
+	// first by this time rather than by the modification time of their files,
+	// which changes when files are restored or linked elsewhere.
+	Stored *metav1.Time `json:"stored,omitempty"`
+	// Files and symlinks packaged in the artifact, in the order they appear
+	// in the tarball
+	Files []ArtifactManifestFile `json:"files"`
+}
+
+// ArtifactManifestFile describes a file or symlink packaged in an artifact
+type ArtifactManifestFile struct {
+	// Path of the file within the artifact
+	Path string `json:"path"`
+	// Size of the file in bytes
+	Size int64 `json:"size"`
+	// Mode holds the octal permission bits of the file
+	Mode string `json:"mode"`
+	// Digest is the SHA-256 digest of the file's content, in the form of
+	// 'sha256:<hex checksum>'. Symlinks have no digest.
+	Digest string `json:"digest,omitempty"`
+	// Link is the target of a symlink
+	Link string `json:"link,omitempty"`
+}
+
+// manifestKey returns the key of the manifest for the artifact at the key
+func manifestKey(key string) string {
+	return strings.TrimSuffix(key, server.ArtifactExtension(key)) + server.ManifestSuffix
+}
+
+// PutManifest stores the manifest of the artifact at the key beside the
+// artifact, at '<name>.json'
+func (s *ArtifactStorage) PutManifest(ctx context.Context, key string, manifest *ArtifactManifest) error {
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return s.Backend.Put(ctx, manifestKey(key), bytes.NewReader(content), int64(len(content)))
+}
+
+// Index records the artifact as the current artifact of its resource, served
+// at the resource's latest alias. The index is only written when it changes.
+func (s *ArtifactStorage) Index(ctx context.Context, artifact *sourcev1alpha1.Artifact) error {
+	content, err := json.Marshal(server.ArtifactIndex{
+		Artifact: path.Base(artifact.Path),
+		Revision: artifact.Revision,
+		Checksum: artifact.Checksum,
+		Digest:   artifact.Digest,
+	})
+	if err != nil {
+		return err
+	}
+	key := path.Join(path.Dir(artifact.Path), server.IndexFile)
+
+	if current, err := s.Backend.Get(ctx, key); err == nil {
+		defer current.Close()
+		if existing, err := io.ReadAll(current); err == nil && bytes.Equal(existing, content) {
+			return nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return s.Backend.Put(ctx, key, bytes.NewReader(content), int64(len(content)))
+}
+
+// Remove removes every object stored beneath the directory
+func (s *ArtifactStorage) Remove(ctx context.Context, dir string) error {
+	objects, err := s.Backend.List(ctx, dir+"/")
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := s.Backend.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune removes the oldest revisions stored alongside the current artifact at
+// the key, retaining the most recent revisions up to the limit. The limit
+// overrides the storage's RevisionHistoryLimit when set. The current artifact
+// is always retained.
+func (s *ArtifactStorage) Prune(ctx context.Context, key string, limit *int32) error {
+	retain := s.RevisionHistoryLimit
+	if limit != nil {
+		retain = int(*limit)
+	}
+	if retain <= 0 {
+		return nil
+	}
+
+	dir := path.Dir(key)
+	objects, err := s.Backend.List(ctx, dir+"/")
+	if err != nil {
+		return err
+	}
+
+	revisions := []ArtifactObject{}
+	for _, object := range objects {
+		if path.Dir(object.Key) != dir || server.ArtifactExtension(object.Key) == "" || object.Key == key {
+			continue
+		}
+		revisions = append(revisions, object)
+	}
+	// files stored alongside a revision share its name, such as the digest
+	// at '<revision>.tar.gz.digest'
+	stem := func(key string) string {
+		return strings.TrimSuffix(key, server.ArtifactExtension(key)) + "."
+	}
+	related := func(revision ArtifactObject) []ArtifactObject {
+		prefix := stem(revision.Key)
+		if prefix == stem(key) {
+			// the revision was packaged in another format than the current
+			// artifact, the manifest they share is retained
+			prefix = revision.Key + "."
+		}
+		files := []ArtifactObject{}
+		for _, object := range objects {
+			if object.Key != revision.Key && path.Dir(object.Key) == dir && strings.HasPrefix(object.Key, prefix) {
+				files = append(files, object)
+			}
+		}
+		return files
+	}
+	if len(revisions) < retain {
+		return nil
+	}
+	// newest first, the current revision counts towards the limit
+	stored := make(map[string]time.Time, len(revisions))
+	for _, revision := range revisions {
+		stored[revision.Key] = s.storedTime(ctx, revision)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		if a, b := stored[revisions[i].Key], stored[revisions[j].Key]; !a.Equal(b) {
+			return a.After(b)
+		}
+		return revisions[i].Key > revisions[j].Key
+	})
+
+	log := logr.FromContextOrDiscard(ctx)
+	kind := strings.SplitN(key, "/", 2)[0]
+	for _, revision := range revisions[retain-1:] {
+		log.Info("removing artifact revision", "key", revision.Key)
+		for _, object := range append(related(revision), revision) {
+			if err := s.Backend.Delete(ctx, object.Key); err != nil {
+				return err
+			}
+			artifactReclaimedBytes.WithLabelValues(kind, reclaimRetention).Add(float64(object.Size))
+		}
+		artifactRemovedRevisions.WithLabelValues(kind, reclaimRetention).Inc()
+	}
+	return nil
+}
+
+// storedTime returns when the revision was stored, as recorded by its
+// manifest. Revisions without a manifest recording the time fall back to
+// the modification time of the object.
+func (s *ArtifactStorage) storedTime(ctx context.Context, revision ArtifactObject) time.Time {
+	content, err := s.Backend.Get(ctx, manifestKey(revision.Key))
+	if err != nil {
+		return revision.ModTime
+	}
+	defer content.Close()
+	manifest := &ArtifactManifest{}
+	if err := json.NewDecoder(content).Decode(manifest); err != nil || manifest.Stored == nil {
+		return revision.ModTime
+	}
+	return manifest.Stored.Time
+}
+
+// Sweep removes the artifacts stored for resources that no longer exist. A
+// resource's artifacts are normally removed when it is finalized, artifacts
+// are orphaned when the finalizer was removed while the controller was not
+// running.
+func (s *ArtifactStorage) Sweep(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for kind, newObject := range artifactKinds {
+		objects, err := s.Backend.List(ctx, kind+"/")
+		if err != nil {
+			return err
+		}
+
+		// group the objects by the resource that owns them
+		owned := map[types.NamespacedName][]ArtifactObject{}
+		for _, object := range objects {
+			segments := strings.Split(object.Key, "/")
+			if len(segments) < 4 {
+				continue
+			}
+			owner := types.NamespacedName{Namespace: segments[1], Name: segments[2]}
+			owned[owner] = append(owned[owner], object)
+		}
+
+		for owner, objects := range owned {
+			err := s.Client.Get(ctx, owner, newObject())
+			if err == nil {
+				continue
+			}
+			if !apierrs.IsNotFound(err) {
+				return err
+			}
+
+			log.Info("removing orphaned artifacts", "kind", kind, "namespace", owner.Namespace, "name", owner.Name)
+			for _, object := range objects {
+				if err := s.Backend.Delete(ctx, object.Key); err != nil {
+					return err
+				}
+				artifactReclaimedBytes.WithLabelValues(kind, reclaimOrphaned).Add(float64(object.Size))
+				if server.ArtifactExtension(object.Key) != "" {
+					artifactRemovedRevisions.WithLabelValues(kind, reclaimOrphaned).Inc()
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Start sweeps orphaned artifacts every SweepInterval until the context is
+// done.
+func (s *ArtifactStorage) Start(ctx context.Context) error {
+	log := controllerruntime.Log.WithName("artifact-storage")
+	ctx = logr.NewContext(ctx, log)
+
+	ticker := time.NewTicker(s.SweepInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sweep(ctx); err != nil {
+			log.Error(err, "unable to sweep orphaned artifacts")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
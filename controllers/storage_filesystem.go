@@ -0,0 +1,175 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FilesystemBackend stores artifacts in a local directory, served by the
+// artifact server at Host.
+type FilesystemBackend struct {
+	// RootDir is the directory artifacts are stored in and served from
+	RootDir string
+	// Host is the host name of the artifact server
+	Host string
+	// Scheme the artifact server is reached with, either http or https.
+	// Defaults to http.
+	Scheme string
+}
+
+var (
+	_ ArtifactBackend = (*FilesystemBackend)(nil)
+	_ fileBackend     = (*FilesystemBackend)(nil)
+)
+
+func (b *FilesystemBackend) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	name := path.Join(b.RootDir, key)
+	if err := os.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	// write to a placeholder name, renamed once complete
+	intermediate := fmt.Sprintf("%s.new", name)
+	file, err := os.Create(intermediate)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(intermediate)
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(intermediate, name)
+}
+
+// PutFile stores the file at name at the key by hard linking it into the root
+// directory, failing when they do not share a filesystem. A file at the
+// staging name of the key is moved rather than linked. The file must not be
+// modified once stored.
+func (b *FilesystemBackend) PutFile(ctx context.Context, key string, name string) error {
+	target := path.Join(b.RootDir, key)
+	intermediate, err := b.StagingName(key)
+	if err != nil {
+		return err
+	}
+	if name == intermediate {
+		return os.Rename(intermediate, target)
+	}
+	// link to a placeholder name, renamed to replace an existing object
+	if err := os.Remove(intermediate); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Link(name, intermediate); err != nil {
+		return err
+	}
+	defer os.Remove(intermediate)
+	return os.Rename(intermediate, target)
+}
+
+// StagingName returns the placeholder name objects are written to within the
+// root directory before they replace the object at the key
+func (b *FilesystemBackend) StagingName(key string) (string, error) {
+	name := path.Join(b.RootDir, key)
+	if err := os.MkdirAll(path.Dir(name), 0755); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.new", name), nil
+}
+
+func (b *FilesystemBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(path.Join(b.RootDir, key))
+}
+
+func (b *FilesystemBackend) Stat(ctx context.Context, key string) (ArtifactObject, error) {
+	info, err := os.Stat(path.Join(b.RootDir, key))
+	if err != nil {
+		return ArtifactObject{}, err
+	}
+	return ArtifactObject{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (b *FilesystemBackend) List(ctx context.Context, prefix string) ([]ArtifactObject, error) {
+	// walk the deepest directory containing the prefix
+	dir := path.Join(b.RootDir, prefix)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		dir = path.Dir(dir)
+	}
+
+	objects := []ArtifactObject{}
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		key, err := filepath.Rel(b.RootDir, name)
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(key)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		objects = append(objects, ArtifactObject{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
+
+func (b *FilesystemBackend) Delete(ctx context.Context, key string) error {
+	name := path.Join(b.RootDir, key)
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	// remove the directories left empty, stopping at the first that is not
+	for dir := path.Dir(name); dir != path.Clean(b.RootDir) && dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (b *FilesystemBackend) URL(key string) string {
+	scheme := b.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s", scheme, path.Join(b.Host, key))
+}
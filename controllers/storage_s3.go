@@ -0,0 +1,169 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Backend stores artifacts in a bucket of an S3 compatible object store,
+// artifacts are served by the object store.
+type S3Backend struct {
+	// Client for the object store
+	Client *minio.Client
+	// Bucket artifacts are stored in
+	Bucket string
+	// Prefix prepended to the key of each artifact
+	Prefix string
+	// URLBase is the URL the bucket is served from, artifact URLs are the
+	// URLBase followed by the object name
+	URLBase string
+}
+
+var _ ArtifactBackend = (*S3Backend)(nil)
+
+// S3Options configures the S3Backend returned by NewS3Backend
+type S3Options struct {
+	// Endpoint is the host, and optional port, of the object store
+	Endpoint string
+	// Insecure connects to the object store over HTTP rather than HTTPS
+	Insecure bool
+	// Region of the bucket, discovered from the object store when empty
+	Region string
+	// Bucket artifacts are stored in
+	Bucket string
+	// Prefix prepended to the key of each artifact
+	Prefix string
+	// URLBase is the URL the bucket is served from. Defaults to the path style
+	// URL of the bucket at the endpoint.
+	URLBase string
+	// Credentials to access the object store. Defaults to the credentials in
+	// the AWS_* or MINIO_* environment variables, or from the instance
+	// metadata service.
+	Credentials *credentials.Credentials
+	// Transport to reach the object store, defaults to the default transport
+	Transport http.RoundTripper
+}
+
+// NewS3Backend creates an S3Backend for the options
+func NewS3Backend(opts S3Options) (*S3Backend, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("an S3 bucket is required")
+	}
+	creds := opts.Credentials
+	if creds == nil {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.IAM{},
+		})
+	}
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:     creds,
+		Secure:    !opts.Insecure,
+		Region:    opts.Region,
+		Transport: opts.Transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	urlBase := opts.URLBase
+	if urlBase == "" {
+		urlBase = client.EndpointURL().JoinPath(opts.Bucket).String()
+	}
+	return &S3Backend{
+		Client:  client,
+		Bucket:  opts.Bucket,
+		Prefix:  opts.Prefix,
+		URLBase: strings.TrimSuffix(urlBase, "/"),
+	}, nil
+}
+
+func (b *S3Backend) objectName(key string) string {
+	return path.Join(b.Prefix, key)
+}
+
+func (b *S3Backend) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	_, err := b.Client.PutObject(ctx, b.Bucket, b.objectName(key), r, size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	return err
+}
+
+func (b *S3Backend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := b.Client.GetObject(ctx, b.Bucket, b.objectName(key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	// objects are fetched lazily, stat to surface a missing object
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, s3Error(err)
+	}
+	return object, nil
+}
+
+func (b *S3Backend) Stat(ctx context.Context, key string) (ArtifactObject, error) {
+	info, err := b.Client.StatObject(ctx, b.Bucket, b.objectName(key), minio.StatObjectOptions{})
+	if err != nil {
+		return ArtifactObject{}, s3Error(err)
+	}
+	return ArtifactObject{Key: key, Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+func (b *S3Backend) List(ctx context.Context, prefix string) ([]ArtifactObject, error) {
+	objectPrefix := b.objectName(prefix)
+	if strings.HasSuffix(prefix, "/") {
+		objectPrefix += "/"
+	}
+
+	objects := []ArtifactObject{}
+	for info := range b.Client.ListObjects(ctx, b.Bucket, minio.ListObjectsOptions{Prefix: objectPrefix, Recursive: true}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		key := strings.TrimPrefix(strings.TrimPrefix(info.Key, b.Prefix), "/")
+		objects = append(objects, ArtifactObject{Key: key, Size: info.Size, ModTime: info.LastModified})
+	}
+	return objects, nil
+}
+
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	// deleting a missing object succeeds
+	return b.Client.RemoveObject(ctx, b.Bucket, b.objectName(key), minio.RemoveObjectOptions{})
+}
+
+func (b *S3Backend) URL(key string) string {
+	return fmt.Sprintf("%s/%s", b.URLBase, b.objectName(key))
+}
+
+// s3Error wraps errors for missing objects with fs.ErrNotExist
+func s3Error(err error) error {
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", fs.ErrNotExist, err)
+	}
+	return err
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/controllers"
+)
+
+func TestS3Backend(t *testing.T) {
+	ctx := context.TODO()
+	bucket := newFakeS3Bucket(t, "artifacts")
+
+	backend, err := controllers.NewS3Backend(controllers.S3Options{
+		Endpoint:    bucket.endpoint,
+		Region:      "us-east-1",
+		Bucket:      "artifacts",
+		Prefix:      "source",
+		Credentials: credentials.NewStaticV4("access-key", "secret-key", ""),
+		Transport:   bucket.server.Client().Transport,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Backend() unexpected error: %v", err)
+	}
+
+	key := "imagerepository/test-namespace/my-image/0123.tar.gz"
+	if expected, actual := fmt.Sprintf("https://%s/artifacts/source/%s", bucket.endpoint, key), backend.URL(key); expected != actual {
+		t.Errorf("URL() expected %q, got %q", expected, actual)
+	}
+
+	if _, err := backend.Stat(ctx, key); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() expected a not exist error for a missing object, got %v", err)
+	}
+	if _, err := backend.Get(ctx, key); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Get() expected a not exist error for a missing object, got %v", err)
+	}
+
+	for _, k := range []string{key, "imagerepository/test-namespace/my-image/4567.tar.gz", "imagerepository/test-namespace/other-image/0123.tar.gz"} {
+		if err := backend.Put(ctx, k, strings.NewReader("artifact"), int64(len("artifact"))); err != nil {
+			t.Fatalf("Put() unexpected error: %v", err)
+		}
+	}
+	if diff := cmp.Diff([]string{"source/" + key, "source/imagerepository/test-namespace/my-image/4567.tar.gz", "source/imagerepository/test-namespace/other-image/0123.tar.gz"}, bucket.keys()); diff != "" {
+		t.Errorf("Put() objects (-expected, +actual): %s", diff)
+	}
+
+	object, err := backend.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat() unexpected error: %v", err)
+	}
+	if object.Key != key || object.Size != int64(len("artifact")) {
+		t.Errorf("Stat() unexpected object %+v", object)
+	}
+
+	file, err := backend.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	content, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("Get() unexpected error reading object: %v", err)
+	}
+	if expected, actual := "artifact", string(content); expected != actual {
+		t.Errorf("Get() expected %q, got %q", expected, actual)
+	}
+
+	objects, err := backend.List(ctx, "imagerepository/test-namespace/my-image/")
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	keys := []string{}
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	if diff := cmp.Diff([]string{key, "imagerepository/test-namespace/my-image/4567.tar.gz"}, keys); diff != "" {
+		t.Errorf("List() (-expected, +actual): %s", diff)
+	}
+
+	storage := &controllers.ArtifactStorage{Backend: backend}
+	if err := storage.Remove(ctx, "imagerepository/test-namespace/my-image"); err != nil {
+		t.Fatalf("Remove() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"source/imagerepository/test-namespace/other-image/0123.tar.gz"}, bucket.keys()); diff != "" {
+		t.Errorf("Remove() objects (-expected, +actual): %s", diff)
+	}
+}
+
+// fakeS3Bucket is an in-process stand-in for a single bucket of an S3
+// compatible object store, supporting the subset of the API used by the
+// S3Backend.
+type fakeS3Bucket struct {
+	name     string
+	server   *httptest.Server
+	endpoint string
+
+	m       sync.Mutex
+	objects map[string]fakeS3Object
+}
+
+type fakeS3Object struct {
+	content []byte
+	modTime time.Time
+}
+
+func newFakeS3Bucket(t *testing.T, name string) *fakeS3Bucket {
+	b := &fakeS3Bucket{
+		name:    name,
+		objects: map[string]fakeS3Object{},
+	}
+	b.server = httptest.NewTLSServer(http.HandlerFunc(b.serveHTTP))
+	t.Cleanup(b.server.Close)
+	u, _ := url.Parse(b.server.URL)
+	b.endpoint = u.Host
+	return b
+}
+
+func (b *fakeS3Bucket) keys() []string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	keys := []string{}
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (b *fakeS3Bucket) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		b.writeError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != b.name {
+		b.writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		b.list(w, r)
+	case r.Method == http.MethodPut:
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			b.writeError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		b.objects[key] = fakeS3Object{content: content, modTime: time.Now().UTC().Truncate(time.Second)}
+		w.Header().Set("ETag", etag(content))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, ok := b.objects[key]
+		if !ok {
+			b.writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", etag(object.content))
+		w.Header().Set("Last-Modified", object.modTime.Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.content)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(object.content)
+		}
+	case r.Method == http.MethodDelete:
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		b.writeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (b *fakeS3Bucket) list(w http.ResponseWriter, r *http.Request) {
+	type contents struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+		StorageClass string
+	}
+	type listBucketResult struct {
+		XMLName     xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		MaxKeys     int
+		IsTruncated bool
+		Contents    []contents
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	result := listBucketResult{Name: b.name, Prefix: prefix, MaxKeys: 1000}
+	keys := []string{}
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		object := b.objects[key]
+		result.Contents = append(result.Contents, contents{
+			Key:          key,
+			LastModified: object.modTime.Format(time.RFC3339),
+			ETag:         etag(object.content),
+			Size:         len(object.content),
+			StorageClass: "STANDARD",
+		})
+	}
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (b *fakeS3Bucket) writeError(w http.ResponseWriter, status int, code string) {
+	type errorResponse struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(errorResponse{Code: code, Message: code})
+}
+
+func etag(content []byte) string {
+	return fmt.Sprintf("\"%x\"", md5.Sum(content))
+}
@@ -0,0 +1,482 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
+	"github.com/vmware-tanzu/tanzu-source-controller/controllers"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/signedurl"
+)
+
+func TestArtifactStoragePrune(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+
+	tests := []struct {
+		name     string
+		defLimit int
+		limit    *int32
+		current  string
+		expected []string
+	}{
+		{
+			name:     "retains every revision without a limit",
+			current:  "a.tar.gz",
+			expected: []string{"a.tar.gz", "b.tar.gz", "b.tar.gz.digest", "c.tar.gz", "cache.sha1", "d.tar.gz"},
+		},
+		{
+			name:     "retains the newest revisions",
+			defLimit: 2,
+			current:  "d.tar.gz",
+			expected: []string{"c.tar.gz", "cache.sha1", "d.tar.gz"},
+		},
+		{
+			name:     "retains the current revision when it is the oldest",
+			defLimit: 2,
+			current:  "a.tar.gz",
+			expected: []string{"a.tar.gz", "cache.sha1", "d.tar.gz"},
+		},
+		{
+			name:     "resource limit overrides the default",
+			defLimit: 2,
+			limit:    int32Ptr(1),
+			current:  "d.tar.gz",
+			expected: []string{"cache.sha1", "d.tar.gz"},
+		},
+		{
+			name:     "resource limit larger than the revisions",
+			limit:    int32Ptr(10),
+			current:  "d.tar.gz",
+			expected: []string{"a.tar.gz", "b.tar.gz", "b.tar.gz.digest", "c.tar.gz", "cache.sha1", "d.tar.gz"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootDir := t.TempDir()
+			dir := path.Join(rootDir, "imagerepository", "test-namespace", "my-image")
+			utilruntime.Must(os.MkdirAll(dir, 0755))
+			// revisions are written in order, a is the oldest
+			for i, name := range []string{"a.tar.gz", "b.tar.gz", "b.tar.gz.digest", "c.tar.gz", "d.tar.gz", "cache.sha1"} {
+				file := path.Join(dir, name)
+				utilruntime.Must(os.WriteFile(file, []byte(name), 0644))
+				mtime := time.Unix(int64(i*60), 0)
+				utilruntime.Must(os.Chtimes(file, mtime, mtime))
+			}
+
+			storage := &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: rootDir}, RevisionHistoryLimit: tt.defLimit}
+			if err := storage.Prune(context.TODO(), path.Join("imagerepository", "test-namespace", "my-image", tt.current), tt.limit); err != nil {
+				t.Fatalf("Prune() unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, listFiles(t, dir)); diff != "" {
+				t.Errorf("Prune() (-expected, +actual): %s", diff)
+			}
+		})
+	}
+}
+
+func TestArtifactStoragePruneByStoredTime(t *testing.T) {
+	rootDir := t.TempDir()
+	storage := &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: rootDir}, RevisionHistoryLimit: 2}
+	dir := path.Join(rootDir, "imagerepository", "test-namespace", "my-image")
+	utilruntime.Must(os.MkdirAll(dir, 0755))
+	// the files of a are modified last, but a was stored before b
+	for i, name := range []string{"b", "c", "a"} {
+		file := path.Join(dir, name+".tar.gz")
+		utilruntime.Must(os.WriteFile(file, []byte(name), 0644))
+		mtime := time.Unix(int64(i*60), 0)
+		utilruntime.Must(os.Chtimes(file, mtime, mtime))
+	}
+	for i, name := range []string{"a", "b", "c"} {
+		stored := metav1.NewTime(time.Unix(int64(i*60), 0))
+		key := path.Join("imagerepository", "test-namespace", "my-image", name+".tar.gz")
+		if err := storage.PutManifest(context.TODO(), key, &controllers.ArtifactManifest{Revision: name, Stored: &stored}); err != nil {
+			t.Fatalf("PutManifest() unexpected error: %v", err)
+		}
+	}
+
+	if err := storage.Prune(context.TODO(), path.Join("imagerepository", "test-namespace", "my-image", "c.tar.gz"), nil); err != nil {
+		t.Fatalf("Prune() unexpected error: %v", err)
+	}
+	expected := []string{"b.json", "b.tar.gz", "c.json", "c.tar.gz"}
+	if diff := cmp.Diff(expected, listFiles(t, dir)); diff != "" {
+		t.Errorf("Prune() (-expected, +actual): %s", diff)
+	}
+}
+
+func TestArtifactStoragePruneAfterCacheHit(t *testing.T) {
+	rootDir := t.TempDir()
+	cache, err := blobcache.New(path.Join(t.TempDir(), "cache"), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: rootDir}, RevisionHistoryLimit: 2, Cache: cache}
+	dir := path.Join(rootDir, "imagerepository", "test-namespace", "my-image")
+	utilruntime.Must(os.MkdirAll(dir, 0755))
+	// revisions are written in order, a is the oldest and shares its file
+	// with the cache
+	for i, name := range []string{"a.tar.gz", "b.tar.gz", "c.tar.gz"} {
+		file := path.Join(dir, name)
+		utilruntime.Must(os.WriteFile(file, []byte(name), 0644))
+		mtime := time.Unix(int64(i*60), 0)
+		utilruntime.Must(os.Chtimes(file, mtime, mtime))
+	}
+	if err := cache.Put("a", path.Join(dir, "a.tar.gz")); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	// the cached file is reused by another resource
+	if err := cache.Link("a", path.Join(t.TempDir(), "a.tar.gz")); err != nil {
+		t.Fatalf("Link() unexpected error: %v", err)
+	}
+
+	if err := storage.Prune(context.TODO(), path.Join("imagerepository", "test-namespace", "my-image", "c.tar.gz"), nil); err != nil {
+		t.Fatalf("Prune() unexpected error: %v", err)
+	}
+	expected := []string{"b.tar.gz", "c.tar.gz"}
+	if diff := cmp.Diff(expected, listFiles(t, dir)); diff != "" {
+		t.Errorf("Prune() (-expected, +actual): %s", diff)
+	}
+}
+
+func TestArtifactStoragePruneAcrossFormats(t *testing.T) {
+	rootDir := t.TempDir()
+	dir := path.Join(rootDir, "imagerepository", "test-namespace", "my-image")
+	utilruntime.Must(os.MkdirAll(dir, 0755))
+	// the revision was repackaged as a zip, a is the oldest
+	for i, name := range []string{"a.tar.gz", "a.json", "b.tar.gz", "b.tar.gz.digest", "b.json", "b.zip", "b.zip.digest"} {
+		file := path.Join(dir, name)
+		utilruntime.Must(os.WriteFile(file, []byte(name), 0644))
+		mtime := time.Unix(int64(i*60), 0)
+		utilruntime.Must(os.Chtimes(file, mtime, mtime))
+	}
+
+	storage := &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: rootDir}, RevisionHistoryLimit: 1}
+	if err := storage.Prune(context.TODO(), path.Join("imagerepository", "test-namespace", "my-image", "b.zip"), nil); err != nil {
+		t.Fatalf("Prune() unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"b.json", "b.zip", "b.zip.digest"}, listFiles(t, dir)); diff != "" {
+		t.Errorf("Prune() (-expected, +actual): %s", diff)
+	}
+}
+
+func TestArtifactStoragePut(t *testing.T) {
+	rootDir := t.TempDir()
+	name := path.Join(t.TempDir(), "artifact.tar.gz")
+	utilruntime.Must(os.WriteFile(name, []byte("artifact"), 0644))
+
+	storage := &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: rootDir}}
+	key := "imagerepository/test-namespace/my-image/0123.tar.gz"
+	if err := storage.Put(context.TODO(), key, name, "sha256:4567"); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	for file, expected := range map[string]string{
+		key:             "artifact",
+		key + ".digest": "sha256:4567",
+	} {
+		actual, err := os.ReadFile(path.Join(rootDir, file))
+		if err != nil {
+			t.Fatalf("Put() expected %q to be stored: %v", file, err)
+		}
+		if string(actual) != expected {
+			t.Errorf("Put() expected %q to contain %q, got %q", file, expected, actual)
+		}
+	}
+	// the file is linked rather than copied within a filesystem
+	original, _ := os.Stat(name)
+	stored, _ := os.Stat(path.Join(rootDir, key))
+	if !os.SameFile(original, stored) {
+		t.Errorf("Put() expected %q to be hard linked", key)
+	}
+}
+
+func TestArtifactStorageStage(t *testing.T) {
+	rootDir := t.TempDir()
+	storage := &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: rootDir}}
+	key := "mavenartifact/test-namespace/my-artifact/0123.tar.gz"
+
+	artifact, err := storage.Stage(context.TODO(), key)
+	if err != nil {
+		t.Fatalf("Stage() unexpected error: %v", err)
+	}
+	defer artifact.Close()
+	// the artifact is written within the root directory
+	if !strings.HasPrefix(artifact.Name, rootDir) {
+		t.Errorf("Stage() expected %q to be within %q", artifact.Name, rootDir)
+	}
+	utilruntime.Must(os.WriteFile(artifact.Name, []byte("artifact"), 0644))
+	staged, _ := os.Stat(artifact.Name)
+	if err := artifact.Commit(context.TODO(), "sha256:4567"); err != nil {
+		t.Fatalf("Commit() unexpected error: %v", err)
+	}
+
+	for file, expected := range map[string]string{
+		key:             "artifact",
+		key + ".digest": "sha256:4567",
+	} {
+		actual, err := os.ReadFile(path.Join(rootDir, file))
+		if err != nil {
+			t.Fatalf("Commit() expected %q to be stored: %v", file, err)
+		}
+		if string(actual) != expected {
+			t.Errorf("Commit() expected %q to contain %q, got %q", file, expected, actual)
+		}
+	}
+	stored, _ := os.Stat(path.Join(rootDir, key))
+	if !os.SameFile(staged, stored) {
+		t.Errorf("Commit() expected %q to be moved", key)
+	}
+	if err := artifact.Close(); err != nil {
+		t.Errorf("Close() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path.Join(rootDir, key)); err != nil {
+		t.Errorf("Close() expected %q to remain stored: %v", key, err)
+	}
+
+	// an artifact closed without being committed is removed
+	discarded, err := storage.Stage(context.TODO(), key)
+	if err != nil {
+		t.Fatalf("Stage() unexpected error: %v", err)
+	}
+	utilruntime.Must(os.WriteFile(discarded.Name, []byte("discarded"), 0644))
+	if err := discarded.Close(); err != nil {
+		t.Errorf("Close() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(discarded.Name); !os.IsNotExist(err) {
+		t.Errorf("Close() expected %q to be removed", discarded.Name)
+	}
+	if actual, _ := os.ReadFile(path.Join(rootDir, key)); string(actual) != "artifact" {
+		t.Errorf("Close() expected %q to remain unchanged, got %q", key, actual)
+	}
+}
+
+func TestArtifactStoragePutManifest(t *testing.T) {
+	rootDir := t.TempDir()
+	storage := &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: rootDir}}
+	manifest := &controllers.ArtifactManifest{
+		Revision: "sha256:0123",
+		Files: []controllers.ArtifactManifestFile{
+			{Path: "dir/file.txt", Size: 5, Mode: "0644", Digest: "sha256:4567"},
+		},
+	}
+	if err := storage.PutManifest(context.TODO(), "imagerepository/test-namespace/my-image/0123.tar.gz", manifest); err != nil {
+		t.Fatalf("PutManifest() unexpected error: %v", err)
+	}
+
+	actual, err := os.ReadFile(path.Join(rootDir, "imagerepository/test-namespace/my-image/0123.json"))
+	if err != nil {
+		t.Fatalf("PutManifest() expected the manifest to be stored: %v", err)
+	}
+	expected := `{"revision":"sha256:0123","files":[{"path":"dir/file.txt","size":5,"mode":"0644","digest":"sha256:4567"}]}`
+	if string(actual) != expected {
+		t.Errorf("PutManifest() expected %s, got %s", expected, actual)
+	}
+}
+
+func TestArtifactStorageIndex(t *testing.T) {
+	rootDir := t.TempDir()
+	storage := &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{RootDir: rootDir}}
+	artifact := &sourcev1alpha1.Artifact{
+		Path:     "imagerepository/test-namespace/my-image/0123.tar.gz",
+		Revision: "sha256:0123",
+		Checksum: "4567",
+		Digest:   "sha256:89ab",
+	}
+	if err := storage.Index(context.TODO(), artifact); err != nil {
+		t.Fatalf("Index() unexpected error: %v", err)
+	}
+
+	index := path.Join(rootDir, "imagerepository/test-namespace/my-image/index.json")
+	actual, err := os.ReadFile(index)
+	if err != nil {
+		t.Fatalf("Index() expected the index to be stored: %v", err)
+	}
+	expected := `{"artifact":"0123.tar.gz","revision":"sha256:0123","checksum":"4567","digest":"sha256:89ab"}`
+	if string(actual) != expected {
+		t.Errorf("Index() expected %s, got %s", expected, actual)
+	}
+
+	// an unchanged index is not rewritten
+	modTime := time.Now().Add(-time.Hour)
+	utilruntime.Must(os.Chtimes(index, modTime, modTime))
+	if err := storage.Index(context.TODO(), artifact); err != nil {
+		t.Fatalf("Index() unexpected error: %v", err)
+	}
+	if info, err := os.Stat(index); err != nil || !info.ModTime().Equal(modTime) {
+		t.Errorf("Index() expected an unchanged index to not be rewritten")
+	}
+}
+
+func TestArtifactStorageSweep(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(sourcev1alpha1.AddToScheme(scheme))
+
+	rootDir := t.TempDir()
+	for _, dir := range []string{
+		"imagerepository/test-namespace/my-image",
+		"imagerepository/test-namespace/deleted-image",
+		"imagerepository/deleted-namespace/deleted-image",
+		"mavenartifact/test-namespace/my-artifact",
+		"mavenartifact/test-namespace/my-image",
+	} {
+		utilruntime.Must(os.MkdirAll(path.Join(rootDir, dir), 0755))
+		utilruntime.Must(os.WriteFile(path.Join(rootDir, dir, "0123.tar.gz"), []byte("artifact"), 0644))
+	}
+
+	storage := &controllers.ArtifactStorage{
+		Backend: &controllers.FilesystemBackend{RootDir: rootDir},
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(
+				&sourcev1alpha1.ImageRepository{ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "my-image"}},
+				&sourcev1alpha1.MavenArtifact{ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "my-artifact"}},
+			).
+			Build(),
+	}
+	if err := storage.Sweep(context.TODO()); err != nil {
+		t.Fatalf("Sweep() unexpected error: %v", err)
+	}
+
+	expected := []string{"my-image"}
+	if diff := cmp.Diff(expected, listFiles(t, path.Join(rootDir, "imagerepository", "test-namespace"))); diff != "" {
+		t.Errorf("Sweep() imagerepository (-expected, +actual): %s", diff)
+	}
+	if _, err := os.Stat(path.Join(rootDir, "imagerepository", "deleted-namespace")); !os.IsNotExist(err) {
+		t.Errorf("Sweep() expected empty namespace directory to be removed, got %v", err)
+	}
+	// artifacts are owned by a resource of the same kind
+	expected = []string{"my-artifact"}
+	if diff := cmp.Diff(expected, listFiles(t, path.Join(rootDir, "mavenartifact", "test-namespace"))); diff != "" {
+		t.Errorf("Sweep() mavenartifact (-expected, +actual): %s", diff)
+	}
+}
+
+func TestArtifactStorageURL(t *testing.T) {
+	ctx := context.TODO()
+	key := "imagerepository/test-namespace/my-image/0123.tar.gz"
+	backend := &controllers.FilesystemBackend{Host: "artifact.example", Scheme: "https"}
+
+	storage := &controllers.ArtifactStorage{Backend: backend}
+	url, err := storage.URL(ctx, key, "")
+	if err != nil {
+		t.Fatalf("URL() unexpected error: %v", err)
+	}
+	if expected := "https://artifact.example/" + key; url != expected {
+		t.Errorf("URL() expected %q, got %q", expected, url)
+	}
+	if interval := storage.URLRefreshInterval(); interval != 0 {
+		t.Errorf("URLRefreshInterval() expected 0 for unsigned URLs, got %v", interval)
+	}
+
+	now := time.Unix(1000, 0)
+	storage.URLSigner = &signedurl.Signer{
+		Keys: signingKeys{[]byte("key")},
+		TTL:  time.Hour,
+		Now:  func() time.Time { return now },
+	}
+	signed, err := storage.URL(ctx, key, url)
+	if err != nil {
+		t.Fatalf("URL() unexpected error: %v", err)
+	}
+	if expected := "https://artifact.example/" + key + "?expires=4600&signature="; !strings.HasPrefix(signed, expected) {
+		t.Errorf("URL() expected a signed URL prefixed with %q, got %q", expected, signed)
+	}
+	if interval := storage.URLRefreshInterval(); interval != 30*time.Minute {
+		t.Errorf("URLRefreshInterval() expected 30m, got %v", interval)
+	}
+
+	// the signed URL is reused until half its TTL has passed
+	now = now.Add(29 * time.Minute)
+	if reused, err := storage.URL(ctx, key, signed); err != nil || reused != signed {
+		t.Errorf("URL() expected the current URL %q to be reused, got %q (%v)", signed, reused, err)
+	}
+	now = now.Add(2 * time.Minute)
+	if refreshed, err := storage.URL(ctx, key, signed); err != nil || refreshed == signed {
+		t.Errorf("URL() expected the current URL %q to be refreshed, got %q (%v)", signed, refreshed, err)
+	}
+	// a URL for another artifact is replaced
+	if replaced, err := storage.URL(ctx, "imagerepository/test-namespace/my-image/4567.tar.gz", signed); err != nil || replaced == signed {
+		t.Errorf("URL() expected a URL for another artifact to be replaced, got %q (%v)", replaced, err)
+	}
+}
+
+func TestArtifactStorageLatestURL(t *testing.T) {
+	ctx := context.Background()
+	artifact := &sourcev1alpha1.Artifact{Path: "imagerepository/test-namespace/my-image/0123.tar.gz"}
+
+	storage := &controllers.ArtifactStorage{Backend: &controllers.FilesystemBackend{Host: "artifact.example", Scheme: "https"}}
+	if url, err := storage.LatestURL(ctx, artifact, ""); err != nil || url != "" {
+		t.Errorf("LatestURL() expected no URL when URLs are not signed, got %q (%v)", url, err)
+	}
+
+	storage.URLSigner = &signedurl.Signer{Keys: signingKeys{[]byte("key")}, TTL: time.Hour}
+	url, err := storage.LatestURL(ctx, artifact, "")
+	if err != nil {
+		t.Fatalf("LatestURL() unexpected error: %v", err)
+	}
+	if expected := "https://artifact.example/imagerepository/test-namespace/my-image/latest.tar.gz?expires="; !strings.HasPrefix(url, expected) {
+		t.Errorf("LatestURL() expected a signed URL prefixed with %q, got %q", expected, url)
+	}
+	if reused, err := storage.LatestURL(ctx, artifact, url); err != nil || reused != url {
+		t.Errorf("LatestURL() expected the current URL %q to be reused, got %q (%v)", url, reused, err)
+	}
+
+	// the alias is not served from S3
+	backend, err := controllers.NewS3Backend(controllers.S3Options{Endpoint: "s3.example", Bucket: "artifacts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.Backend = backend
+	if url, err := storage.LatestURL(ctx, artifact, ""); err != nil || url != "" {
+		t.Errorf("LatestURL() expected no URL for S3, got %q (%v)", url, err)
+	}
+}
+
+type signingKeys [][]byte
+
+func (k signingKeys) Keys(ctx context.Context) ([][]byte, error) {
+	return k, nil
+}
+
+func listFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unable to read directory %q: %v", dir, err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
@@ -18,6 +18,8 @@ package controllers
 
 import (
 	"archive/zip"
+	"errors"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
@@ -58,7 +60,7 @@ func TestExtractArchiveRejectsZipSlip(t *testing.T) {
 			parentDir := t.TempDir()
 			zipPath := writeTestZip(t, parentDir, tc.entryName, "pwned")
 
-			if _, err := extractArchive(parentDir, zipPath); err == nil {
+			if _, err := extractArchive(parentDir, zipPath, ArtifactLimits{}); err == nil {
 				t.Fatalf("expected extractArchive to reject entry %q, got nil error", tc.entryName)
 			}
 
@@ -82,7 +84,7 @@ func TestExtractArchiveWellBehavedEntries(t *testing.T) {
 		{name: "com/example/Foo.class", contents: "fake-class-bytes"},
 	})
 
-	extractedDir, err := extractArchive(parentDir, zipPath)
+	extractedDir, err := extractArchive(parentDir, zipPath, ArtifactLimits{})
 	if err != nil {
 		t.Fatalf("unexpected error extracting well-behaved archive: %v", err)
 	}
@@ -94,9 +96,143 @@ func TestExtractArchiveWellBehavedEntries(t *testing.T) {
 	}
 }
 
+// TestExtractArchiveSymlinks recreates symlinks within the destination
+// directory and rejects those leading out of it, along with entries written
+// through them.
+func TestExtractArchiveSymlinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []zipEntry
+		unsafe  bool
+	}{
+		{
+			name: "symlink within the archive",
+			entries: []zipEntry{
+				{name: "lib/"},
+				{name: "lib/app.jar", contents: "jar"},
+				{name: "app.jar", contents: "lib/app.jar", mode: fs.ModeSymlink | 0o777},
+			},
+		},
+		{
+			name: "symlink to a parent directory",
+			entries: []zipEntry{
+				{name: "evil", contents: "../..", mode: fs.ModeSymlink | 0o777},
+			},
+			unsafe: true,
+		},
+		{
+			name: "symlink to an absolute path",
+			entries: []zipEntry{
+				{name: "evil", contents: "/etc", mode: fs.ModeSymlink | 0o777},
+			},
+			unsafe: true,
+		},
+		{
+			name: "entry written through a chained symlink",
+			entries: []zipEntry{
+				{name: "sub/"},
+				{name: "sub/parent", contents: "..", mode: fs.ModeSymlink | 0o777},
+				{name: "evil", contents: "sub/parent/..", mode: fs.ModeSymlink | 0o777},
+				{name: "evil/pwned.txt", contents: "pwned"},
+			},
+			unsafe: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parentDir := t.TempDir()
+			zipPath := writeTestZipMulti(t, parentDir, tc.entries)
+
+			extractedDir, err := extractArchive(parentDir, zipPath, ArtifactLimits{})
+			if tc.unsafe {
+				if !errors.Is(err, errUnsafeSymlink) {
+					t.Fatalf("expected an unsafe symlink error, got %v", err)
+				}
+				if _, err := os.Stat(filepath.Join(parentDir, "pwned.txt")); !os.IsNotExist(err) {
+					t.Fatalf("archive entry escaped destination directory")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error extracting archive: %v", err)
+			}
+			if target, err := os.Readlink(filepath.Join(extractedDir, "app.jar")); err != nil || target != "lib/app.jar" {
+				t.Fatalf("expected symlink to %q, got %q: %v", "lib/app.jar", target, err)
+			}
+		})
+	}
+}
+
+// TestExtractArchiveLimits covers archives expanding beyond the limits, such
+// as a zip bomb, which must fail before the content is fully written.
+func TestExtractArchiveLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   ArtifactLimits
+		entries  []zipEntry
+		tooLarge bool
+	}{
+		{
+			name:   "within the limits",
+			limits: ArtifactLimits{MaxSize: 10, MaxFiles: 2},
+			entries: []zipEntry{
+				{name: "a.txt", contents: "01234"},
+				{name: "b.txt", contents: "56789"},
+			},
+		},
+		{
+			name:   "too many files",
+			limits: ArtifactLimits{MaxFiles: 2},
+			entries: []zipEntry{
+				{name: "lib/"},
+				{name: "lib/a.txt", contents: "a"},
+				{name: "lib/b.txt", contents: "b"},
+			},
+			tooLarge: true,
+		},
+		{
+			name:   "too large",
+			limits: ArtifactLimits{MaxSize: 1 << 20},
+			entries: []zipEntry{
+				{name: "bomb.txt", contents: strings.Repeat("0", 1<<21)},
+			},
+			tooLarge: true,
+		},
+		{
+			name:   "too large across files",
+			limits: ArtifactLimits{MaxSize: 9},
+			entries: []zipEntry{
+				{name: "a.txt", contents: "01234"},
+				{name: "b.txt", contents: "56789"},
+			},
+			tooLarge: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parentDir := t.TempDir()
+			zipPath := writeTestZipMulti(t, parentDir, tc.entries)
+
+			_, err := extractArchive(parentDir, zipPath, tc.limits)
+			if tc.tooLarge {
+				if !errors.Is(err, errArtifactTooLarge) {
+					t.Fatalf("expected an artifact too large error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error extracting archive: %v", err)
+			}
+		})
+	}
+}
+
 type zipEntry struct {
 	name     string
 	contents string
+	mode     fs.FileMode
 }
 
 func writeTestZip(t *testing.T, dir, entryName, contents string) string {
@@ -120,7 +256,11 @@ func writeTestZipMulti(t *testing.T, dir string, entries []zipEntry) string {
 
 	zipWriter := zip.NewWriter(zipFile)
 	for _, entry := range entries {
-		w, err := zipWriter.Create(entry.name)
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		if entry.mode != 0 {
+			header.SetMode(entry.mode)
+		}
+		w, err := zipWriter.CreateHeader(header)
 		if err != nil {
 			t.Fatalf("failed to create zip entry %q: %v", entry.name, err)
 		}
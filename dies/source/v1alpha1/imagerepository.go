@@ -64,4 +64,5 @@ var (
 	ImageRepositoryConditionArtifactAvailableBlank = diemetav1.ConditionBlank.Type(sourcev1alpha1.ImageRepositoryConditionArtifactAvailable)
 	ImageRepositoryConditionImageResolvedBlank     = diemetav1.ConditionBlank.Type(sourcev1alpha1.ImageRepositoryConditionImageResolved)
 	ImageRepositoryConditionReadyBlank             = diemetav1.ConditionBlank.Type(sourcev1alpha1.ImageRepositoryConditionReady)
+	ImageRepositoryConditionSignatureVerifiedBlank = diemetav1.ConditionBlank.Type(sourcev1alpha1.ImageRepositoryConditionSignatureVerified)
 )
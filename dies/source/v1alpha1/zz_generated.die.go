@@ -614,13 +614,37 @@ func (d *ImageRepositorySpecDie) DiePatch(patchType types.PatchType) ([]byte, er
 	return patch.Create(d.seal, d.r, patchType)
 }
 
-// Image is a reference to an image in a remote repository
+// Image is a reference to an image in a remote repository. When TagPolicy
+//
+// is set, Image must name the repository without a tag or digest.
 func (d *ImageRepositorySpecDie) Image(v string) *ImageRepositorySpecDie {
 	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
 		r.Image = v
 	})
 }
 
+// TagPolicy selects the tag to resolve from the tags available in the
+//
+// image repository, rather than using a fixed tag or digest.
+func (d *ImageRepositorySpecDie) TagPolicy(v *sourcev1alpha1.ImageTagPolicy) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.TagPolicy = v
+	})
+}
+
+// Platform selects the image for a platform, such as "linux/arm64" or
+//
+// "linux/arm/v7", when Image resolves to a multi-platform image index. The
+//
+// digests of both the index and the selected image are recorded in the
+//
+// artifact revision. Images that are not an index are used as is.
+func (d *ImageRepositorySpecDie) Platform(v string) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.Platform = v
+	})
+}
+
 // The interval at which to check for repository updates.
 func (d *ImageRepositorySpecDie) Interval(v metav1.Duration) *ImageRepositorySpecDie {
 	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
@@ -648,6 +672,90 @@ func (d *ImageRepositorySpecDie) ServiceAccountName(v string) *ImageRepositorySp
 	})
 }
 
+// Verify requires the resolved image to carry a trusted cosign signature
+//
+// before it is pulled and packaged as an artifact.
+func (d *ImageRepositorySpecDie) Verify(v *sourcev1alpha1.ImageVerification) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.Verify = v
+	})
+}
+
+// RevisionHistoryLimit is the number of artifact revisions to retain,
+//
+// including the current revision. Older revisions are removed from the
+//
+// artifact server. Defaults to the limit configured for the controller.
+func (d *ImageRepositorySpecDie) RevisionHistoryLimit(v *int32) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.RevisionHistoryLimit = v
+	})
+}
+
+// Format is the archive format the source is packaged as, one of
+//
+// "tar.gz", "tar.zst" or "zip". Defaults to "tar.gz".
+func (d *ImageRepositorySpecDie) Format(v sourcev1alpha1.ArtifactFormat) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.Format = v
+	})
+}
+
+// Limits bounds the content fetched and extracted to produce the
+//
+// artifact, exceeding a limit fails the artifact with the
+//
+// ArtifactTooLarge reason.
+func (d *ImageRepositorySpecDie) Limits(v *sourcev1alpha1.ArtifactLimits) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.Limits = v
+	})
+}
+
+// Path is a directory within the image whose content is packaged in place
+//
+// of the whole image. Include and Ignore patterns are relative to it.
+func (d *ImageRepositorySpecDie) Path(v string) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.Path = v
+	})
+}
+
+// Include lists gitignore-style patterns selecting the files packaged
+//
+// from the image, files within a matching directory are also selected.
+//
+// All files are selected when empty.
+func (d *ImageRepositorySpecDie) Include(v ...string) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.Include = v
+	})
+}
+
+// Ignore lists gitignore-style patterns, like those of a .gitignore or
+//
+// .sourceignore file, excluding files from the artifact. Patterns
+//
+// prefixed with "!" select files excluded by an earlier pattern.
+func (d *ImageRepositorySpecDie) Ignore(v ...string) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.Ignore = v
+	})
+}
+
+// LayerSelector selects a single layer of the image to package, such as
+//
+// the content layer of an OCI artifact. All layers of the image are
+//
+// flattened, later layers replacing the files of earlier layers, when not
+//
+// set.
+func (d *ImageRepositorySpecDie) LayerSelector(v *sourcev1alpha1.ImageLayerSelector) *ImageRepositorySpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositorySpec) {
+		r.LayerSelector = v
+	})
+}
+
 var ImageRepositoryStatusBlank = (&ImageRepositoryStatusDie{}).DieFeed(sourcev1alpha1.ImageRepositoryStatus{})
 
 type ImageRepositoryStatusDie struct {
@@ -891,6 +999,19 @@ func (d *ImageRepositoryStatusDie) URL(v string) *ImageRepositoryStatusDie {
 	})
 }
 
+// LatestURL is the signed download link for the latest alias of the
+//
+// artifact, which always resolves to the current artifact. Set only when
+//
+// artifact URLs are signed and served by the controller, the alias is not
+//
+// served from S3.
+func (d *ImageRepositoryStatusDie) LatestURL(v string) *ImageRepositoryStatusDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositoryStatus) {
+		r.LatestURL = v
+	})
+}
+
 // Artifact represents the output of the last successful repository sync.
 func (d *ImageRepositoryStatusDie) Artifact(v *sourcev1alpha1.Artifact) *ImageRepositoryStatusDie {
 	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositoryStatus) {
@@ -898,6 +1019,15 @@ func (d *ImageRepositoryStatusDie) Artifact(v *sourcev1alpha1.Artifact) *ImageRe
 	})
 }
 
+// Tag is the image tag selected by the tag policy during the last
+//
+// repository sync.
+func (d *ImageRepositoryStatusDie) Tag(v string) *ImageRepositoryStatusDie {
+	return d.DieStamp(func(r *sourcev1alpha1.ImageRepositoryStatus) {
+		r.Tag = v
+	})
+}
+
 var ArtifactBlank = (&ArtifactDie{}).DieFeed(sourcev1alpha1.Artifact{})
 
 type ArtifactDie struct {
@@ -1158,6 +1288,17 @@ func (d *ArtifactDie) Checksum(v string) *ArtifactDie {
 	})
 }
 
+// Digest is the digest of the artifact in the form of
+//
+// '<algorithm>:<checksum>', for example 'sha256:<checksum>'. Unlike
+//
+// Checksum, the algorithm is SHA-256 or SHA-512.
+func (d *ArtifactDie) Digest(v string) *ArtifactDie {
+	return d.DieStamp(func(r *sourcev1alpha1.Artifact) {
+		r.Digest = v
+	})
+}
+
 // LastUpdateTime is the timestamp corresponding to the last update of this
 //
 // artifact.
@@ -1770,6 +1911,50 @@ func (d *MavenArtifactSpecDie) Timeout(v *metav1.Duration) *MavenArtifactSpecDie
 	})
 }
 
+// TrustedKeysSecretRef can be given the name of a secret containing
+//
+// ASCII armored OpenPGP public keys. When set, the detached signature
+//
+// published at '<artifact>.asc' must be signed by one of the keys before
+//
+// the artifact is made available.
+func (d *MavenArtifactSpecDie) TrustedKeysSecretRef(v *corev1.LocalObjectReference) *MavenArtifactSpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.MavenArtifactSpec) {
+		r.TrustedKeysSecretRef = v
+	})
+}
+
+// RevisionHistoryLimit is the number of artifact revisions to retain,
+//
+// including the current revision. Older revisions are removed from the
+//
+// artifact server. Defaults to the limit configured for the controller.
+func (d *MavenArtifactSpecDie) RevisionHistoryLimit(v *int32) *MavenArtifactSpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.MavenArtifactSpec) {
+		r.RevisionHistoryLimit = v
+	})
+}
+
+// Format is the archive format the source is packaged as, one of
+//
+// "tar.gz", "tar.zst" or "zip". Defaults to "tar.gz".
+func (d *MavenArtifactSpecDie) Format(v sourcev1alpha1.ArtifactFormat) *MavenArtifactSpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.MavenArtifactSpec) {
+		r.Format = v
+	})
+}
+
+// Limits bounds the content fetched and extracted to produce the
+//
+// artifact, exceeding a limit fails the artifact with the
+//
+// ArtifactTooLarge reason.
+func (d *MavenArtifactSpecDie) Limits(v *sourcev1alpha1.ArtifactLimits) *MavenArtifactSpecDie {
+	return d.DieStamp(func(r *sourcev1alpha1.MavenArtifactSpec) {
+		r.Limits = v
+	})
+}
+
 var MavenArtifactStatusBlank = (&MavenArtifactStatusDie{}).DieFeed(sourcev1alpha1.MavenArtifactStatus{})
 
 type MavenArtifactStatusDie struct {
@@ -2013,6 +2198,19 @@ func (d *MavenArtifactStatusDie) URL(v string) *MavenArtifactStatusDie {
 	})
 }
 
+// LatestURL is the signed download link for the latest alias of the
+//
+// artifact, which always resolves to the current artifact. Set only when
+//
+// artifact URLs are signed and served by the controller, the alias is not
+//
+// served from S3.
+func (d *MavenArtifactStatusDie) LatestURL(v string) *MavenArtifactStatusDie {
+	return d.DieStamp(func(r *sourcev1alpha1.MavenArtifactStatus) {
+		r.LatestURL = v
+	})
+}
+
 // Artifact represents the output of the last successful repository sync.
 func (d *MavenArtifactStatusDie) Artifact(v *sourcev1alpha1.Artifact) *MavenArtifactStatusDie {
 	return d.DieStamp(func(r *sourcev1alpha1.MavenArtifactStatus) {
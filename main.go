@@ -7,7 +7,9 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -16,8 +18,10 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"reconciler.io/runtime/reconcilers"
@@ -26,11 +30,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	sourcev1alpha1 "github.com/vmware-tanzu/tanzu-source-controller/apis/source/v1alpha1"
 	"github.com/vmware-tanzu/tanzu-source-controller/controllers"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/signedurl"
 	"github.com/vmware-tanzu/tanzu-source-controller/server"
 	//+kubebuilder:scaffold:imports
 )
@@ -58,6 +65,23 @@ func main() {
 	var artifactRootDir string
 	var artifactHost string
 	var caCertPath string
+	var artifactDigestAlgorithm string
+	var mavenChecksumFloor string
+	var artifactRevisionHistoryLimit int
+	var artifactGCInterval time.Duration
+	var artifactStorage string
+	var s3Options controllers.S3Options
+	var artifactTLSCertFile string
+	var artifactTLSKeyFile string
+	var artifactRequireToken bool
+	var artifactTokenAudience string
+	var artifactURLSigningSecret string
+	var artifactURLTTL time.Duration
+	var artifactMaxCompressedSize string
+	var artifactMaxSize string
+	var artifactMaxFiles int64
+	var cacheDir string
+	var cacheMaxSize string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":0", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -67,6 +91,28 @@ func main() {
 	flag.StringVar(&artifactRootDir, "artifact-root-directory", "./artifact-root", "The directory to stash and serve artifacts from.")
 	flag.StringVar(&artifactHost, "artifact-host", "localhost:8082", "The host name to use when constructing artifact urls.")
 	flag.StringVar(&caCertPath, "ca-cert-path", "", "The path to addition CA certificates.")
+	flag.StringVar(&artifactDigestAlgorithm, "artifact-digest-algorithm", string(controllers.SHA256), "The hash algorithm used to compute artifact digests, either sha256 or sha512.")
+	flag.StringVar(&mavenChecksumFloor, "maven-checksum-floor", string(controllers.SHA1), "The weakest checksum algorithm accepted to verify Maven artifact downloads, one of sha512, sha256, sha1 or md5.")
+	flag.IntVar(&artifactRevisionHistoryLimit, "artifact-revision-history-limit", 2, "The number of artifact revisions to retain for each resource that does not set its own limit. Zero retains every revision.")
+	flag.DurationVar(&artifactGCInterval, "artifact-gc-interval", time.Hour, "How often to remove the artifacts of resources that no longer exist. Zero disables the removal.")
+	flag.StringVar(&artifactStorage, "artifact-storage", "filesystem", "Where artifacts are stored, either filesystem to serve them from the artifact root directory or s3 to store them in an S3 compatible bucket.")
+	flag.StringVar(&s3Options.Endpoint, "artifact-s3-endpoint", "s3.amazonaws.com", "The host, and optional port, of the S3 compatible object store.")
+	flag.StringVar(&s3Options.Bucket, "artifact-s3-bucket", "", "The S3 bucket to store artifacts in.")
+	flag.StringVar(&s3Options.Prefix, "artifact-s3-prefix", "", "The prefix prepended to the name of each artifact stored in the S3 bucket.")
+	flag.StringVar(&s3Options.Region, "artifact-s3-region", "", "The region of the S3 bucket, discovered from the object store when empty.")
+	flag.BoolVar(&s3Options.Insecure, "artifact-s3-insecure", false, "Connect to the S3 compatible object store over HTTP rather than HTTPS.")
+	flag.StringVar(&s3Options.URLBase, "artifact-s3-url", "", "The URL the S3 bucket is served from when constructing artifact urls. Defaults to the bucket at the S3 endpoint.")
+	flag.StringVar(&artifactTLSCertFile, "artifact-tls-cert-file", "", "The certificate the artifact server serves HTTPS with, reloaded when updated. Artifacts are served over HTTP when empty.")
+	flag.StringVar(&artifactTLSKeyFile, "artifact-tls-key-file", "", "The private key of the artifact server's certificate, reloaded when updated.")
+	flag.BoolVar(&artifactRequireToken, "artifact-require-token", false, "Require artifact downloads to present a bearer token accepted by the Kubernetes TokenReview API.")
+	flag.StringVar(&artifactTokenAudience, "artifact-token-audience", "", "The audience bearer tokens must be issued for when tokens are required. Any audience accepted by the API Server when empty.")
+	flag.StringVar(&artifactURLSigningSecret, "artifact-url-signing-secret", "", "The Secret, in the form of '<namespace>/<name>', holding the key artifact urls are signed with. Artifact urls are not signed when empty.")
+	flag.DurationVar(&artifactURLTTL, "artifact-url-ttl", time.Hour, "How long signed artifact urls are valid for, urls are refreshed before they expire.")
+	flag.StringVar(&artifactMaxCompressedSize, "artifact-max-compressed-size", "0", "The largest size of the content downloaded for an artifact, such as the layers of an image, for example 500Mi. Zero is unlimited.")
+	flag.StringVar(&artifactMaxSize, "artifact-max-size", "0", "The largest total size of the files extracted from the content downloaded for an artifact, for example 2Gi. Zero is unlimited.")
+	flag.Int64Var(&artifactMaxFiles, "artifact-max-files", 0, "The largest number of files extracted from the content downloaded for an artifact. Zero is unlimited.")
+	flag.StringVar(&cacheDir, "cache-directory", "", "The directory to cache downloaded image layers and packaged artifacts in, shared by resources fetching the same content. Nothing is cached when empty.")
+	flag.StringVar(&cacheMaxSize, "cache-max-size", "5Gi", "The largest total size of the files in the cache directory, the least recently used files are removed beyond it.")
 	opts := zap.Options{
 		Development: false,
 		TimeEncoder: zapcore.RFC3339NanoTimeEncoder,
@@ -76,6 +122,62 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	digestAlgorithm, err := controllers.ParseDigestAlgorithm(artifactDigestAlgorithm)
+	if err != nil {
+		setupLog.Error(err, "invalid artifact digest algorithm")
+		os.Exit(1)
+	}
+	checksumFloor, err := controllers.ParseChecksumFloor(mavenChecksumFloor)
+	if err != nil {
+		setupLog.Error(err, "invalid Maven checksum floor")
+		os.Exit(1)
+	}
+	maxCompressedSize, err := resource.ParseQuantity(artifactMaxCompressedSize)
+	if err != nil {
+		setupLog.Error(err, "invalid artifact max compressed size")
+		os.Exit(1)
+	}
+	maxSize, err := resource.ParseQuantity(artifactMaxSize)
+	if err != nil {
+		setupLog.Error(err, "invalid artifact max size")
+		os.Exit(1)
+	}
+	var blobCache *blobcache.Cache
+	if cacheDir != "" {
+		size, err := resource.ParseQuantity(cacheMaxSize)
+		if err != nil {
+			setupLog.Error(err, "invalid cache max size")
+			os.Exit(1)
+		}
+		blobCache, err = blobcache.New(cacheDir, size.Value())
+		if err != nil {
+			setupLog.Error(err, "unable to open cache directory")
+			os.Exit(1)
+		}
+		metrics.Registry.MustRegister(controllers.CacheCollector(blobCache))
+	}
+	var urlSigningSecret types.NamespacedName
+	if artifactURLSigningSecret != "" {
+		namespace, name, ok := strings.Cut(artifactURLSigningSecret, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Error(fmt.Errorf("expected '<namespace>/<name>', got %q", artifactURLSigningSecret), "invalid artifact url signing secret")
+			os.Exit(1)
+		}
+		if artifactStorage != "filesystem" {
+			setupLog.Error(fmt.Errorf("artifact urls are only signed for the filesystem artifact storage"), "invalid artifact url signing secret")
+			os.Exit(1)
+		}
+		if artifactURLTTL <= 0 {
+			setupLog.Error(fmt.Errorf("--artifact-url-ttl must be positive, got %s", artifactURLTTL), "invalid artifact url ttl")
+			os.Exit(1)
+		}
+		urlSigningSecret = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	if (artifactTLSCertFile == "") != (artifactTLSKeyFile == "") {
+		setupLog.Error(fmt.Errorf("--artifact-tls-cert-file and --artifact-tls-key-file must be set together"), "invalid artifact server TLS configuration")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -108,9 +210,57 @@ func main() {
 
 	certs := []controllers.Cert{{Path: caCertPath}}
 
+	var backend controllers.ArtifactBackend
+	switch artifactStorage {
+	case "filesystem":
+		filesystem := &controllers.FilesystemBackend{RootDir: artifactRootDir, Host: artifactHost}
+		if artifactTLSCertFile != "" {
+			filesystem.Scheme = "https"
+		}
+		backend = filesystem
+	case "s3":
+		backend, err = controllers.NewS3Backend(s3Options)
+		if err != nil {
+			setupLog.Error(err, "unable to create S3 artifact storage")
+			os.Exit(1)
+		}
+	default:
+		setupLog.Error(fmt.Errorf("unknown artifact storage %q, expected filesystem or s3", artifactStorage), "invalid artifact storage")
+		os.Exit(1)
+	}
+
+	storage := &controllers.ArtifactStorage{
+		Backend:              backend,
+		RevisionHistoryLimit: artifactRevisionHistoryLimit,
+		Client:               mgr.GetAPIReader(),
+		SweepInterval:        artifactGCInterval,
+		Limits: controllers.ArtifactLimits{
+			MaxCompressedSize: maxCompressedSize.Value(),
+			MaxSize:           maxSize.Value(),
+			MaxFiles:          artifactMaxFiles,
+		},
+		Cache: blobCache,
+	}
+	if artifactURLSigningSecret != "" {
+		storage.URLSigner = &signedurl.Signer{
+			Keys: &signedurl.SecretKeySource{
+				Client:          mgr.GetAPIReader(),
+				Secret:          urlSigningSecret,
+				RefreshInterval: time.Minute,
+			},
+			TTL: artifactURLTTL,
+		}
+	}
+	if artifactGCInterval > 0 {
+		if err := mgr.Add(storage); err != nil {
+			setupLog.Error(err, "unable to set up artifact garbage collection")
+			os.Exit(1)
+		}
+	}
+
 	if err = controllers.ImageRepositoryReconciler(
 		reconcilers.NewConfig(mgr, &sourcev1alpha1.ImageRepository{}, syncPeriod),
-		artifactRootDir, artifactHost, metav1.Now, certs,
+		storage, digestAlgorithm, metav1.Now, certs,
 	).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ImageRepository")
 		os.Exit(1)
@@ -118,8 +268,9 @@ func main() {
 
 	if err = controllers.MavenArtifactReconciler(
 		reconcilers.NewConfig(mgr, &sourcev1alpha1.MavenArtifact{}, syncPeriod),
-		artifactRootDir,
-		artifactHost,
+		storage,
+		digestAlgorithm,
+		checksumFloor,
 		metav1.Now,
 		certs,
 	).SetupWithManager(ctx, mgr); err != nil {
@@ -145,8 +296,23 @@ func main() {
 		os.Exit(1)
 	}
 
-	// http blob server for artifacts
-	mgr.Add(server.New(artifactAddr, artifactRootDir))
+	// http blob server for artifacts, the object store serves artifacts it stores
+	if artifactStorage == "filesystem" {
+		artifactServer := server.New(artifactAddr, artifactRootDir)
+		artifactServer.CertFile = artifactTLSCertFile
+		artifactServer.KeyFile = artifactTLSKeyFile
+		artifactServer.URLSigner = storage.URLSigner
+		if artifactRequireToken {
+			authenticator := &server.TokenReviewAuthenticator{Client: mgr.GetClient()}
+			if artifactTokenAudience != "" {
+				authenticator.Audiences = []string{artifactTokenAudience}
+			}
+			artifactServer.Authenticator = authenticator
+			// users may only download the artifacts of resources they may get
+			artifactServer.Authorizer = &server.SubjectAccessReviewAuthorizer{Client: mgr.GetClient(), Resources: controllers.ArtifactResources}
+		}
+		mgr.Add(artifactServer)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctx); err != nil {
@@ -0,0 +1,325 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package blobcache keeps files on disk by key, evicting the least recently
+// used files once their total size exceeds a limit.
+package blobcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempPrefix marks files being written, they are not part of the cache until
+// committed
+const tempPrefix = ".tmp-"
+
+// Cache is a directory of files addressed by key. Keys are typically the
+// digest of the content, a file is never modified once committed. The order
+// in which files are used is only tracked in memory, files may be hard linked
+// elsewhere and their modification time is left untouched. Files committed by
+// a previous process are reused, ordered by their modification time, the
+// time they were written.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	m       sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+	evicted Eviction
+}
+
+// Eviction counts the files evicted from a cache
+type Eviction struct {
+	// Files is the number of files evicted
+	Files int64
+	// Bytes is the total size of the files evicted
+	Bytes int64
+}
+
+type entry struct {
+	name string
+	size int64
+}
+
+// New opens the cache in the directory, creating the directory when missing.
+// Files are evicted once their total size exceeds maxSize bytes.
+func New(dir string, maxSize int64) (*Cache, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("cache size must be greater than zero")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type existing struct {
+		entry
+		modTime time.Time
+	}
+	files := []existing{}
+	for _, d := range dirEntries {
+		if d.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(d.Name(), tempPrefix) {
+			// left behind by an interrupted write
+			if err := os.Remove(filepath.Join(dir, d.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, existing{entry: entry{name: d.Name(), size: info.Size()}, modTime: info.ModTime()})
+	}
+	// most recently used first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	for _, f := range files {
+		c.entries[f.name] = c.lru.PushBack(&f.entry)
+		c.size += f.size
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c, c.evict()
+}
+
+// Size returns the total size in bytes of the files in the cache
+func (c *Cache) Size() int64 {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.size
+}
+
+// Len returns the number of files in the cache
+func (c *Cache) Len() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.lru.Len()
+}
+
+// Evicted returns the files evicted since the cache was opened
+func (c *Cache) Evicted() Eviction {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.evicted
+}
+
+// Open opens the file for the key, returning an error satisfying
+// errors.Is(err, fs.ErrNotExist) when the key is not cached. The file remains
+// readable when evicted while open.
+func (c *Cache) Open(key string) (*os.File, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	name := c.name(key)
+	if _, ok := c.entries[name]; !ok {
+		return nil, fmt.Errorf("%w: %q is not cached", fs.ErrNotExist, key)
+	}
+	file, err := os.Open(filepath.Join(c.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	c.touch(name)
+	return file, nil
+}
+
+// Link makes the file for the key available at dst, hard linking it when
+// the cache and dst share a filesystem and copying it otherwise. An error
+// satisfying errors.Is(err, fs.ErrNotExist) is returned when the key is not
+// cached.
+func (c *Cache) Link(key, dst string) error {
+	file, err := c.Open(key)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := os.Link(file.Name(), dst); err == nil {
+		return nil
+	}
+	// copied from the open file, which remains readable when evicted
+	return copyTo(dst, file)
+}
+
+// Create returns a writer for the file of the key. The file is added to the
+// cache once committed, it is discarded when closed without being committed.
+func (c *Cache) Create(key string) (*Writer, error) {
+	file, err := os.CreateTemp(c.dir, tempPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{File: file, cache: c, name: c.name(key)}, nil
+}
+
+// Writer writes a file to the cache
+type Writer struct {
+	*os.File
+	cache     *Cache
+	name      string
+	committed bool
+}
+
+// Commit adds the file written to the cache, replacing a file cached for the
+// same key. Files larger than the cache are discarded.
+func (w *Writer) Commit() error {
+	if err := w.File.Close(); err != nil {
+		return err
+	}
+	if err := w.cache.add(w.File.Name(), w.name); err != nil {
+		return err
+	}
+	w.committed = true
+	return nil
+}
+
+// Close discards the file unless it was committed
+func (w *Writer) Close() error {
+	if w.committed {
+		return nil
+	}
+	// the file may already be closed by a failed commit
+	_ = w.File.Close()
+	if err := os.Remove(w.File.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Put adds the file at src to the cache for the key, hard linking it when the
+// cache and src share a filesystem and copying it otherwise. The file at src
+// must not be modified once added.
+func (c *Cache) Put(key, src string) error {
+	file, err := os.CreateTemp(c.dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	tmp := file.Name()
+	// a no-op once the file is added
+	defer os.Remove(tmp)
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(tmp); err != nil {
+		return err
+	}
+	if err := os.Link(src, tmp); err != nil {
+		if err := copyFile(src, tmp); err != nil {
+			return err
+		}
+	}
+	return c.add(tmp, c.name(key))
+}
+
+// add moves the file at tmp into the cache under the name, evicting the least
+// recently used files as needed
+func (c *Cache) add(tmp, name string) error {
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return err
+	}
+	if info.Size() > c.maxSize {
+		return os.Remove(tmp)
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+	if err := os.Rename(tmp, filepath.Join(c.dir, name)); err != nil {
+		return err
+	}
+	if element, ok := c.entries[name]; ok {
+		c.size -= element.Value.(*entry).size
+		c.lru.Remove(element)
+	}
+	c.entries[name] = c.lru.PushFront(&entry{name: name, size: info.Size()})
+	c.size += info.Size()
+	return c.evict()
+}
+
+// name is the file name of the key, keys are hashed as they may contain
+// characters that are not valid in a file name
+func (c *Cache) name(key string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
+
+// touch marks the file as the most recently used
+func (c *Cache) touch(name string) {
+	c.lru.MoveToFront(c.entries[name])
+}
+
+// evict removes the least recently used files until the size of the cache is
+// within the limit. The caller must hold the lock.
+func (c *Cache) evict() error {
+	for c.size > c.maxSize {
+		element := c.lru.Back()
+		e := element.Value.(*entry)
+		if err := os.Remove(filepath.Join(c.dir, e.name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		c.lru.Remove(element)
+		delete(c.entries, e.name)
+		c.size -= e.size
+		c.evicted.Files++
+		c.evicted.Bytes += e.size
+	}
+	return nil
+}
+
+// copyFile copies the file at src to a new file at dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return copyTo(dst, in)
+}
+
+// copyTo writes the content of r to a new file at dst
+func copyTo(dst string, r io.Reader) error {
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, r); err != nil {
+		return err
+	}
+	return out.Close()
+}
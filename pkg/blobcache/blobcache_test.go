@@ -0,0 +1,243 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blobcache_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/blobcache"
+)
+
+func put(t *testing.T, c *blobcache.Cache, key, content string) {
+	t.Helper()
+	w, err := c.Create(key)
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Commit(); err != nil {
+		t.Fatalf("Commit() unexpected error: %v", err)
+	}
+}
+
+func get(t *testing.T, c *blobcache.Cache, key string) (string, bool) {
+	t.Helper()
+	file, err := c.Open(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false
+	}
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content), true
+}
+
+func TestCache(t *testing.T) {
+	c, err := blobcache.New(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	put(t, c, "sha256:a", "aaaa")
+	if content, ok := get(t, c, "sha256:a"); !ok || content != "aaaa" {
+		t.Errorf("expected %q to be cached, got %q", "sha256:a", content)
+	}
+	if _, ok := get(t, c, "sha256:b"); ok {
+		t.Errorf("expected %q not to be cached", "sha256:b")
+	}
+
+	// a file is not cached until committed
+	w, err := c.Create("sha256:b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("bbbb")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := get(t, c, "sha256:b"); ok {
+		t.Errorf("expected %q not to be cached before it is committed", "sha256:b")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := get(t, c, "sha256:b"); ok {
+		t.Errorf("expected %q to be discarded", "sha256:b")
+	}
+	if c.Size() != 4 {
+		t.Errorf("expected a size of 4, got %d", c.Size())
+	}
+
+	// files larger than the cache are discarded
+	put(t, c, "sha256:large", "0123456789a")
+	if _, ok := get(t, c, "sha256:large"); ok {
+		t.Errorf("expected %q to be discarded", "sha256:large")
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	c, err := blobcache.New(dir, 10)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	put(t, c, "a", "aaaa")
+	put(t, c, "b", "bbbb")
+	// a is used more recently than b
+	get(t, c, "a")
+	put(t, c, "c", "cccc")
+
+	if _, ok := get(t, c, "b"); ok {
+		t.Errorf("expected the least recently used file to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := get(t, c, key); !ok {
+			t.Errorf("expected %q to be cached", key)
+		}
+	}
+	if c.Size() != 8 || c.Len() != 2 {
+		t.Errorf("expected 2 files of 8 bytes, got %d files of %d bytes", c.Len(), c.Size())
+	}
+	if evicted := c.Evicted(); evicted != (blobcache.Eviction{Files: 1, Bytes: 4}) {
+		t.Errorf("expected a file of 4 bytes to be evicted, got %+v", evicted)
+	}
+
+	// replacing a file does not count it twice
+	put(t, c, "c", "cc")
+	if c.Size() != 6 {
+		t.Errorf("expected a size of 6, got %d", c.Size())
+	}
+}
+
+func TestCacheReopen(t *testing.T) {
+	dir := t.TempDir()
+	c, err := blobcache.New(dir, 10)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	put(t, c, "a", "aaaa")
+	// files are reopened in the order they were written
+	past := time.Now().Add(-time.Hour)
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if err := os.Chtimes(filepath.Join(dir, e.Name()), past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put(t, c, "b", "bbbb")
+	// the order of use is not recorded on disk
+	get(t, c, "a")
+	// left behind by an interrupted write
+	if err := os.WriteFile(filepath.Join(dir, ".tmp-123"), []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = blobcache.New(dir, 6)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, ok := get(t, c, "a"); ok {
+		t.Errorf("expected the least recently written file to be evicted")
+	}
+	if content, ok := get(t, c, "b"); !ok || content != "bbbb" {
+		t.Errorf("expected %q to be cached, got %q", "b", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".tmp-123")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the partial file to be removed")
+	}
+}
+
+func TestCacheLink(t *testing.T) {
+	c, err := blobcache.New(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	put(t, c, "a", "aaaa")
+
+	dst := filepath.Join(t.TempDir(), "a.tar.gz")
+	if err := c.Link("a", dst); err != nil {
+		t.Fatalf("Link() unexpected error: %v", err)
+	}
+	if content, err := os.ReadFile(dst); err != nil || string(content) != "aaaa" {
+		t.Errorf("expected the file to be linked, got %q: %v", content, err)
+	}
+	if err := c.Link("b", filepath.Join(t.TempDir(), "b")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestCachePut(t *testing.T) {
+	dir := t.TempDir()
+	c, err := blobcache.New(filepath.Join(dir, "cache"), 10)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	src := filepath.Join(dir, "a.tar.gz")
+	if err := os.WriteFile(src, []byte("aaaa"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(src, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Put("a", src); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+	if content, ok := get(t, c, "a"); !ok || content != "aaaa" {
+		t.Errorf("expected %q to be cached, got %q", "a", content)
+	}
+	file, err := c.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	cached, _ := file.Stat()
+	original, _ := os.Stat(src)
+	if !os.SameFile(cached, original) {
+		t.Errorf("expected the file to be hard linked into the cache")
+	}
+	// using the file does not modify the file it is linked to
+	if err := c.Link("a", filepath.Join(dir, "b.tar.gz")); err != nil {
+		t.Fatalf("Link() unexpected error: %v", err)
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("expected the modification time %v to be preserved, got %v", past, info.ModTime())
+	}
+	if c.Size() != 4 {
+		t.Errorf("expected a size of 4, got %d", c.Size())
+	}
+}
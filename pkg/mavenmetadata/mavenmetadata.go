@@ -77,6 +77,20 @@ func (m *MavenMetadata) LatestVersion() (string, error) {
 	return m.Versioning.Latest, nil
 }
 
+// RangeVersion returns the highest version listed in the metadata that
+// satisfies the Maven version range
+func (m *MavenMetadata) RangeVersion(spec string) (string, error) {
+	r, err := ParseVersionRange(spec)
+	if err != nil {
+		return "", err
+	}
+	v, ok := r.MatchVersion(m.Versioning.Versions.Version)
+	if !ok {
+		return "", fmt.Errorf("artifact metadata does not have a version matching range %q", spec)
+	}
+	return v, nil
+}
+
 // SnapshotResolvedFileName returns resolved artifact version
 // if SNAPSHOT version is enabled, set the SNAPSHOT artifact name
 // based on match conditions Snapshot.Timestamp, Snapshot.BuildNumber and Version.Extension
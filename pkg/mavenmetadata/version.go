@@ -0,0 +1,466 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mavenmetadata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ComparableVersion is a parsed Maven version that orders the same way as
+// Maven's org.apache.maven.artifact.versioning.ComparableVersion.
+// https://maven.apache.org/pom.html#version-order-specification
+type ComparableVersion struct {
+	value string
+	items *listItem
+}
+
+// ParseVersion parses a Maven version string. Any string is a valid version,
+// so parsing never fails.
+func ParseVersion(version string) ComparableVersion {
+	return ComparableVersion{
+		value: version,
+		items: parseItems(version),
+	}
+}
+
+// String returns the version as it was originally given.
+func (v ComparableVersion) String() string {
+	return v.value
+}
+
+// Compare returns -1, 0 or 1 when v is respectively older, equivalent or newer
+// than other.
+func (v ComparableVersion) Compare(other ComparableVersion) int {
+	return v.items.compare(other.items)
+}
+
+// CompareVersions parses and compares two Maven version strings.
+func CompareVersions(a, b string) int {
+	return ParseVersion(a).Compare(ParseVersion(b))
+}
+
+type item interface {
+	// compare orders this item relative to other; other may be nil to
+	// represent a missing item
+	compare(other item) int
+	isNull() bool
+}
+
+// intItem holds a numeric version segment as a decimal string without leading
+// zeros, avoiding overflow for arbitrarily long build numbers
+type intItem string
+
+func (i intItem) isNull() bool {
+	return i == "0"
+}
+
+func (i intItem) compare(other item) int {
+	switch o := other.(type) {
+	case nil:
+		if i.isNull() {
+			// 1.0 == 1
+			return 0
+		}
+		// 1.1 > 1
+		return 1
+	case intItem:
+		if len(i) != len(o) {
+			return sign(len(i) - len(o))
+		}
+		return strings.Compare(string(i), string(o))
+	case stringItem:
+		// 1.1 > 1-sp
+		return 1
+	case *listItem:
+		// 1.1 > 1-1
+		return 1
+	}
+	return 0
+}
+
+// qualifiers are the well known qualifiers in ascending order; "" is a release
+var qualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+var qualifierAliases = map[string]string{
+	"ga":      "",
+	"final":   "",
+	"release": "",
+	"cr":      "rc",
+}
+
+// releaseQualifierIndex is the comparable form of the empty qualifier
+var releaseQualifierIndex = comparableQualifier("")
+
+type stringItem string
+
+func newStringItem(value string, followedByDigit bool) stringItem {
+	if followedByDigit && len(value) == 1 {
+		// a1 = alpha-1, b1 = beta-1, m1 = milestone-1
+		switch value {
+		case "a":
+			value = "alpha"
+		case "b":
+			value = "beta"
+		case "m":
+			value = "milestone"
+		}
+	}
+	if alias, ok := qualifierAliases[value]; ok {
+		value = alias
+	}
+	return stringItem(value)
+}
+
+// comparableQualifier maps well known qualifiers to their index, and unknown
+// qualifiers after all known qualifiers in lexical order
+func comparableQualifier(qualifier string) string {
+	for i, q := range qualifiers {
+		if q == qualifier {
+			return strconv.Itoa(i)
+		}
+	}
+	return fmt.Sprintf("%d-%s", len(qualifiers), qualifier)
+}
+
+func (s stringItem) isNull() bool {
+	return comparableQualifier(string(s)) == releaseQualifierIndex
+}
+
+func (s stringItem) compare(other item) int {
+	switch o := other.(type) {
+	case nil:
+		// 1-rc < 1, 1-ga == 1
+		return strings.Compare(comparableQualifier(string(s)), releaseQualifierIndex)
+	case intItem:
+		// 1.any < 1.1
+		return -1
+	case stringItem:
+		return strings.Compare(comparableQualifier(string(s)), comparableQualifier(string(o)))
+	case *listItem:
+		// 1.any < 1-1
+		return -1
+	}
+	return 0
+}
+
+type listItem struct {
+	items []item
+}
+
+func (l *listItem) add(i item) {
+	l.items = append(l.items, i)
+}
+
+func (l *listItem) isNull() bool {
+	return len(l.items) == 0
+}
+
+func (l *listItem) compare(other item) int {
+	switch o := other.(type) {
+	case nil:
+		if len(l.items) == 0 {
+			// 1-0 == 1- (normalized) == 1
+			return 0
+		}
+		for _, i := range l.items {
+			if result := i.compare(nil); result != 0 {
+				return result
+			}
+		}
+		return 0
+	case intItem:
+		// 1-1 < 1.0.x
+		return -1
+	case stringItem:
+		// 1-1 > 1-sp
+		return 1
+	case *listItem:
+		for i := 0; i < len(l.items) || i < len(o.items); i++ {
+			var left, right item
+			if i < len(l.items) {
+				left = l.items[i]
+			}
+			if i < len(o.items) {
+				right = o.items[i]
+			}
+			var result int
+			if left == nil {
+				if right != nil {
+					// this is shorter, so invert the comparison
+					result = -1 * right.compare(nil)
+				}
+			} else {
+				result = left.compare(right)
+			}
+			if result != 0 {
+				return result
+			}
+		}
+		return 0
+	}
+	return 0
+}
+
+// normalize removes trailing null items: 0, "" and empty lists
+func (l *listItem) normalize() {
+	for i := len(l.items) - 1; i >= 0; i-- {
+		last := l.items[i]
+		if last.isNull() {
+			l.items = append(l.items[:i], l.items[i+1:]...)
+		} else if _, ok := last.(*listItem); !ok {
+			break
+		}
+	}
+}
+
+func parseItems(version string) *listItem {
+	version = strings.ToLower(version)
+
+	root := &listItem{}
+	list := root
+	stack := []*listItem{root}
+	newList := func() {
+		l := &listItem{}
+		list.add(l)
+		list = l
+		stack = append(stack, l)
+	}
+
+	isDigit := false
+	start := 0
+	for i, c := range version {
+		switch {
+		case c == '.':
+			if i == start {
+				list.add(intItem("0"))
+			} else {
+				list.add(parseItem(isDigit, version[start:i]))
+			}
+			start = i + 1
+		case c == '-':
+			if i == start {
+				list.add(intItem("0"))
+			} else {
+				list.add(parseItem(isDigit, version[start:i]))
+			}
+			start = i + 1
+			newList()
+		case unicode.IsDigit(c):
+			if !isDigit && i > start {
+				// 1.0.0.X1 < 1.0.0-X2, treat .X as -X for any string qualifier X
+				if len(list.items) != 0 {
+					newList()
+				}
+				list.add(newStringItem(version[start:i], true))
+				start = i
+				newList()
+			}
+			isDigit = true
+		default:
+			if isDigit && i > start {
+				list.add(parseItem(true, version[start:i]))
+				start = i
+				newList()
+			}
+			isDigit = false
+		}
+	}
+	if len(version) > start {
+		// 1.0.0.X1 < 1.0.0-X2, treat .X as -X for any string qualifier X
+		if !isDigit && len(list.items) != 0 {
+			newList()
+		}
+		list.add(parseItem(isDigit, version[start:]))
+	}
+
+	// normalize the most deeply nested lists first
+	for i := len(stack) - 1; i >= 0; i-- {
+		stack[i].normalize()
+	}
+
+	return root
+}
+
+func parseItem(isDigit bool, value string) item {
+	if isDigit {
+		value = strings.TrimLeft(value, "0")
+		if value == "" {
+			value = "0"
+		}
+		return intItem(value)
+	}
+	return newStringItem(value, false)
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
+
+// VersionRange is a parsed Maven version range specification, such as
+// "[1.0,2.0)" or "(,1.0],[1.2,)".
+// https://maven.apache.org/enforcer/enforcer-rules/versionRanges.html
+type VersionRange struct {
+	spec         string
+	restrictions []restriction
+}
+
+type restriction struct {
+	lower          *ComparableVersion
+	lowerInclusive bool
+	upper          *ComparableVersion
+	upperInclusive bool
+}
+
+// IsVersionRange returns true when the version is a range specification
+// rather than a single version.
+func IsVersionRange(version string) bool {
+	return strings.HasPrefix(version, "[") || strings.HasPrefix(version, "(")
+}
+
+// ParseVersionRange parses a Maven version range specification. Unlike Maven,
+// a bare version (a soft requirement) is not accepted as a range.
+func ParseVersionRange(spec string) (*VersionRange, error) {
+	r := &VersionRange{spec: spec}
+
+	process := strings.TrimSpace(spec)
+	if !IsVersionRange(process) {
+		return nil, fmt.Errorf("version range %q must start with '[' or '('", spec)
+	}
+	var upperBound *ComparableVersion
+	for IsVersionRange(process) {
+		index := strings.IndexAny(process, ")]")
+		if index < 0 {
+			return nil, fmt.Errorf("unbounded version range %q", spec)
+		}
+		res, err := parseRestriction(process[:index+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range %q: %w", spec, err)
+		}
+		if upperBound != nil && (res.lower == nil || res.lower.Compare(*upperBound) < 0) {
+			return nil, fmt.Errorf("invalid version range %q: ranges overlap", spec)
+		}
+		r.restrictions = append(r.restrictions, res)
+		upperBound = res.upper
+
+		process = strings.TrimSpace(process[index+1:])
+		if strings.HasPrefix(process, ",") {
+			process = strings.TrimSpace(process[1:])
+		}
+	}
+	if process != "" {
+		return nil, fmt.Errorf("invalid version range %q: only fully-qualified sets are allowed", spec)
+	}
+
+	return r, nil
+}
+
+func parseRestriction(spec string) (restriction, error) {
+	res := restriction{
+		lowerInclusive: strings.HasPrefix(spec, "["),
+		upperInclusive: strings.HasSuffix(spec, "]"),
+	}
+
+	process := strings.TrimSpace(spec[1 : len(spec)-1])
+	lowerBound, upperBound, found := strings.Cut(process, ",")
+	if !found {
+		if !res.lowerInclusive || !res.upperInclusive {
+			return res, fmt.Errorf("single version %q must be surrounded by []", process)
+		}
+		if process == "" {
+			return res, fmt.Errorf("empty version in %q", spec)
+		}
+		v := ParseVersion(process)
+		res.lower = &v
+		res.upper = &v
+		return res, nil
+	}
+	if strings.Contains(upperBound, ",") {
+		return res, fmt.Errorf("too many versions in %q", spec)
+	}
+	if lowerBound = strings.TrimSpace(lowerBound); lowerBound != "" {
+		v := ParseVersion(lowerBound)
+		res.lower = &v
+	}
+	if upperBound = strings.TrimSpace(upperBound); upperBound != "" {
+		v := ParseVersion(upperBound)
+		res.upper = &v
+	}
+	if res.lower != nil && res.upper != nil {
+		if cmp := res.upper.Compare(*res.lower); cmp < 0 || (cmp == 0 && (!res.lowerInclusive || !res.upperInclusive)) {
+			return res, fmt.Errorf("range %q defies version ordering", spec)
+		}
+	}
+	return res, nil
+}
+
+// String returns the range as it was originally given.
+func (r *VersionRange) String() string {
+	return r.spec
+}
+
+// Contains returns true if the version satisfies any restriction in the range.
+func (r *VersionRange) Contains(version ComparableVersion) bool {
+	for _, res := range r.restrictions {
+		if res.contains(version) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r restriction) contains(version ComparableVersion) bool {
+	if r.lower != nil {
+		cmp := r.lower.Compare(version)
+		if cmp > 0 || (cmp == 0 && !r.lowerInclusive) {
+			return false
+		}
+	}
+	if r.upper != nil {
+		cmp := r.upper.Compare(version)
+		if cmp < 0 || (cmp == 0 && !r.upperInclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchVersion returns the highest of the versions contained by the range.
+func (r *VersionRange) MatchVersion(versions []string) (string, bool) {
+	var match *ComparableVersion
+	for _, version := range versions {
+		v := ParseVersion(strings.TrimSpace(version))
+		if !r.Contains(v) {
+			continue
+		}
+		if match == nil || v.Compare(*match) > 0 {
+			match = &v
+		}
+	}
+	if match == nil {
+		return "", false
+	}
+	return match.String(), true
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mavenmetadata_test
+
+import (
+	"testing"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/mavenmetadata"
+)
+
+func TestCompareVersions(t *testing.T) {
+	// each version is strictly older than the version that follows it, cases
+	// taken from Maven's ComparableVersionTest
+	orderings := [][]string{
+		{
+			"1-alpha2snapshot", "1-alpha2", "1-alpha-123", "1-beta-2", "1-beta123", "1-m2", "1-m11", "1-rc", "1-cr2",
+			"1-rc123", "1-SNAPSHOT", "1", "1-sp", "1-sp2", "1-sp123", "1-abc", "1-def", "1-pom-1", "1-1-snapshot",
+			"1-1", "1-2", "1-123",
+		},
+		{
+			"2.0", "2.0.a", "2-1", "2.0.2", "2.0.123", "2.1.0", "2.1-a", "2.1b", "2.1-c", "2.1-1", "2.1.0.1", "2.2",
+			"2.123", "11.a2", "11.a11", "11.b2", "11.b11", "11.m2", "11.m11", "11", "11.a", "11b", "11c", "11m",
+		},
+	}
+	for _, ordered := range orderings {
+		for i := 1; i < len(ordered); i++ {
+			for _, older := range ordered[:i] {
+				newer := ordered[i]
+				if got := mavenmetadata.CompareVersions(older, newer); got != -1 {
+					t.Errorf("CompareVersions(%q, %q) = %d, want -1", older, newer, got)
+				}
+				if got := mavenmetadata.CompareVersions(newer, older); got != 1 {
+					t.Errorf("CompareVersions(%q, %q) = %d, want 1", newer, older, got)
+				}
+			}
+		}
+	}
+
+	equivalent := [][2]string{
+		{"1", "1.0"},
+		{"1", "1.0.0"},
+		{"1.0", "1-0"},
+		{"1", "1-ga"},
+		{"1", "1.final"},
+		{"1", "1-release"},
+		{"1a1", "1-alpha-1"},
+		{"1b2", "1-beta-2"},
+		{"1m3", "1-milestone-3"},
+		{"1-cr1", "1-rc1"},
+		{"1X", "1x"},
+		{"1.0.0-RELEASE", "1"},
+		{"2.7.0-SNAPSHOT", "2.7.0.snapshot"},
+		{"1.0001", "1.1"},
+	}
+	for _, c := range equivalent {
+		if got := mavenmetadata.CompareVersions(c[0], c[1]); got != 0 {
+			t.Errorf("CompareVersions(%q, %q) = %d, want 0", c[0], c[1], got)
+		}
+	}
+
+	if got := mavenmetadata.CompareVersions("1.123456789012345678901234567890", "1.123456789012345678901234567891"); got != -1 {
+		t.Errorf("CompareVersions with large numeric segments = %d, want -1", got)
+	}
+}
+
+func TestParseVersionRange(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantErr  bool
+		contains []string
+		excludes []string
+	}{
+		{
+			spec:     "[1.0]",
+			contains: []string{"1.0", "1", "1.0.0"},
+			excludes: []string{"1.0.1", "0.9"},
+		},
+		{
+			spec:     "[1.2,2.0)",
+			contains: []string{"1.2", "1.2.1", "1.10", "2.0-rc1"},
+			excludes: []string{"1.1", "2.0", "2.0.1"},
+		},
+		{
+			spec:     "(1.0,2.0]",
+			contains: []string{"1.0.1", "2.0"},
+			excludes: []string{"1.0", "2.0.1"},
+		},
+		{
+			spec:     "(,1.0]",
+			contains: []string{"0.1", "1.0"},
+			excludes: []string{"1.0.1"},
+		},
+		{
+			spec:     "[1.5,)",
+			contains: []string{"1.5", "99"},
+			excludes: []string{"1.4"},
+		},
+		{
+			spec:     "(,1.0],[1.2,)",
+			contains: []string{"1.0", "1.2", "3"},
+			excludes: []string{"1.1"},
+		},
+		{
+			spec:     "[ 1.2 , 2.0 )",
+			contains: []string{"1.5"},
+		},
+		{spec: "1.0", wantErr: true},
+		{spec: "[1.0", wantErr: true},
+		{spec: "(1.0)", wantErr: true},
+		{spec: "[]", wantErr: true},
+		{spec: "[1.0,2.0,3.0]", wantErr: true},
+		{spec: "[2.0,1.0]", wantErr: true},
+		{spec: "(1.0,1.0]", wantErr: true},
+		{spec: "[1.0,2.0],[1.5,3.0]", wantErr: true},
+		{spec: "[1.0,2.0]1.5", wantErr: true},
+	}
+	for _, c := range tests {
+		t.Run(c.spec, func(t *testing.T) {
+			r, err := mavenmetadata.ParseVersionRange(c.spec)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("ParseVersionRange() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for _, v := range c.contains {
+				if !r.Contains(mavenmetadata.ParseVersion(v)) {
+					t.Errorf("expected range %q to contain %q", c.spec, v)
+				}
+			}
+			for _, v := range c.excludes {
+				if r.Contains(mavenmetadata.ParseVersion(v)) {
+					t.Errorf("expected range %q to not contain %q", c.spec, v)
+				}
+			}
+		})
+	}
+}
+
+func TestMavenMetadata_RangeVersion(t *testing.T) {
+	meta := mavenmetadata.MavenMetadata{
+		Versioning: mavenmetadata.Versioning{
+			Versions: mavenmetadata.Versions{
+				Version: []string{"1.9.0", "1.10.0", "2.0.0-RC1", "2.0.0", "2.1.0"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "highest in range by maven ordering",
+			spec: "[1.0,2.0)",
+			want: "2.0.0-RC1",
+		},
+		{
+			name: "exclusive lower bound",
+			spec: "(1.10.0,2.0.0]",
+			want: "2.0.0",
+		},
+		{
+			name: "unbounded upper",
+			spec: "[1.0,)",
+			want: "2.1.0",
+		},
+		{
+			name: "multiple sets",
+			spec: "(,1.9.0],[3.0,)",
+			want: "1.9.0",
+		},
+		{
+			name:    "no match",
+			spec:    "[3.0,)",
+			wantErr: true,
+		},
+		{
+			name:    "malformed range",
+			spec:    "[1.0,2.0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := meta.RangeVersion(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MavenMetadata.RangeVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MavenMetadata.RangeVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pathfilter selects the files of a directory tree with gitignore-style
+// patterns.
+package pathfilter
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Filter selects files by their slash separated path relative to the root of
+// a directory tree. A file is selected when it is included and not ignored.
+type Filter struct {
+	include []pattern
+	ignore  []pattern
+}
+
+// New parses the include and ignore patterns of a filter. Every file is
+// included when there are no include patterns.
+func New(include, ignore []string) (*Filter, error) {
+	f := &Filter{}
+	for _, p := range include {
+		parsed, ok, err := parsePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if parsed.negate {
+				return nil, fmt.Errorf("include pattern %q must not be negated", p)
+			}
+			f.include = append(f.include, parsed)
+		}
+	}
+	for _, p := range ignore {
+		parsed, ok, err := parsePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			f.ignore = append(f.ignore, parsed)
+		}
+	}
+	return f, nil
+}
+
+// Included reports whether the path, or one of its parent directories,
+// matches an include pattern
+func (f *Filter) Included(name string, isDir bool) bool {
+	if len(f.include) == 0 {
+		return true
+	}
+	segments := strings.Split(name, "/")
+	for i := 1; i <= len(segments); i++ {
+		for _, p := range f.include {
+			if p.match(segments[:i], isDir || i < len(segments)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Ignored reports whether the path is excluded by the ignore patterns. The
+// last pattern matching the path decides, a negated pattern re-includes the
+// path.
+func (f *Filter) Ignored(name string, isDir bool) bool {
+	segments := strings.Split(name, "/")
+	ignored := false
+	for _, p := range f.ignore {
+		if p.match(segments, isDir) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// Apply removes the files beneath the root that are not selected. As with
+// gitignore, the content of an ignored directory is removed even when
+// matched by a negated pattern. Directories left empty are removed unless
+// they are included themselves.
+func (f *Filter) Apply(root string) error {
+	emptied := []string{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if f.Ignored(name, d.IsDir()) {
+			if err := os.RemoveAll(p); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if !f.Included(name, true) {
+				emptied = append(emptied, p)
+			}
+			return nil
+		}
+		if !f.Included(name, false) {
+			return os.Remove(p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// nested directories are removed before their parents
+	for i := len(emptied) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(emptied[i])
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(emptied[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pattern is a parsed gitignore-style pattern
+type pattern struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// parsePattern parses a gitignore-style pattern. Blank patterns and comments
+// starting with '#' are skipped.
+func parsePattern(p string) (pattern, bool, error) {
+	raw := p
+	p = strings.TrimRight(p, " ")
+	if p == "" || strings.HasPrefix(p, "#") {
+		return pattern{}, false, nil
+	}
+	parsed := pattern{}
+	if strings.HasPrefix(p, "!") {
+		parsed.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		parsed.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if strings.Trim(p, "/") == "" {
+		return pattern{}, false, fmt.Errorf("pattern %q does not match any path", raw)
+	}
+	// patterns without a slash match at any depth, otherwise they are
+	// relative to the root
+	if !strings.Contains(p, "/") {
+		p = "**/" + p
+	}
+	p = strings.TrimPrefix(p, "/")
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return pattern{}, false, fmt.Errorf("pattern %q is malformed: %w", raw, err)
+		}
+		parsed.segments = append(parsed.segments, segment)
+	}
+	return parsed, true, nil
+}
+
+// match reports whether the pattern matches the path segments
+func (p pattern) match(segments []string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	return matchSegments(p.segments, segments)
+}
+
+// matchSegments matches path segments, '**' matches any number of segments,
+// at least one when it ends the pattern
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return len(segments) > 0
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
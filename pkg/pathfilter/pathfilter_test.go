@@ -0,0 +1,157 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pathfilter_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/pathfilter"
+)
+
+func TestFilterIgnored(t *testing.T) {
+	tests := []struct {
+		name     string
+		ignore   []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "no patterns", path: "a.txt", expected: false},
+		{name: "name at any depth", ignore: []string{"*.md"}, path: "docs/guide/README.md", expected: true},
+		{name: "name not matching", ignore: []string{"*.md"}, path: "config/app.yaml", expected: false},
+		{name: "anchored to the root", ignore: []string{"/build"}, path: "build", isDir: true, expected: true},
+		{name: "anchored not matching nested", ignore: []string{"/build"}, path: "src/build", isDir: true, expected: false},
+		{name: "relative path", ignore: []string{"docs/*.md"}, path: "docs/README.md", expected: true},
+		{name: "relative path not matching nested", ignore: []string{"docs/*.md"}, path: "docs/guide/README.md", expected: false},
+		{name: "double star", ignore: []string{"docs/**/*.md"}, path: "docs/guide/README.md", expected: true},
+		{name: "trailing double star", ignore: []string{"docs/**"}, path: "docs/README.md", expected: true},
+		{name: "trailing double star not matching the directory", ignore: []string{"docs/**"}, path: "docs", isDir: true, expected: false},
+		{name: "directory only", ignore: []string{"tmp/"}, path: "tmp", isDir: true, expected: true},
+		{name: "directory only not matching files", ignore: []string{"tmp/"}, path: "tmp", expected: false},
+		{name: "negated", ignore: []string{"*.md", "!README.md"}, path: "README.md", expected: false},
+		{name: "last pattern wins", ignore: []string{"!README.md", "*.md"}, path: "README.md", expected: true},
+		{name: "comments and blank lines", ignore: []string{"# *.md", ""}, path: "README.md", expected: false},
+		{name: "escaped", ignore: []string{`\#notes`}, path: "#notes", expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := pathfilter.New(nil, tt.ignore)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if actual := filter.Ignored(tt.path, tt.isDir); actual != tt.expected {
+				t.Errorf("Ignored() expected %t, got %t", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFilterIncluded(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "no patterns", path: "a.txt", expected: true},
+		{name: "matching", include: []string{"*.yaml"}, path: "config/app.yaml", expected: true},
+		{name: "not matching", include: []string{"*.yaml"}, path: "config/app.json", expected: false},
+		{name: "parent directory", include: []string{"config/"}, path: "config/nested/app.json", expected: true},
+		{name: "directory itself", include: []string{"config/"}, path: "config", isDir: true, expected: true},
+		{name: "other directory", include: []string{"config/"}, path: "docs/config", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := pathfilter.New(tt.include, nil)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if actual := filter.Included(tt.path, tt.isDir); actual != tt.expected {
+				t.Errorf("Included() expected %t, got %t", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidPatterns(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		include []string
+		ignore  []string
+	}{
+		{name: "malformed", ignore: []string{"[a-"}},
+		{name: "root", ignore: []string{"/"}},
+		{name: "negated include", include: []string{"!*.md"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := pathfilter.New(tt.include, tt.ignore); err == nil {
+				t.Errorf("New() expected an error")
+			}
+		})
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"config/app.yaml", "config/nested/db.yaml", "config/nested/README.md", "config/secret.yaml", "docs/README.md", "README.md"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"config/empty", "docs/empty"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filter, err := pathfilter.New([]string{"config/"}, []string{"*.md", "secret.yaml"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := filter.Apply(root); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+
+	actual := []string{}
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == root {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		actual = append(actual, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(actual)
+	expected := []string{"config", "config/app.yaml", "config/empty", "config/nested", "config/nested/db.yaml"}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("Apply() (-expected, +actual): %s", diff)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signedurl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SecretKey is the Secret data key holding the current signing key
+	SecretKey = "key"
+	// SecretPreviousKey is the Secret data key holding the signing key being
+	// rotated out, URLs signed with it remain valid until they expire
+	SecretPreviousKey = "previousKey"
+)
+
+// defaultRetryInterval is how long a failure to read the Secret is cached for
+// when the RetryInterval is not set
+const defaultRetryInterval = 10 * time.Second
+
+// SecretKeySource reads the signing keys from a Secret, updates to the Secret
+// are observed within the RefreshInterval.
+type SecretKeySource struct {
+	// Client reads the Secret
+	Client client.Reader
+	// Secret holding the keys
+	Secret types.NamespacedName
+	// RefreshInterval is how long the keys are cached for
+	RefreshInterval time.Duration
+	// RetryInterval is how long a failure to read the keys is cached for
+	// before the Secret is read again, ten seconds when zero. The last keys
+	// read are used in the meantime.
+	RetryInterval time.Duration
+
+	m         sync.Mutex
+	keys      [][]byte
+	refreshed time.Time
+	err       error
+	failed    time.Time
+}
+
+var _ KeySource = (*SecretKeySource)(nil)
+
+func (s *SecretKeySource) Keys(ctx context.Context) ([][]byte, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.keys != nil && time.Since(s.refreshed) < s.RefreshInterval {
+		return s.keys, nil
+	}
+	retryInterval := s.RetryInterval
+	if retryInterval <= 0 {
+		retryInterval = defaultRetryInterval
+	}
+	if s.err == nil || time.Since(s.failed) >= retryInterval {
+		keys, err := s.read(ctx)
+		if err == nil {
+			s.keys, s.refreshed, s.err = keys, time.Now(), nil
+			return s.keys, nil
+		}
+		s.err, s.failed = err, time.Now()
+	}
+	if s.keys != nil {
+		// keep signing with the last known keys while the Secret is
+		// unavailable
+		return s.keys, nil
+	}
+	return nil, s.err
+}
+
+// read returns the keys held by the Secret, the current key first
+func (s *SecretKeySource) read(ctx context.Context) ([][]byte, error) {
+	secret := &corev1.Secret{}
+	if err := s.Client.Get(ctx, s.Secret, secret); err != nil {
+		return nil, err
+	}
+	if len(secret.Data[SecretKey]) == 0 {
+		return nil, fmt.Errorf("Secret %q does not contain a %q signing key", s.Secret, SecretKey)
+	}
+	keys := [][]byte{secret.Data[SecretKey]}
+	if previous := secret.Data[SecretPreviousKey]; len(previous) != 0 {
+		keys = append(keys, previous)
+	}
+	return keys, nil
+}
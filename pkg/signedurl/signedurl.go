@@ -0,0 +1,159 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signedurl signs URLs with an expiring HMAC-SHA256 token carried in
+// the query, verified by the server before the resource is served.
+package signedurl
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// ExpiresParam is the query parameter holding the unix time the URL
+	// expires at
+	ExpiresParam = "expires"
+	// SignatureParam is the query parameter holding the base64url encoded
+	// signature of the URL
+	SignatureParam = "signature"
+)
+
+var (
+	// ErrMissingSignature is returned for URLs that are not signed
+	ErrMissingSignature = errors.New("URL is not signed")
+	// ErrExpired is returned for URLs signed with an expiry in the past
+	ErrExpired = errors.New("signed URL has expired")
+	// ErrInvalidSignature is returned for URLs not signed by a known key
+	ErrInvalidSignature = errors.New("URL signature is not valid")
+)
+
+// KeySource provides the keys URLs are signed with
+type KeySource interface {
+	// Keys returns the signing keys. New URLs are signed with the first key,
+	// URLs signed with any of the keys are valid.
+	Keys(ctx context.Context) ([][]byte, error)
+}
+
+// Signer signs and verifies expiring URLs
+type Signer struct {
+	// Keys URLs are signed with
+	Keys KeySource
+	// TTL is how long a signed URL is valid for
+	TTL time.Duration
+	// Now returns the current time, defaults to time.Now
+	Now func() time.Time
+}
+
+func (s *Signer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Sign returns the URL signed with the current key, valid for the TTL
+func (s *Signer) Sign(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	keys, err := s.Keys.Keys(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("no URL signing keys found")
+	}
+
+	expires := s.now().Add(s.TTL).Unix()
+	query := u.Query()
+	query.Set(ExpiresParam, strconv.FormatInt(expires, 10))
+	query.Set(SignatureParam, signature(keys[0], u.Path, expires))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Verify checks the signature of a request for the path with the query
+func (s *Signer) Verify(ctx context.Context, path string, query url.Values) error {
+	if query.Get(ExpiresParam) == "" || query.Get(SignatureParam) == "" {
+		return ErrMissingSignature
+	}
+	expires, err := strconv.ParseInt(query.Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !s.now().Before(time.Unix(expires, 0)) {
+		return ErrExpired
+	}
+
+	keys, err := s.Keys.Keys(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if hmac.Equal([]byte(signature(key, path, expires)), []byte(query.Get(SignatureParam))) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// NeedsRefresh returns true when the signed URL has passed the first half of
+// its TTL, or is not signed
+func (s *Signer) NeedsRefresh(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	expires, err := strconv.ParseInt(u.Query().Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return true
+	}
+	return !s.now().Add(s.TTL / 2).Before(time.Unix(expires, 0))
+}
+
+// Unsigned returns the URL without its signature, or the raw URL when it can
+// not be parsed
+func Unsigned(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	if !query.Has(ExpiresParam) && !query.Has(SignatureParam) {
+		return rawURL
+	}
+	query.Del(ExpiresParam)
+	query.Del(SignatureParam)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// signature is the HMAC of the path and expiry, the host is not signed so URLs
+// remain valid when the server is reached through a proxy
+func signature(key []byte, path string, expires int64) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%d", path, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
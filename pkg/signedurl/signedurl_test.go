@@ -0,0 +1,211 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signedurl_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/signedurl"
+)
+
+type staticKeys [][]byte
+
+func (k staticKeys) Keys(ctx context.Context) ([][]byte, error) {
+	return k, nil
+}
+
+func TestSigner(t *testing.T) {
+	ctx := context.TODO()
+	now := time.Unix(1000, 0)
+	signer := &signedurl.Signer{
+		Keys: staticKeys{[]byte("current"), []byte("previous")},
+		TTL:  time.Hour,
+		Now:  func() time.Time { return now },
+	}
+
+	rawURL := "http://artifact.example/imagerepository/test-namespace/my-image/0123.tar.gz"
+	signed, err := signer.Sign(ctx, rawURL)
+	if err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+	u, _ := url.Parse(signed)
+	if expected, actual := "4600", u.Query().Get(signedurl.ExpiresParam); expected != actual {
+		t.Errorf("Sign() expected expiry %q, got %q", expected, actual)
+	}
+	if expected, actual := rawURL, signedurl.Unsigned(signed); expected != actual {
+		t.Errorf("Unsigned() expected %q, got %q", expected, actual)
+	}
+
+	if err := signer.Verify(ctx, u.Path, u.Query()); err != nil {
+		t.Errorf("Verify() unexpected error: %v", err)
+	}
+	if err := signer.Verify(ctx, "/imagerepository/test-namespace/other-image/0123.tar.gz", u.Query()); !errors.Is(err, signedurl.ErrInvalidSignature) {
+		t.Errorf("Verify() expected invalid signature for another path, got %v", err)
+	}
+	if err := signer.Verify(ctx, u.Path, url.Values{}); !errors.Is(err, signedurl.ErrMissingSignature) {
+		t.Errorf("Verify() expected missing signature, got %v", err)
+	}
+	tampered := u.Query()
+	tampered.Set(signedurl.ExpiresParam, "9999")
+	if err := signer.Verify(ctx, u.Path, tampered); !errors.Is(err, signedurl.ErrInvalidSignature) {
+		t.Errorf("Verify() expected invalid signature for a modified expiry, got %v", err)
+	}
+
+	if signer.NeedsRefresh(signed) {
+		t.Errorf("NeedsRefresh() expected a fresh URL to not need a refresh")
+	}
+	if !signer.NeedsRefresh(rawURL) {
+		t.Errorf("NeedsRefresh() expected an unsigned URL to need a refresh")
+	}
+
+	now = now.Add(31 * time.Minute)
+	if !signer.NeedsRefresh(signed) {
+		t.Errorf("NeedsRefresh() expected a URL past half its TTL to need a refresh")
+	}
+	now = now.Add(30 * time.Minute)
+	if err := signer.Verify(ctx, u.Path, u.Query()); !errors.Is(err, signedurl.ErrExpired) {
+		t.Errorf("Verify() expected expired, got %v", err)
+	}
+
+	// URLs signed with the previous key remain valid after a rotation
+	rotated := &signedurl.Signer{
+		Keys: staticKeys{[]byte("next"), []byte("current")},
+		TTL:  time.Hour,
+		Now:  func() time.Time { return time.Unix(1000, 0) },
+	}
+	if err := rotated.Verify(ctx, u.Path, u.Query()); err != nil {
+		t.Errorf("Verify() unexpected error after rotation: %v", err)
+	}
+}
+
+func TestSecretKeySource(t *testing.T) {
+	ctx := context.TODO()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "source-system", Name: "url-signing"},
+		Data: map[string][]byte{
+			signedurl.SecretKey: []byte("current"),
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+	keys := &signedurl.SecretKeySource{
+		Client: c,
+		Secret: types.NamespacedName{Namespace: "source-system", Name: "url-signing"},
+	}
+
+	actual, err := keys.Keys(ctx)
+	if err != nil {
+		t.Fatalf("Keys() unexpected error: %v", err)
+	}
+	if len(actual) != 1 || string(actual[0]) != "current" {
+		t.Errorf("Keys() unexpected keys %q", actual)
+	}
+
+	// rotate the key
+	secret.Data = map[string][]byte{
+		signedurl.SecretKey:         []byte("next"),
+		signedurl.SecretPreviousKey: []byte("current"),
+	}
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	actual, err = keys.Keys(ctx)
+	if err != nil {
+		t.Fatalf("Keys() unexpected error: %v", err)
+	}
+	if len(actual) != 2 || string(actual[0]) != "next" || string(actual[1]) != "current" {
+		t.Errorf("Keys() unexpected keys after rotation %q", actual)
+	}
+
+	missing := &signedurl.SecretKeySource{
+		Client: c,
+		Secret: types.NamespacedName{Namespace: "source-system", Name: "missing"},
+	}
+	if _, err := missing.Keys(ctx); err == nil {
+		t.Errorf("Keys() expected an error for a missing Secret")
+	}
+}
+
+func TestSecretKeySourceCachesFailures(t *testing.T) {
+	ctx := context.TODO()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "source-system", Name: "url-signing"},
+		Data: map[string][]byte{
+			signedurl.SecretKey: []byte("current"),
+		},
+	}
+	gets := 0
+	c := fake.NewClientBuilder().
+		WithObjects(secret).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				gets++
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).
+		Build()
+
+	missing := &signedurl.SecretKeySource{
+		Client:        c,
+		Secret:        types.NamespacedName{Namespace: "source-system", Name: "missing"},
+		RetryInterval: time.Hour,
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := missing.Keys(ctx); err == nil {
+			t.Errorf("Keys() expected an error for a missing Secret")
+		}
+	}
+	if gets != 1 {
+		t.Errorf("Keys() expected the missing Secret to be read once, got %d reads", gets)
+	}
+
+	gets = 0
+	keys := &signedurl.SecretKeySource{
+		Client:        c,
+		Secret:        types.NamespacedName{Namespace: "source-system", Name: "url-signing"},
+		RetryInterval: time.Hour,
+	}
+	if _, err := keys.Keys(ctx); err != nil {
+		t.Fatalf("Keys() unexpected error: %v", err)
+	}
+	if err := c.Delete(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	// the last keys read are used while the Secret is unavailable
+	for i := 0; i < 3; i++ {
+		actual, err := keys.Keys(ctx)
+		if err != nil {
+			t.Fatalf("Keys() unexpected error: %v", err)
+		}
+		if len(actual) != 1 || string(actual[0]) != "current" {
+			t.Errorf("Keys() expected the last keys read, got %q", actual)
+		}
+	}
+	if gets != 2 {
+		t.Errorf("Keys() expected the Secret to be read twice, got %d reads", gets)
+	}
+}
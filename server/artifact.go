@@ -18,11 +18,47 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/signedurl"
 )
 
+// DigestSuffix is appended to the name of an artifact to find the file holding
+// its algorithm-prefixed digest, the digest is served as the artifact's ETag
+const DigestSuffix = ".digest"
+
+// ManifestSuffix replaces the extension of an artifact to find the manifest
+// describing its content
+const ManifestSuffix = ".json"
+
+// artifactContentTypes maps the extension of each artifact format to the
+// content type artifacts in the format are served with
+var artifactContentTypes = map[string]string{
+	".tar.gz":  "application/gzip",
+	".tar.zst": "application/zstd",
+	".zip":     "application/zip",
+}
+
+// ArtifactExtension returns the extension of the artifact format the file is
+// named for, such as '.tar.gz', or an empty string when the file is not an
+// artifact
+func ArtifactExtension(name string) string {
+	for ext := range artifactContentTypes {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
 const (
 	readHeaderTimeout = 10 * time.Second
 	readTimeout       = 30 * time.Second
@@ -39,6 +75,21 @@ func New(addr string, dir string) *server {
 type server struct {
 	Addr string
 	Dir  string
+	// CertFile and KeyFile are the certificate and private key artifacts are
+	// served over TLS with. Updates to the files are picked up without a
+	// restart. Artifacts are served over plain HTTP when empty.
+	CertFile string
+	KeyFile  string
+	// Authenticator authenticates the bearer token presented by each request.
+	// Requests are not authenticated when nil.
+	Authenticator Authenticator
+	// Authorizer authorizes the user authenticated by each request to
+	// download the artifact. Any authenticated user may download every
+	// artifact when nil.
+	Authorizer Authorizer
+	// URLSigner verifies the signature of each request's URL. URLs are not
+	// required to be signed when nil.
+	URLSigner *signedurl.Signer
 }
 
 // newHTTPServer builds the http.Server used to serve artifacts. WriteTimeout
@@ -57,15 +108,58 @@ func newHTTPServer(addr string, handler http.Handler) *http.Server {
 
 func (s *server) Start(ctx context.Context) error {
 	directoryHandler := http.FileServer(http.Dir(s.Dir))
-	server := newHTTPServer(s.Addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, "/") {
 			// deactivate directory listings
 			// TODO deactivate redirects for directories `dir` -> `dir/`
 			http.NotFound(w, r)
 			return
 		}
+		base := path.Base(r.URL.Path)
+		ext := ArtifactExtension(base)
+		if ext != "" && strings.TrimSuffix(base, ext) == LatestName {
+			// resolve the alias to the current artifact of the resource
+			dir := path.Dir(r.URL.Path)
+			index, err := s.index(dir)
+			if err != nil || ArtifactExtension(index.Artifact) != ext {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set(RevisionHeader, index.Revision)
+			w.Header().Set(ChecksumHeader, index.Checksum)
+			// the alias changes with each revision
+			w.Header().Set("Cache-Control", "no-cache")
+			r = r.Clone(r.Context())
+			r.URL.Path = path.Join(dir, index.Artifact)
+		}
+		if contentType, ok := artifactContentTypes[ext]; ok {
+			w.Header().Set("Content-Type", contentType)
+		}
+		if etag := s.etag(r.URL.Path); etag != "" {
+			// the file server answers conditional and range requests
+			// against the ETag
+			w.Header().Set("ETag", etag)
+		}
 		directoryHandler.ServeHTTP(w, r)
-	}))
+	})
+	if s.URLSigner != nil {
+		handler = requireSignedURL(s.URLSigner, handler)
+	}
+	if s.Authenticator != nil {
+		handler = requireBearerToken(s.Authenticator, s.Authorizer, handler)
+	}
+	server := newHTTPServer(s.Addr, handler)
+
+	if s.CertFile != "" || s.KeyFile != "" {
+		certificates, err := newCertificateReloader(s.CertFile, s.KeyFile)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certificates.GetCertificate,
+		}
+	}
 
 	// shutdown server when the context closes
 	go func() {
@@ -73,5 +167,42 @@ func (s *server) Start(ctx context.Context) error {
 		server.Close()
 	}()
 
+	if server.TLSConfig != nil {
+		return server.ListenAndServeTLS("", "")
+	}
 	return server.ListenAndServe()
 }
+
+// etag returns the strong ETag of the artifact at the path, derived from its
+// stored digest. Artifacts without a digest have no ETag.
+func (s *server) etag(name string) string {
+	digest, err := os.ReadFile(filepath.Join(s.Dir, filepath.FromSlash(path.Clean("/"+name+DigestSuffix))))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%q", strings.TrimSpace(string(digest)))
+}
+
+// requireSignedURL rejects requests for URLs without a valid, unexpired
+// signature. The signed URL of an artifact is also accepted for its manifest.
+func requireSignedURL(signer *signedurl.Signer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := signer.Verify(r.Context(), r.URL.Path, r.URL.Query())
+		if errors.Is(err, signedurl.ErrInvalidSignature) && strings.HasSuffix(r.URL.Path, ManifestSuffix) {
+			for ext := range artifactContentTypes {
+				if err = signer.Verify(r.Context(), strings.TrimSuffix(r.URL.Path, ManifestSuffix)+ext, r.URL.Query()); err == nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			if errors.Is(err, signedurl.ErrMissingSignature) || errors.Is(err, signedurl.ErrExpired) || errors.Is(err, signedurl.ErrInvalidSignature) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			http.Error(w, "unable to verify URL signature", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -18,12 +18,35 @@ package server
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/vmware-tanzu/tanzu-source-controller/pkg/signedurl"
 )
 
 func TestNewHTTPServer_SetsTimeouts(t *testing.T) {
@@ -93,3 +116,722 @@ func TestStart_ServesFilesAndRejectsDirectoryListing(t *testing.T) {
 		t.Errorf("Start() returned unexpected error: %v", err)
 	}
 }
+
+func TestStart_ConditionalAndRangeRequests(t *testing.T) {
+	dir := t.TempDir()
+	digest := "sha256:ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+	modTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, content := range map[string]string{
+		"artifact.tar.gz":                "0123456789",
+		"artifact.tar.gz" + DigestSuffix: digest,
+		"nodigest.tar.gz":                "0123456789",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filepath.Join(dir, name), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New(freeAddr(t), dir)
+	startServer(t, s)
+
+	tests := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		status         int
+		expectedETag   string
+		expectedBody   string
+		expectedHeader map[string]string
+	}{
+		{
+			name:         "etag from the stored digest",
+			path:         "/artifact.tar.gz",
+			status:       http.StatusOK,
+			expectedETag: `"` + digest + `"`,
+			expectedBody: "0123456789",
+			expectedHeader: map[string]string{
+				"Accept-Ranges": "bytes",
+			},
+		},
+		{
+			name:         "no etag without a stored digest",
+			path:         "/nodigest.tar.gz",
+			status:       http.StatusOK,
+			expectedBody: "0123456789",
+		},
+		{
+			name:         "if-none-match current",
+			path:         "/artifact.tar.gz",
+			headers:      map[string]string{"If-None-Match": `"` + digest + `"`},
+			status:       http.StatusNotModified,
+			expectedETag: `"` + digest + `"`,
+		},
+		{
+			name:         "if-none-match stale",
+			path:         "/artifact.tar.gz",
+			headers:      map[string]string{"If-None-Match": `"sha256:0000"`},
+			status:       http.StatusOK,
+			expectedETag: `"` + digest + `"`,
+			expectedBody: "0123456789",
+		},
+		{
+			name:    "if-modified-since unmodified",
+			path:    "/nodigest.tar.gz",
+			headers: map[string]string{"If-Modified-Since": modTime.Add(time.Hour).Format(http.TimeFormat)},
+			status:  http.StatusNotModified,
+		},
+		{
+			name:         "if-modified-since modified",
+			path:         "/nodigest.tar.gz",
+			headers:      map[string]string{"If-Modified-Since": modTime.Add(-time.Hour).Format(http.TimeFormat)},
+			status:       http.StatusOK,
+			expectedBody: "0123456789",
+		},
+		{
+			name:         "range",
+			path:         "/artifact.tar.gz",
+			headers:      map[string]string{"Range": "bytes=4-"},
+			status:       http.StatusPartialContent,
+			expectedETag: `"` + digest + `"`,
+			expectedBody: "456789",
+			expectedHeader: map[string]string{
+				"Content-Range": "bytes 4-9/10",
+			},
+		},
+		{
+			name:         "range resumed against the current etag",
+			path:         "/artifact.tar.gz",
+			headers:      map[string]string{"Range": "bytes=4-5", "If-Range": `"` + digest + `"`},
+			status:       http.StatusPartialContent,
+			expectedETag: `"` + digest + `"`,
+			expectedBody: "45",
+		},
+		{
+			name:         "range resumed against a stale etag",
+			path:         "/artifact.tar.gz",
+			headers:      map[string]string{"Range": "bytes=4-5", "If-Range": `"sha256:0000"`},
+			status:       http.StatusOK,
+			expectedETag: `"` + digest + `"`,
+			expectedBody: "0123456789",
+		},
+		{
+			name:    "unsatisfiable range",
+			path:    "/artifact.tar.gz",
+			headers: map[string]string{"Range": "bytes=20-"},
+			status:  http.StatusRequestedRangeNotSatisfiable,
+			expectedHeader: map[string]string{
+				"Content-Range": "bytes */10",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			var err error
+			for range 50 {
+				req, _ := http.NewRequest(http.MethodGet, "http://"+s.Addr+tt.path, nil)
+				for k, v := range tt.headers {
+					req.Header.Set(k, v)
+				}
+				resp, err = http.DefaultClient.Do(req)
+				if err == nil {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			if err != nil {
+				t.Fatalf("GET file: %v", err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("GET file body: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("GET file status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if etag := resp.Header.Get("ETag"); etag != tt.expectedETag {
+				t.Errorf("GET file ETag = %q, want %q", etag, tt.expectedETag)
+			}
+			if tt.status != http.StatusRequestedRangeNotSatisfiable && string(body) != tt.expectedBody {
+				t.Errorf("GET file body = %q, want %q", body, tt.expectedBody)
+			}
+			for k, v := range tt.expectedHeader {
+				if actual := resp.Header.Get(k); actual != v {
+					t.Errorf("GET file header %s = %q, want %q", k, actual, v)
+				}
+			}
+		})
+	}
+}
+
+func TestStart_ServesArtifactContentTypes(t *testing.T) {
+	dir := t.TempDir()
+	expected := map[string]string{
+		"artifact.tar.gz":  "application/gzip",
+		"artifact.tar.zst": "application/zstd",
+		"artifact.zip":     "application/zip",
+		"artifact.json":    "application/json",
+	}
+	for name := range expected {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New(freeAddr(t), dir)
+	startServer(t, s)
+
+	for name, contentType := range expected {
+		var resp *http.Response
+		var err error
+		for range 50 {
+			resp, err = http.Get("http://" + s.Addr + "/" + name)
+			if err == nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("GET %s: %v", name, err)
+		}
+		resp.Body.Close()
+		if actual := resp.Header.Get("Content-Type"); actual != contentType {
+			t.Errorf("GET %s Content-Type = %q, want %q", name, actual, contentType)
+		}
+	}
+}
+
+func TestStart_ServesLatestAlias(t *testing.T) {
+	dir := t.TempDir()
+	digest := "sha256:ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+	for name, content := range map[string]string{
+		"imagerepository/test-namespace/my-image/0123.tar.gz":                "0123456789",
+		"imagerepository/test-namespace/my-image/0123.tar.gz" + DigestSuffix: digest,
+		"imagerepository/test-namespace/my-image/" + IndexFile:               `{"artifact":"0123.tar.gz","revision":"sha256:0123","checksum":"4567","digest":"` + digest + `"}`,
+		"imagerepository/test-namespace/escape/" + IndexFile:                 `{"artifact":"../my-image/0123.tar.gz"}`,
+		"imagerepository/test-namespace/unindexed/0123.tar.gz":               "0123456789",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New(freeAddr(t), dir)
+	startServer(t, s)
+
+	get := func(path string) (*http.Response, string) {
+		var resp *http.Response
+		var err error
+		for range 50 {
+			resp, err = http.Get("http://" + s.Addr + path)
+			if err == nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("GET %s body: %v", path, err)
+		}
+		return resp, string(body)
+	}
+
+	resp, body := get("/imagerepository/test-namespace/my-image/" + LatestName + ".tar.gz")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET latest status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if body != "0123456789" {
+		t.Errorf("GET latest body = %q, want %q", body, "0123456789")
+	}
+	for k, v := range map[string]string{
+		RevisionHeader:  "sha256:0123",
+		ChecksumHeader:  "4567",
+		"ETag":          `"` + digest + `"`,
+		"Cache-Control": "no-cache",
+		"Content-Type":  "application/gzip",
+	} {
+		if actual := resp.Header.Get(k); actual != v {
+			t.Errorf("GET latest header %s = %q, want %q", k, actual, v)
+		}
+	}
+
+	for _, path := range []string{
+		"/imagerepository/test-namespace/my-image/" + LatestName + ".zip",
+		"/imagerepository/test-namespace/unindexed/" + LatestName + ".tar.gz",
+		"/imagerepository/test-namespace/escape/" + LatestName + ".tar.gz",
+		"/imagerepository/test-namespace/missing/" + LatestName + ".tar.gz",
+	} {
+		if resp, _ := get(path); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, http.StatusNotFound)
+		}
+	}
+}
+
+func TestStart_ServesTLSAndReloadsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "artifact.tar.gz"), []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(t.TempDir(), "tls.crt")
+	keyFile := filepath.Join(t.TempDir(), "tls.key")
+	writeCertificate(t, certFile, keyFile, 1, time.Now())
+
+	s := New(freeAddr(t), dir)
+	s.CertFile = certFile
+	s.KeyFile = keyFile
+	startServer(t, s)
+
+	// trust the certificate without verifying it, the serial is checked
+	transport := &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+	client := &http.Client{Transport: transport}
+	get := func() *http.Response {
+		t.Helper()
+		var resp *http.Response
+		var err error
+		for range 50 {
+			resp, err = client.Get("https://" + s.Addr + "/artifact.tar.gz")
+			if err == nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("GET file: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET file status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		return resp
+	}
+
+	if serial := get().TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 1 {
+		t.Errorf("certificate serial = %d, want 1", serial)
+	}
+
+	// rotate the certificate
+	writeCertificate(t, certFile, keyFile, 2, time.Now().Add(time.Minute))
+	if serial := get().TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 2 {
+		t.Errorf("certificate serial after rotation = %d, want 2", serial)
+	}
+
+	// plain HTTP requests are rejected
+	resp, err := http.Get("http://" + s.Addr + "/artifact.tar.gz")
+	if err != nil {
+		t.Fatalf("GET file over HTTP: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET file over HTTP status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestStart_RequiresCertificateAndKey(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "tls.crt")
+	keyFile := filepath.Join(t.TempDir(), "tls.key")
+	writeCertificate(t, certFile, keyFile, 1, time.Now())
+
+	s := New(freeAddr(t), t.TempDir())
+	s.CertFile = certFile
+	if err := s.Start(context.Background()); err == nil {
+		t.Errorf("Start() expected an error without a key file")
+	}
+}
+
+func TestStart_RequiresBearerToken(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "artifact.tar.gz"), []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(freeAddr(t), dir)
+	s.Authenticator = authenticatorFunc(func(ctx context.Context, token string) (*authenticationv1.UserInfo, error) {
+		switch token {
+		case "broken":
+			return nil, fmt.Errorf("review failed")
+		case "valid", "forbidden", "unreviewable":
+			return &authenticationv1.UserInfo{Username: token}, nil
+		}
+		return nil, nil
+	})
+	s.Authorizer = authorizerFunc(func(ctx context.Context, user *authenticationv1.UserInfo, artifactPath string) (bool, error) {
+		if user.Username == "unreviewable" {
+			return false, fmt.Errorf("review failed")
+		}
+		return user.Username == "valid" && artifactPath == "/artifact.tar.gz", nil
+	})
+	startServer(t, s)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "basic auth", authorization: "Basic dXNlcjpwYXNz", status: http.StatusUnauthorized},
+		{name: "invalid token", authorization: "Bearer invalid", status: http.StatusUnauthorized},
+		{name: "valid token", authorization: "Bearer valid", status: http.StatusOK},
+		{name: "review error", authorization: "Bearer broken", status: http.StatusServiceUnavailable},
+		{name: "user not authorized", authorization: "Bearer forbidden", status: http.StatusForbidden},
+		{name: "authorization error", authorization: "Bearer unreviewable", status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			var err error
+			for range 50 {
+				req, _ := http.NewRequest(http.MethodGet, "http://"+s.Addr+"/artifact.tar.gz", nil)
+				if tt.authorization != "" {
+					req.Header.Set("Authorization", tt.authorization)
+				}
+				resp, err = http.DefaultClient.Do(req)
+				if err == nil {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			if err != nil {
+				t.Fatalf("GET file: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("GET file status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Errorf("GET file expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestStart_RequiresSignedURL(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"artifact.tar.gz", "artifact" + ManifestSuffix, "other" + ManifestSuffix} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New(freeAddr(t), dir)
+	s.URLSigner = &signedurl.Signer{Keys: staticKeys{[]byte("key")}, TTL: time.Hour}
+	startServer(t, s)
+
+	signed, err := s.URLSigner.Sign(context.Background(), "http://"+s.Addr+"/artifact.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := (&signedurl.Signer{Keys: staticKeys{[]byte("key")}, TTL: -time.Minute}).Sign(context.Background(), "http://"+s.Addr+"/artifact.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := (&signedurl.Signer{Keys: staticKeys{[]byte("other")}, TTL: time.Hour}).Sign(context.Background(), "http://"+s.Addr+"/artifact.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{name: "unsigned", url: "http://" + s.Addr + "/artifact.tar.gz", status: http.StatusForbidden},
+		{name: "signed", url: signed, status: http.StatusOK},
+		{name: "expired", url: expired, status: http.StatusForbidden},
+		{name: "signed with another key", url: foreign, status: http.StatusForbidden},
+		{name: "manifest of a signed artifact", url: strings.Replace(signed, "/artifact.tar.gz", "/artifact"+ManifestSuffix, 1), status: http.StatusOK},
+		{name: "manifest of another artifact", url: strings.Replace(signed, "/artifact.tar.gz", "/other"+ManifestSuffix, 1), status: http.StatusForbidden},
+		{name: "manifest of an expired artifact", url: strings.Replace(expired, "/artifact.tar.gz", "/artifact"+ManifestSuffix, 1), status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			var err error
+			for range 50 {
+				resp, err = http.Get(tt.url)
+				if err == nil {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			if err != nil {
+				t.Fatalf("GET file: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("GET file status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestStart_ServesSignedLatestAlias(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"imagerepository/test-namespace/my-image/0123.tar.gz":  "0123456789",
+		"imagerepository/test-namespace/my-image/" + IndexFile: `{"artifact":"0123.tar.gz","revision":"sha256:0123"}`,
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New(freeAddr(t), dir)
+	s.URLSigner = &signedurl.Signer{Keys: staticKeys{[]byte("key")}, TTL: time.Hour}
+	startServer(t, s)
+
+	latest := "http://" + s.Addr + "/imagerepository/test-namespace/my-image/" + LatestName + ".tar.gz"
+	signed, err := s.URLSigner.Sign(context.Background(), latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedArtifact, err := s.URLSigner.Sign(context.Background(), "http://"+s.Addr+"/imagerepository/test-namespace/my-image/0123.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		body   string
+	}{
+		{name: "signed alias", url: signed, status: http.StatusOK, body: "0123456789"},
+		{name: "unsigned alias", url: latest, status: http.StatusForbidden},
+		{name: "alias with the signature of the artifact", url: strings.Replace(signedArtifact, "/0123.tar.gz", "/"+LatestName+".tar.gz", 1), status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			var err error
+			for range 50 {
+				resp, err = http.Get(tt.url)
+				if err == nil {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			if err != nil {
+				t.Fatalf("GET latest: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("GET latest status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.body == "" {
+				return
+			}
+			if body, err := io.ReadAll(resp.Body); err != nil || string(body) != tt.body {
+				t.Errorf("GET latest body = %q, %v, want %q", body, err, tt.body)
+			}
+		})
+	}
+}
+
+func TestTokenReviewAuthenticator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	reviews := 0
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authenticationv1.TokenReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				reviews++
+				if len(review.Spec.Audiences) != 1 || review.Spec.Audiences[0] != "artifacts" {
+					return fmt.Errorf("unexpected audiences %v", review.Spec.Audiences)
+				}
+				review.Status.Authenticated = review.Spec.Token == "valid"
+				if review.Status.Authenticated {
+					review.Status.User = authenticationv1.UserInfo{Username: "system:serviceaccount:default:consumer"}
+				}
+				return nil
+			},
+		}).
+		Build()
+
+	authenticator := &TokenReviewAuthenticator{Client: c, Audiences: []string{"artifacts"}}
+	for i, tt := range []struct {
+		token         string
+		authenticated bool
+		reviews       int
+	}{
+		{token: "invalid", authenticated: false, reviews: 1},
+		{token: "valid", authenticated: true, reviews: 2},
+		// authenticated tokens are cached
+		{token: "valid", authenticated: true, reviews: 2},
+		{token: "invalid", authenticated: false, reviews: 3},
+	} {
+		user, err := authenticator.Authenticate(context.Background(), tt.token)
+		if err != nil {
+			t.Fatalf("%d: Authenticate() unexpected error: %v", i, err)
+		}
+		if authenticated := user != nil; authenticated != tt.authenticated {
+			t.Errorf("%d: Authenticate() authenticated = %v, want %v", i, authenticated, tt.authenticated)
+		}
+		if user != nil && user.Username != "system:serviceaccount:default:consumer" {
+			t.Errorf("%d: Authenticate() user = %q, want the reviewed user", i, user.Username)
+		}
+		if reviews != tt.reviews {
+			t.Errorf("%d: token reviews = %d, want %d", i, reviews, tt.reviews)
+		}
+	}
+}
+
+func TestSubjectAccessReviewAuthorizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	reviews := 0
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				reviews++
+				attributes := review.Spec.ResourceAttributes
+				if attributes.Group != "source.apps.tanzu.vmware.com" || attributes.Resource != "imagerepositories" || attributes.Verb != "get" {
+					return fmt.Errorf("unexpected resource attributes %+v", attributes)
+				}
+				// the consumer may only get the resources in its namespace
+				review.Status.Allowed = review.Spec.User == "system:serviceaccount:team-a:consumer" && attributes.Namespace == "team-a"
+				return nil
+			},
+		}).
+		Build()
+
+	authorizer := &SubjectAccessReviewAuthorizer{
+		Client: c,
+		Resources: map[string]schema.GroupResource{
+			"imagerepository": {Group: "source.apps.tanzu.vmware.com", Resource: "imagerepositories"},
+		},
+	}
+	consumer := &authenticationv1.UserInfo{Username: "system:serviceaccount:team-a:consumer"}
+	other := &authenticationv1.UserInfo{Username: "system:serviceaccount:team-b:consumer"}
+	for i, tt := range []struct {
+		user    *authenticationv1.UserInfo
+		path    string
+		allowed bool
+		reviews int
+	}{
+		{user: consumer, path: "/imagerepository/team-a/app/0123.tar.gz", allowed: true, reviews: 1},
+		// decisions are cached
+		{user: consumer, path: "/imagerepository/team-a/app/latest.tar.gz", allowed: true, reviews: 1},
+		{user: consumer, path: "/imagerepository/team-b/app/0123.tar.gz", allowed: false, reviews: 2},
+		{user: other, path: "/imagerepository/team-a/app/0123.tar.gz", allowed: false, reviews: 3},
+		{user: other, path: "/imagerepository/team-a/app/0123.tar.gz", allowed: false, reviews: 3},
+		// artifacts of unknown resources are never authorized
+		{user: consumer, path: "/mavenartifact/team-a/app/0123.tar.gz", allowed: false, reviews: 3},
+		{user: consumer, path: "/imagerepository/team-a/../../0123.tar.gz", allowed: false, reviews: 3},
+	} {
+		allowed, err := authorizer.Authorize(context.Background(), tt.user, tt.path)
+		if err != nil {
+			t.Fatalf("%d: Authorize() unexpected error: %v", i, err)
+		}
+		if allowed != tt.allowed {
+			t.Errorf("%d: Authorize() = %v, want %v", i, allowed, tt.allowed)
+		}
+		if reviews != tt.reviews {
+			t.Errorf("%d: subject access reviews = %d, want %d", i, reviews, tt.reviews)
+		}
+	}
+}
+
+type staticKeys [][]byte
+
+func (k staticKeys) Keys(ctx context.Context) ([][]byte, error) {
+	return k, nil
+}
+
+type authenticatorFunc func(ctx context.Context, token string) (*authenticationv1.UserInfo, error)
+
+func (f authenticatorFunc) Authenticate(ctx context.Context, token string) (*authenticationv1.UserInfo, error) {
+	return f(ctx, token)
+}
+
+type authorizerFunc func(ctx context.Context, user *authenticationv1.UserInfo, artifactPath string) (bool, error)
+
+func (f authorizerFunc) Authorize(ctx context.Context, user *authenticationv1.UserInfo, artifactPath string) (bool, error) {
+	return f(ctx, user, artifactPath)
+}
+
+func freeAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+func startServer(t *testing.T, s *server) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-errCh; err != nil && err != http.ErrServerClosed {
+			t.Errorf("Start() returned unexpected error: %v", err)
+		}
+	})
+}
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1, setting the
+// modification time of the files
+func writeCertificate(t *testing.T, certFile, keyFile string, serial int64, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "artifact-server"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{certFile, keyFile} {
+		if err := os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
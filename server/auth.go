@@ -0,0 +1,226 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// tokenCacheTTL is how long a token is trusted after it was last reviewed,
+// and an authorization decision is reused
+const tokenCacheTTL = time.Minute
+
+// Authenticator authenticates the bearer token presented by a request
+type Authenticator interface {
+	// Authenticate returns the user the token identifies, or nil when the
+	// token is not valid
+	Authenticate(ctx context.Context, token string) (*authenticationv1.UserInfo, error)
+}
+
+// Authorizer authorizes an authenticated user to download an artifact
+type Authorizer interface {
+	// Authorize returns true when the user may download the artifact at the
+	// path, in the form of '<kind>/<namespace>/<name>/<file>'
+	Authorize(ctx context.Context, user *authenticationv1.UserInfo, artifactPath string) (bool, error)
+}
+
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+
+// TokenReviewAuthenticator authenticates Kubernetes service account, or other
+// bearer, tokens with the API Server's TokenReview API.
+type TokenReviewAuthenticator struct {
+	// Client creates the TokenReviews
+	Client client.Client
+	// Audiences the token must be issued for, any audience accepted by the
+	// API Server when empty
+	Audiences []string
+
+	cache decisionCache[*authenticationv1.UserInfo]
+}
+
+func (a *TokenReviewAuthenticator) Authenticate(ctx context.Context, token string) (*authenticationv1.UserInfo, error) {
+	// tokens are cached by their hash, avoiding a review for each request
+	key := sha256.Sum256([]byte(token))
+	if user, ok := a.cache.get(key); ok {
+		return user, nil
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: a.Audiences,
+		},
+	}
+	if err := a.Client.Create(ctx, review); err != nil {
+		return nil, err
+	}
+	if !review.Status.Authenticated {
+		return nil, nil
+	}
+
+	user := review.Status.User
+	a.cache.put(key, &user)
+	return &user, nil
+}
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// SubjectAccessReviewAuthorizer authorizes users to download the artifacts of
+// the resources they may get, checked with the API Server's
+// SubjectAccessReview API.
+type SubjectAccessReviewAuthorizer struct {
+	// Client creates the SubjectAccessReviews
+	Client client.Client
+	// Resources maps the top level directories of the artifact root to the
+	// resource that owns the artifacts stored beneath them. Artifacts beneath
+	// other directories are never authorized.
+	Resources map[string]schema.GroupResource
+
+	cache decisionCache[bool]
+}
+
+func (a *SubjectAccessReviewAuthorizer) Authorize(ctx context.Context, user *authenticationv1.UserInfo, artifactPath string) (bool, error) {
+	segments := strings.Split(strings.TrimPrefix(path.Clean("/"+artifactPath), "/"), "/")
+	if len(segments) < 4 {
+		return false, nil
+	}
+	resource, ok := a.Resources[segments[0]]
+	if !ok {
+		return false, nil
+	}
+	attributes := &authorizationv1.ResourceAttributes{
+		Group:     resource.Group,
+		Resource:  resource.Resource,
+		Namespace: segments[1],
+		Name:      segments[2],
+		Verb:      "get",
+	}
+
+	// decisions are cached by the user and resource, avoiding a review for
+	// each request
+	key := sha256.Sum256([]byte(strings.Join(append([]string{user.Username, user.UID, attributes.Group, attributes.Resource, attributes.Namespace, attributes.Name}, user.Groups...), "\x00")))
+	if allowed, ok := a.cache.get(key); ok {
+		return allowed, nil
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: attributes,
+			User:               user.Username,
+			UID:                user.UID,
+			Groups:             user.Groups,
+			Extra:              extra,
+		},
+	}
+	if err := a.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	allowed := review.Status.Allowed && !review.Status.Denied
+	a.cache.put(key, allowed)
+	return allowed, nil
+}
+
+// decisionCache holds values by key for the tokenCacheTTL
+type decisionCache[T any] struct {
+	m       sync.Mutex
+	entries map[[sha256.Size]byte]cachedDecision[T]
+}
+
+type cachedDecision[T any] struct {
+	value   T
+	expires time.Time
+}
+
+func (c *decisionCache[T]) get(key [sha256.Size]byte) (T, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !time.Now().Before(entry.expires) {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (c *decisionCache[T]) put(key [sha256.Size]byte, value T) {
+	now := time.Now()
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.entries == nil {
+		c.entries = map[[sha256.Size]byte]cachedDecision[T]{}
+	}
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedDecision[T]{value: value, expires: now.Add(tokenCacheTTL)}
+}
+
+// requireBearerToken rejects requests that do not present a bearer token
+// accepted by the authenticator, or whose user is not authorized to download
+// the artifact when the authorizer is not nil
+func requireBearerToken(authenticator Authenticator, authorizer Authorizer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			unauthorized(w)
+			return
+		}
+		user, err := authenticator.Authenticate(r.Context(), token)
+		if err != nil {
+			http.Error(w, "unable to authenticate request", http.StatusServiceUnavailable)
+			return
+		}
+		if user == nil {
+			unauthorized(w)
+			return
+		}
+		if authorizer != nil {
+			allowed, err := authorizer.Authorize(r.Context(), user, r.URL.Path)
+			if err != nil {
+				http.Error(w, "unable to authorize request", http.StatusServiceUnavailable)
+				return
+			}
+			if !allowed {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="artifacts"`)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 VMware, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	// IndexFile is the name of the file in each resource's artifact
+	// directory recording its current artifact
+	IndexFile = "index.json"
+	// LatestName is the name the current artifact of each resource is served
+	// at, followed by the extension of the artifact's format, in the form of
+	// '<kind>/<namespace>/<name>/latest.tar.gz'
+	LatestName = "latest"
+
+	// RevisionHeader holds the source revision of the artifact served at
+	// the latest alias
+	RevisionHeader = "X-Artifact-Revision"
+	// ChecksumHeader holds the checksum of the artifact served at the latest
+	// alias
+	ChecksumHeader = "X-Artifact-Checksum"
+)
+
+// ArtifactIndex records the current artifact of a resource
+type ArtifactIndex struct {
+	// Artifact is the file name of the current artifact, in the same
+	// directory as the index
+	Artifact string `json:"artifact"`
+	// Revision is the source revision of the artifact
+	Revision string `json:"revision"`
+	// Checksum is the SHA-1 checksum of the artifact
+	Checksum string `json:"checksum"`
+	// Digest is the algorithm-prefixed digest of the artifact
+	Digest string `json:"digest"`
+}
+
+// index reads the index of the artifact directory
+func (s *server) index(dir string) (*ArtifactIndex, error) {
+	content, err := os.ReadFile(filepath.Join(s.Dir, filepath.FromSlash(path.Clean("/"+path.Join(dir, IndexFile)))))
+	if err != nil {
+		return nil, err
+	}
+	index := &ArtifactIndex{}
+	if err := json.Unmarshal(content, index); err != nil {
+		return nil, err
+	}
+	if index.Artifact == "" || path.Base(index.Artifact) != index.Artifact || index.Artifact == ".." {
+		return nil, fmt.Errorf("index %q names an artifact outside of its directory", dir)
+	}
+	return index, nil
+}